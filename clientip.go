@@ -0,0 +1,121 @@
+package drophere_go
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+type contextKey string
+
+const (
+	clientIPContextKey  contextKey = "clientIP"
+	userAgentContextKey contextKey = "userAgent"
+)
+
+// ParseTrustedProxies parses the addresses and CIDR ranges of the reverse proxies
+func ParseTrustedProxies(proxies []string) ([]*net.IPNet, error) {
+	nets := make([]*net.IPNet, 0, len(proxies))
+	for _, proxy := range proxies {
+		if !strings.Contains(proxy, "/") {
+			ip := net.ParseIP(proxy)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			continue
+		}
+
+		_, ipNet, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q", proxy)
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, nil
+}
+
+// ClientIPMiddleware keeps the client address and user agent in the request context for the resolvers.
+// The X-Forwarded-For and X-Real-IP headers are only read from the trusted proxies since anyone
+// else can spoof them, the request address is replaced with the client address as well
+func ClientIPMiddleware(trustedProxies []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r, trustedProxies)
+			r.RemoteAddr = ip
+
+			ctx := context.WithValue(r.Context(), clientIPContextKey, ip)
+			ctx = context.WithValue(ctx, userAgentContextKey, r.UserAgent())
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clientIP returns the address of the request, or the address forwarded by the trusted proxies
+func clientIP(r *http.Request, trustedProxies []*net.IPNet) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		ip = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(ip, trustedProxies) {
+		return ip
+	}
+
+	// every proxy appends the address it received the request from,
+	// so the client is the last one which is not a trusted proxy
+	if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+		addrs := strings.Split(forwarded, ",")
+		for i := len(addrs) - 1; i >= 0; i-- {
+			addr := strings.TrimSpace(addrs[i])
+			if net.ParseIP(addr) == nil {
+				break
+			}
+			ip = addr
+			if !isTrustedProxy(addr, trustedProxies) {
+				break
+			}
+		}
+		return ip
+	}
+
+	if realIP := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(realIP) != nil {
+		return realIP
+	}
+
+	return ip
+}
+
+// isTrustedProxy checks whether the address belongs to the trusted proxies
+func isTrustedProxy(addr string, trustedProxies []*net.IPNet) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+
+	for _, ipNet := range trustedProxies {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIPFromContext returns the client address kept by ClientIPMiddleware
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPContextKey).(string)
+	return ip
+}
+
+// sessionDeviceFromContext describes the client signing in from the values kept by ClientIPMiddleware
+func sessionDeviceFromContext(ctx context.Context) domain.SessionDevice {
+	userAgent, _ := ctx.Value(userAgentContextKey).(string)
+	return domain.SessionDevice{
+		UserAgent: userAgent,
+		IP:        ClientIPFromContext(ctx),
+	}
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrAccessTokenNotFound error
+	ErrAccessTokenNotFound = errors.New("Personal access token not found")
+	// ErrAccessTokenInvalid error
+	ErrAccessTokenInvalid = errors.New("Invalid or expired personal access token")
+	// ErrAccessTokenEmptyName error
+	ErrAccessTokenEmptyName = errors.New("Personal access token name must not be empty")
+	// ErrAccessTokenInvalidScope error
+	ErrAccessTokenInvalidScope = errors.New("Invalid personal access token scope")
+	// ErrAccessTokenNoScope error
+	ErrAccessTokenNoScope = errors.New("Personal access token must have at least one scope")
+	// ErrAccessTokenInvalidExpiry error
+	ErrAccessTokenInvalidExpiry = errors.New("Personal access token must expire within a year from now")
+)
+
+// AccessTokenPrefix starts every personal access token, it tells them apart from the JWTs
+const AccessTokenPrefix = "dph_"
+
+const (
+	// AccessTokenScopeLinksRead allows listing the links and their statistics
+	AccessTokenScopeLinksRead = "links:read"
+	// AccessTokenScopeLinksWrite allows creating, updating and deleting the links and their rosters
+	AccessTokenScopeLinksWrite = "links:write"
+	// AccessTokenScopeSubmissionsRead allows listing and downloading the submissions and the rosters
+	AccessTokenScopeSubmissionsRead = "submissions:read"
+	// AccessTokenScopeWebhooks allows managing the webhooks
+	AccessTokenScopeWebhooks = "webhooks"
+)
+
+// AccessTokenScopes lists every scope a personal access token can have
+var AccessTokenScopes = []string{
+	AccessTokenScopeLinksRead,
+	AccessTokenScopeLinksWrite,
+	AccessTokenScopeSubmissionsRead,
+	AccessTokenScopeWebhooks,
+}
+
+// AccessToken is a personal access token of a user for scripts and CI, it can only do what its scopes allow
+type AccessToken struct {
+	ID     uint
+	UserID uint
+	Name   string
+	// TokenHash is the hash of the secret part of the token, the token itself is only shown once
+	TokenHash string
+	// Scopes is comma-separated list of the scopes
+	Scopes     string
+	ExpiresAt  time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// ScopeList returns the scopes of the token
+func (t *AccessToken) ScopeList() []string {
+	return splitList(t.Scopes)
+}
+
+// HasScope checks whether the token has the scope
+func (t *AccessToken) HasScope(scope string) bool {
+	for _, s := range t.ScopeList() {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// AccessTokenRepository abstraction
+type AccessTokenRepository interface {
+	Create(t *AccessToken) (*AccessToken, error)
+	FindByID(id uint) (*AccessToken, error)
+	ListByUser(userID uint) ([]AccessToken, error)
+	Update(t *AccessToken) (*AccessToken, error)
+	Delete(t *AccessToken) error
+}
+
+// AccessTokenService abstraction
+type AccessTokenService interface {
+	// CreateAccessToken returns the created token along with the token string, which is not stored
+	CreateAccessToken(user *User, name string, scopes []string, expiresAt *time.Time) (*AccessToken, string, error)
+	ListAccessTokens(userID uint) ([]AccessToken, error)
+	RevokeAccessToken(userID, id uint) error
+	// Authenticate returns the personal access token of the token string,
+	// it fails with ErrAccessTokenInvalid when the token is unknown or expired
+	Authenticate(token string) (*AccessToken, error)
+}
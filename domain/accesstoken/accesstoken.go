@@ -0,0 +1,172 @@
+package accesstoken
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+const (
+	defaultExpiryDuration = 90 * 24 * time.Hour
+	maxExpiryDuration     = 366 * 24 * time.Hour
+
+	// lastUsedPrecision avoids writing the last usage on every request of a busy script
+	lastUsedPrecision = time.Minute
+)
+
+type service struct {
+	accessTokenRepo domain.AccessTokenRepository
+	hasher          domain.Hasher
+	stringGenerator domain.StringGenerator
+}
+
+// NewService returns new service instance
+func NewService(
+	accessTokenRepo domain.AccessTokenRepository,
+	hasher domain.Hasher,
+	stringGenerator domain.StringGenerator,
+) domain.AccessTokenService {
+	return &service{
+		accessTokenRepo: accessTokenRepo,
+		hasher:          hasher,
+		stringGenerator: stringGenerator,
+	}
+}
+
+// CreateAccessToken generates the token, it expires after 90 days by default.
+// The token is "dph_<ID>_<secret>", only the hash of the secret is stored
+func (s *service) CreateAccessToken(user *domain.User, name string, scopes []string, expiresAt *time.Time) (*domain.AccessToken, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", domain.ErrAccessTokenEmptyName
+	}
+
+	scopeList, err := normalizeScopes(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+
+	now := time.Now()
+	expiry := now.Add(defaultExpiryDuration)
+	if expiresAt != nil {
+		if !expiresAt.After(now) || expiresAt.After(now.Add(maxExpiryDuration)) {
+			return nil, "", domain.ErrAccessTokenInvalidExpiry
+		}
+		expiry = *expiresAt
+	}
+
+	secret := s.stringGenerator.Generate()
+	tokenHash, err := s.hasher.Hash(secret)
+	if err != nil {
+		return nil, "", err
+	}
+
+	t, err := s.accessTokenRepo.Create(&domain.AccessToken{
+		UserID:    user.ID,
+		Name:      name,
+		TokenHash: tokenHash,
+		Scopes:    scopeList,
+		ExpiresAt: expiry,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return t, fmt.Sprintf("%s%d_%s", domain.AccessTokenPrefix, t.ID, secret), nil
+}
+
+// ListAccessTokens returns the tokens of the user, including the expired ones
+func (s *service) ListAccessTokens(userID uint) ([]domain.AccessToken, error) {
+	return s.accessTokenRepo.ListByUser(userID)
+}
+
+// RevokeAccessToken deletes the token of the user
+func (s *service) RevokeAccessToken(userID, id uint) error {
+	t, err := s.accessTokenRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	if t.UserID != userID {
+		return domain.ErrAccessTokenNotFound
+	}
+
+	return s.accessTokenRepo.Delete(t)
+}
+
+// Authenticate implementation
+func (s *service) Authenticate(token string) (*domain.AccessToken, error) {
+	if !strings.HasPrefix(token, domain.AccessTokenPrefix) {
+		return nil, domain.ErrAccessTokenInvalid
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(token, domain.AccessTokenPrefix), "_", 2)
+	if len(parts) != 2 {
+		return nil, domain.ErrAccessTokenInvalid
+	}
+
+	id, err := strconv.ParseUint(parts[0], 10, 32)
+	if err != nil {
+		return nil, domain.ErrAccessTokenInvalid
+	}
+
+	t, err := s.accessTokenRepo.FindByID(uint(id))
+	if err == domain.ErrAccessTokenNotFound {
+		return nil, domain.ErrAccessTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !now.Before(t.ExpiresAt) || !s.hasher.Verify(t.TokenHash, parts[1]) {
+		return nil, domain.ErrAccessTokenInvalid
+	}
+
+	if t.LastUsedAt == nil || now.Sub(*t.LastUsedAt) >= lastUsedPrecision {
+		t.LastUsedAt = &now
+		if t, err = s.accessTokenRepo.Update(t); err != nil {
+			return nil, err
+		}
+	}
+
+	return t, nil
+}
+
+// normalizeScopes checks the scopes and returns them as comma-separated list without duplicates
+func normalizeScopes(scopes []string) (string, error) {
+	seen := make(map[string]bool, len(scopes))
+	scopeList := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if seen[scope] {
+			continue
+		}
+
+		if !isKnownScope(scope) {
+			return "", domain.ErrAccessTokenInvalidScope
+		}
+
+		seen[scope] = true
+		scopeList = append(scopeList, scope)
+	}
+
+	if len(scopeList) < 1 {
+		return "", domain.ErrAccessTokenNoScope
+	}
+
+	return strings.Join(scopeList, ","), nil
+}
+
+func isKnownScope(scope string) bool {
+	for _, s := range domain.AccessTokenScopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,101 @@
+package accesstoken_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/domain/accesstoken"
+	"github.com/bccfilkom/drophere-go/infrastructure/database/inmemory"
+	"github.com/bccfilkom/drophere-go/infrastructure/hasher"
+	"github.com/bccfilkom/drophere-go/infrastructure/stringgenerator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newService() domain.AccessTokenService {
+	return accesstoken.NewService(
+		inmemory.NewAccessTokenRepository(inmemory.New()),
+		hasher.NewNotAHasher(),
+		stringgenerator.NewMock(),
+	)
+}
+
+func TestCreateAccessToken(t *testing.T) {
+	now := time.Now()
+
+	type test struct {
+		name       string
+		scopes     []string
+		expiresAt  *time.Time
+		wantScopes []string
+		wantErr    error
+	}
+
+	tests := []test{
+		{
+			name:       " CI ",
+			scopes:     []string{"links:write", "Links:Read ", "links:write"},
+			wantScopes: []string{"links:write", "links:read"},
+		},
+		{name: " ", scopes: []string{"links:read"}, wantErr: domain.ErrAccessTokenEmptyName},
+		{name: "CI", scopes: []string{"links:delete"}, wantErr: domain.ErrAccessTokenInvalidScope},
+		{name: "CI", scopes: []string{}, wantErr: domain.ErrAccessTokenNoScope},
+		{name: "CI", scopes: []string{"links:read"}, expiresAt: time2ptr(now.Add(-time.Minute)), wantErr: domain.ErrAccessTokenInvalidExpiry},
+		{name: "CI", scopes: []string{"links:read"}, expiresAt: time2ptr(now.AddDate(2, 0, 0)), wantErr: domain.ErrAccessTokenInvalidExpiry},
+	}
+
+	stringgenerator.SetMockResult("token_secret")
+
+	for i, tc := range tests {
+		accessTokenSvc := newService()
+
+		at, token, err := accessTokenSvc.CreateAccessToken(&domain.User{ID: 1}, tc.name, tc.scopes, tc.expiresAt)
+		assert.Equal(t, tc.wantErr, err, "test %d", i)
+		if tc.wantErr != nil {
+			continue
+		}
+
+		assert.Equal(t, "dph_1_token_secret", token, "test %d", i)
+		assert.Equal(t, "CI", at.Name, "test %d", i)
+		assert.Equal(t, tc.wantScopes, at.ScopeList(), "test %d", i)
+		assert.WithinDuration(t, now.AddDate(0, 0, 90), at.ExpiresAt, time.Minute, "test %d", i)
+	}
+}
+
+func TestAuthenticateAccessToken(t *testing.T) {
+	accessTokenSvc := newService()
+
+	stringgenerator.SetMockResult("token_secret")
+	expiresAt := time.Now().Add(time.Hour)
+	_, token, _ := accessTokenSvc.CreateAccessToken(&domain.User{ID: 1}, "CI", []string{"links:read"}, &expiresAt)
+
+	at, err := accessTokenSvc.Authenticate(token)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), at.UserID)
+	assert.True(t, at.HasScope(domain.AccessTokenScopeLinksRead))
+	assert.False(t, at.HasScope(domain.AccessTokenScopeLinksWrite))
+	assert.NotNil(t, at.LastUsedAt)
+
+	for i, invalidToken := range []string{"dph_1_wrong_secret", "dph_2_token_secret", "dph_x_token_secret", "dph_1", "token_secret"} {
+		_, err = accessTokenSvc.Authenticate(invalidToken)
+		assert.Equal(t, domain.ErrAccessTokenInvalid, err, "token %d", i)
+	}
+
+	// the token of another user can not be revoked
+	err = accessTokenSvc.RevokeAccessToken(357, at.ID)
+	assert.Equal(t, domain.ErrAccessTokenNotFound, err)
+
+	err = accessTokenSvc.RevokeAccessToken(1, at.ID)
+	assert.Nil(t, err)
+
+	_, err = accessTokenSvc.Authenticate(token)
+	assert.Equal(t, domain.ErrAccessTokenInvalid, err)
+
+	tokens, _ := accessTokenSvc.ListAccessTokens(1)
+	assert.Len(t, tokens, 0)
+}
+
+func time2ptr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrAnalyticsInvalidRange error
+	ErrAnalyticsInvalidRange = errors.New("The start of the range must be before its end")
+	// ErrAnalyticsRangeTooLong error
+	ErrAnalyticsRangeTooLong = errors.New("The range can not be longer than a year")
+)
+
+const (
+	// AnalyticsEventView is recorded when the public link page is opened
+	AnalyticsEventView = "view"
+	// AnalyticsEventUploadAttempt is recorded when an uploader starts uploading to the link
+	AnalyticsEventUploadAttempt = "upload_attempt"
+	// AnalyticsEventUploadSucceeded is recorded when a file is stored on the storage provider
+	AnalyticsEventUploadSucceeded = "upload_succeeded"
+	// AnalyticsEventUploadFailed is recorded when an upload is rejected or fails to be stored
+	AnalyticsEventUploadFailed = "upload_failed"
+)
+
+const (
+	// LinkStatsBucketHour groups the stats by hour
+	LinkStatsBucketHour = "hour"
+	// LinkStatsBucketDay groups the stats by day (UTC)
+	LinkStatsBucketDay = "day"
+)
+
+// AnalyticsEvent records an activity on a link
+type AnalyticsEvent struct {
+	ID     uint
+	LinkID uint
+	Type   string
+	// Bytes is the size of the stored file of a succeeded upload
+	Bytes int64
+	// FailureReason is the error shown to the uploader of a failed upload
+	FailureReason string
+	CreatedAt     time.Time
+}
+
+// AnalyticsEventCount is the number of the events of a type which happened in a bucket
+type AnalyticsEventCount struct {
+	BucketStart time.Time
+	Type        string
+	Count       int
+	// Bytes is the total size of the stored files of the succeeded uploads
+	Bytes int64
+}
+
+// LinkStatsCounts holds the number of each activity
+type LinkStatsCounts struct {
+	Views            int
+	UploadAttempts   int
+	UploadsSucceeded int
+	UploadsFailed    int
+	BytesReceived    int64
+}
+
+// LinkStatsBucket holds the activities from its start until the start of the next bucket
+type LinkStatsBucket struct {
+	Start time.Time
+	LinkStatsCounts
+}
+
+// FailureReasonCount is the number of failed uploads with the reason
+type FailureReasonCount struct {
+	Reason string
+	Count  int
+}
+
+// LinkStats summarizes the activities of a link in the range
+type LinkStats struct {
+	From       time.Time
+	To         time.Time
+	BucketSize string
+	Buckets    []LinkStatsBucket
+	LinkStatsCounts
+	TopFailureReasons []FailureReasonCount
+}
+
+// AnalyticsRepository abstraction
+type AnalyticsRepository interface {
+	Create(e *AnalyticsEvent) (*AnalyticsEvent, error)
+	// CountByLinkBetween counts the events of each type grouped by the bucket size (LinkStatsBucketHour
+	// or LinkStatsBucketDay), the buckets start at the whole hour or day (UTC)
+	CountByLinkBetween(linkID uint, from, to time.Time, bucketSize string) ([]AnalyticsEventCount, error)
+	// CountFailureReasonsByLinkBetween returns the most common reasons of the failed uploads
+	CountFailureReasonsByLinkBetween(linkID uint, from, to time.Time, limit int) ([]FailureReasonCount, error)
+}
+
+// AnalyticsService abstraction
+type AnalyticsService interface {
+	RecordView(l *Link) error
+	RecordUploadAttempt(l *Link) error
+	RecordUploadSucceeded(l *Link, size int64) error
+	RecordUploadFailed(l *Link, reason string) error
+	LinkStats(l *Link, from, to time.Time) (*LinkStats, error)
+}
@@ -0,0 +1,125 @@
+package analytics
+
+import (
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+const (
+	// hourlyRangeLimit is the longest range grouped by hour, longer ranges are grouped by day
+	hourlyRangeLimit = 48 * time.Hour
+	maxRange         = 366 * 24 * time.Hour
+
+	topFailureReasonCount  = 5
+	maxFailureReasonLength = 255
+)
+
+type service struct {
+	analyticsRepo domain.AnalyticsRepository
+}
+
+// NewService returns new service instance
+func NewService(analyticsRepo domain.AnalyticsRepository) domain.AnalyticsService {
+	return &service{analyticsRepo: analyticsRepo}
+}
+
+// RecordView records a view of the public link page
+func (s *service) RecordView(l *domain.Link) error {
+	return s.record(l, domain.AnalyticsEventView, 0, "")
+}
+
+// RecordUploadAttempt records the start of an upload
+func (s *service) RecordUploadAttempt(l *domain.Link) error {
+	return s.record(l, domain.AnalyticsEventUploadAttempt, 0, "")
+}
+
+// RecordUploadSucceeded records a stored file
+func (s *service) RecordUploadSucceeded(l *domain.Link, size int64) error {
+	return s.record(l, domain.AnalyticsEventUploadSucceeded, size, "")
+}
+
+// RecordUploadFailed records a rejected or failed upload
+func (s *service) RecordUploadFailed(l *domain.Link, reason string) error {
+	if runes := []rune(reason); len(runes) > maxFailureReasonLength {
+		reason = string(runes[:maxFailureReasonLength])
+	}
+	return s.record(l, domain.AnalyticsEventUploadFailed, 0, reason)
+}
+
+func (s *service) record(l *domain.Link, eventType string, bytes int64, reason string) error {
+	_, err := s.analyticsRepo.Create(&domain.AnalyticsEvent{
+		LinkID:        l.ID,
+		Type:          eventType,
+		Bytes:         bytes,
+		FailureReason: reason,
+		CreatedAt:     time.Now(),
+	})
+	return err
+}
+
+// LinkStats counts the activities of the link from the start of the range until before its end.
+// Ranges up to two days are grouped by hour, longer ranges are grouped by day
+func (s *service) LinkStats(l *domain.Link, from, to time.Time) (*domain.LinkStats, error) {
+	if !from.Before(to) {
+		return nil, domain.ErrAnalyticsInvalidRange
+	}
+	if to.Sub(from) > maxRange {
+		return nil, domain.ErrAnalyticsRangeTooLong
+	}
+
+	stats := &domain.LinkStats{
+		From:       from,
+		To:         to,
+		BucketSize: domain.LinkStatsBucketHour,
+	}
+
+	bucketSize := time.Hour
+	if to.Sub(from) > hourlyRangeLimit {
+		bucketSize = 24 * time.Hour
+		stats.BucketSize = domain.LinkStatsBucketDay
+	}
+
+	// the events are counted by the database, only the counts are loaded
+	counts, err := s.analyticsRepo.CountByLinkBetween(l.ID, from, to, stats.BucketSize)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.TopFailureReasons, err = s.analyticsRepo.CountFailureReasonsByLinkBetween(l.ID, from, to, topFailureReasonCount)
+	if err != nil {
+		return nil, err
+	}
+
+	// the buckets are aligned to the whole hour or day, so the first one may start before the range
+	firstStart := from.Truncate(bucketSize)
+	for start := firstStart; start.Before(to); start = start.Add(bucketSize) {
+		stats.Buckets = append(stats.Buckets, domain.LinkStatsBucket{Start: start})
+	}
+
+	for _, c := range counts {
+		i := int(c.BucketStart.Sub(firstStart) / bucketSize)
+		if i < 0 || i >= len(stats.Buckets) {
+			continue
+		}
+
+		add(&stats.Buckets[i].LinkStatsCounts, c)
+		add(&stats.LinkStatsCounts, c)
+	}
+
+	return stats, nil
+}
+
+func add(counts *domain.LinkStatsCounts, c domain.AnalyticsEventCount) {
+	switch c.Type {
+	case domain.AnalyticsEventView:
+		counts.Views += c.Count
+	case domain.AnalyticsEventUploadAttempt:
+		counts.UploadAttempts += c.Count
+	case domain.AnalyticsEventUploadSucceeded:
+		counts.UploadsSucceeded += c.Count
+		counts.BytesReceived += c.Bytes
+	case domain.AnalyticsEventUploadFailed:
+		counts.UploadsFailed += c.Count
+	}
+}
@@ -0,0 +1,98 @@
+package analytics_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/domain/analytics"
+	"github.com/bccfilkom/drophere-go/infrastructure/database/inmemory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLinkStats(t *testing.T) {
+	memdb := inmemory.New()
+	analyticsRepo := inmemory.NewAnalyticsRepository(memdb)
+	analyticsSvc := analytics.NewService(analyticsRepo)
+
+	day := time.Date(2019, time.July, 14, 0, 0, 0, 0, time.UTC)
+	for _, e := range []domain.AnalyticsEvent{
+		{LinkID: 1, Type: domain.AnalyticsEventView, CreatedAt: day.Add(9 * time.Hour)},
+		{LinkID: 1, Type: domain.AnalyticsEventView, CreatedAt: day.Add(9*time.Hour + 30*time.Minute)},
+		{LinkID: 1, Type: domain.AnalyticsEventUploadAttempt, CreatedAt: day.Add(9*time.Hour + 31*time.Minute)},
+		{LinkID: 1, Type: domain.AnalyticsEventUploadSucceeded, Bytes: 1024, CreatedAt: day.Add(9*time.Hour + 32*time.Minute)},
+		{LinkID: 1, Type: domain.AnalyticsEventUploadAttempt, CreatedAt: day.Add(11 * time.Hour)},
+		{LinkID: 1, Type: domain.AnalyticsEventUploadFailed, FailureReason: "Invalid Password", CreatedAt: day.Add(11 * time.Hour)},
+		{LinkID: 1, Type: domain.AnalyticsEventUploadAttempt, CreatedAt: day.Add(26 * time.Hour)},
+		{LinkID: 1, Type: domain.AnalyticsEventUploadFailed, FailureReason: "Link is Expired", CreatedAt: day.Add(26 * time.Hour)},
+		{LinkID: 1, Type: domain.AnalyticsEventUploadFailed, FailureReason: "Invalid Password", CreatedAt: day.Add(27 * time.Hour)},
+		{LinkID: 1, Type: domain.AnalyticsEventUploadSucceeded, Bytes: 2048, CreatedAt: day.Add(72 * time.Hour)},
+		// other links are not counted
+		{LinkID: 2, Type: domain.AnalyticsEventView, CreatedAt: day.Add(9 * time.Hour)},
+	} {
+		analyticsRepo.Create(&e)
+	}
+
+	l := &domain.Link{ID: 1}
+
+	// short ranges are grouped by hour
+	stats, err := analyticsSvc.LinkStats(l, day.Add(9*time.Hour+15*time.Minute), day.Add(12*time.Hour))
+	assert.Nil(t, err)
+	assert.Equal(t, domain.LinkStatsBucketHour, stats.BucketSize)
+	assert.Equal(t, []domain.LinkStatsBucket{
+		{
+			Start:           day.Add(9 * time.Hour),
+			LinkStatsCounts: domain.LinkStatsCounts{Views: 1, UploadAttempts: 1, UploadsSucceeded: 1, BytesReceived: 1024},
+		},
+		{Start: day.Add(10 * time.Hour)},
+		{
+			Start:           day.Add(11 * time.Hour),
+			LinkStatsCounts: domain.LinkStatsCounts{UploadAttempts: 1, UploadsFailed: 1},
+		},
+	}, stats.Buckets)
+	assert.Equal(t, domain.LinkStatsCounts{
+		Views:            1,
+		UploadAttempts:   2,
+		UploadsSucceeded: 1,
+		UploadsFailed:    1,
+		BytesReceived:    1024,
+	}, stats.LinkStatsCounts)
+
+	// long ranges are grouped by day
+	stats, err = analyticsSvc.LinkStats(l, day, day.AddDate(0, 0, 7))
+	assert.Nil(t, err)
+	assert.Equal(t, domain.LinkStatsBucketDay, stats.BucketSize)
+	assert.Len(t, stats.Buckets, 7)
+	assert.Equal(t, domain.LinkStatsCounts{UploadAttempts: 1, UploadsFailed: 2}, stats.Buckets[1].LinkStatsCounts)
+	assert.Equal(t, domain.LinkStatsCounts{UploadsSucceeded: 1, BytesReceived: 2048}, stats.Buckets[3].LinkStatsCounts)
+	assert.Equal(t, int64(3072), stats.BytesReceived)
+	assert.Equal(t, []domain.FailureReasonCount{
+		{Reason: "Invalid Password", Count: 2},
+		{Reason: "Link is Expired", Count: 1},
+	}, stats.TopFailureReasons)
+
+	_, err = analyticsSvc.LinkStats(l, day, day)
+	assert.Equal(t, domain.ErrAnalyticsInvalidRange, err)
+
+	_, err = analyticsSvc.LinkStats(l, day, day.AddDate(2, 0, 0))
+	assert.Equal(t, domain.ErrAnalyticsRangeTooLong, err)
+}
+
+func TestRecordUploadFailed(t *testing.T) {
+	memdb := inmemory.New()
+	analyticsRepo := inmemory.NewAnalyticsRepository(memdb)
+	analyticsSvc := analytics.NewService(analyticsRepo)
+
+	l := &domain.Link{ID: 1}
+	now := time.Now()
+
+	err := analyticsSvc.RecordUploadFailed(l, string(make([]rune, 300)))
+	assert.Nil(t, err)
+
+	reasons, _ := analyticsRepo.CountFailureReasonsByLinkBetween(1, now.Add(-time.Minute), now.Add(time.Minute), 5)
+	if assert.Len(t, reasons, 1) {
+		assert.Equal(t, 1, reasons[0].Count)
+		assert.Len(t, []rune(reasons[0].Reason), 255)
+	}
+}
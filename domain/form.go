@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrLinkInvalidFormField error
+	ErrLinkInvalidFormField = errors.New("Invalid form field")
+	// ErrFormAnswerRequired error
+	ErrFormAnswerRequired = errors.New("This field is required")
+	// ErrFormAnswerInvalidEmail error
+	ErrFormAnswerInvalidEmail = errors.New("Invalid email address")
+	// ErrFormAnswerInvalidNumber error
+	ErrFormAnswerInvalidNumber = errors.New("Invalid number")
+	// ErrFormAnswerInvalidOption error
+	ErrFormAnswerInvalidOption = errors.New("Invalid option")
+)
+
+const (
+	// FormFieldText accepts any text
+	FormFieldText = "text"
+	// FormFieldEmail accepts an email address
+	FormFieldEmail = "email"
+	// FormFieldNumber accepts a number
+	FormFieldNumber = "number"
+	// FormFieldSelect accepts one of the field options
+	FormFieldSelect = "select"
+)
+
+// FormField is a field the uploader fills before uploading to a link
+type FormField struct {
+	Name     string   `json:"name"`
+	Label    string   `json:"label"`
+	Type     string   `json:"type"`
+	Required bool     `json:"required"`
+	Options  []string `json:"options,omitempty"`
+	// UseInFileName prepends the answer to the stored file name
+	UseInFileName bool `json:"useInFileName,omitempty"`
+}
+
+// FormFields is the form schema of a link, stored as JSON
+type FormFields []FormField
+
+// Has checks whether the form contains a field with the name
+func (f FormFields) Has(name string) bool {
+	for _, field := range f {
+		if field.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Value implements driver.Valuer
+func (f FormFields) Value() (driver.Value, error) {
+	if f == nil {
+		return "[]", nil
+	}
+
+	b, err := json.Marshal(f)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (f *FormFields) Scan(src interface{}) error {
+	return scanJSON(src, f)
+}
+
+// FormAnswers maps the form field name to the uploader's answer, stored as JSON
+type FormAnswers map[string]string
+
+// Value implements driver.Valuer
+func (a FormAnswers) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+
+	b, err := json.Marshal(a)
+	return string(b), err
+}
+
+// Scan implements sql.Scanner
+func (a *FormAnswers) Scan(src interface{}) error {
+	return scanJSON(src, a)
+}
+
+// FormAnswerError tells which field has invalid answer
+type FormAnswerError struct {
+	Field FormField
+	Err   error
+}
+
+func (e *FormAnswerError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Field.Label, e.Err.Error())
+}
+
+func scanJSON(src interface{}, v interface{}) error {
+	var b []byte
+	switch src := src.(type) {
+	case nil:
+		return nil
+	case []byte:
+		b = src
+	case string:
+		b = []byte(src)
+	default:
+		return fmt.Errorf("unsupported type %T", src)
+	}
+
+	if len(b) == 0 {
+		return nil
+	}
+
+	return json.Unmarshal(b, v)
+}
@@ -2,6 +2,7 @@ package domain
 
 import (
 	"errors"
+	"strings"
 	"time"
 )
 
@@ -12,8 +13,60 @@ var (
 	ErrLinkInvalidPassword = errors.New("Invalid password")
 	// ErrLinkNotFound error
 	ErrLinkNotFound = errors.New("Not found")
+	// ErrLinkFileTooLarge error
+	ErrLinkFileTooLarge = errors.New("File is too large")
+	// ErrLinkFileExtensionNotAllowed error
+	ErrLinkFileExtensionNotAllowed = errors.New("File extension is not allowed")
+	// ErrLinkFileTypeNotAllowed error
+	ErrLinkFileTypeNotAllowed = errors.New("File type is not allowed")
+	// ErrLinkInvalidMaxFileSize error
+	ErrLinkInvalidMaxFileSize = errors.New("Max file size can not be negative")
+	// ErrLinkInvalidNotificationMode error
+	ErrLinkInvalidNotificationMode = errors.New("Invalid notification mode")
+	// ErrLinkInvalidFileNameTemplate error
+	ErrLinkInvalidFileNameTemplate = errors.New("Invalid file name template")
+	// ErrLinkInvalidLatePolicy error
+	ErrLinkInvalidLatePolicy = errors.New("Invalid late policy")
+	// ErrLinkInvalidGracePeriod error
+	ErrLinkInvalidGracePeriod = errors.New("Grace period can not be negative")
+	// ErrLinkExpired error
+	ErrLinkExpired = errors.New("Link is Expired")
+	// ErrLinkNotYetOpen error
+	ErrLinkNotYetOpen = errors.New("Link is not yet open")
+	// ErrLinkInvalidOpeningTime error
+	ErrLinkInvalidOpeningTime = errors.New("Opening time must be before the deadline")
 )
 
+const (
+	// LinkNotificationNone disables upload notification
+	LinkNotificationNone = "none"
+	// LinkNotificationImmediate notifies the owner on every upload
+	LinkNotificationImmediate = "immediate"
+	// LinkNotificationDaily notifies the owner once a day with the summary of the uploads
+	LinkNotificationDaily = "daily"
+)
+
+const (
+	// LatePolicyClose rejects uploads after the deadline
+	LatePolicyClose = "close"
+	// LatePolicyGracePeriod accepts uploads until the grace period after the deadline passes
+	LatePolicyGracePeriod = "grace"
+	// LatePolicyAccept accepts uploads after the deadline and flags them as late
+	LatePolicyAccept = "accept"
+)
+
+const (
+	// LinkStatusNotYetOpen is the status of a link before its opening time
+	LinkStatusNotYetOpen = "not yet open"
+	// LinkStatusOpen is the status of a link which accepts uploads
+	LinkStatusOpen = "open"
+	// LinkStatusClosed is the status of a link which no longer accepts uploads
+	LinkStatusClosed = "closed"
+)
+
+// LateFolder is the sub folder of the link's folder for late uploads
+const LateFolder = "late"
+
 // Link domain model
 type Link struct {
 	ID                      uint
@@ -23,9 +76,44 @@ type Link struct {
 	Password                string
 	Slug                    string
 	Deadline                *time.Time
+	OpensAt                 *time.Time
 	Description             string
 	UserStorageCredentialID *uint
 	UserStorageCredential   *UserStorageCredential
+
+	// MaxFileSize is in bytes, 0 means unlimited
+	MaxFileSize int64
+	// AllowedExtensions is comma-separated list of extensions without the dot, empty means any extension
+	AllowedExtensions string
+	// AllowedMimeTypes is comma-separated list of MIME types (e.g. "application/pdf,image/*"), empty means any type
+	AllowedMimeTypes string
+	// NotificationMode is one of LinkNotificationNone, LinkNotificationImmediate and LinkNotificationDaily
+	NotificationMode string
+	// FormFields is filled by the uploader before uploading
+	FormFields FormFields
+	// FileNameTemplate is a text/template for the stored file name (e.g. "{{.Field.nim}}_{{.Timestamp}}{{.Ext}}"),
+	// empty means the original file name is kept
+	FileNameTemplate string
+	// LatePolicy is one of LatePolicyClose, LatePolicyGracePeriod and LatePolicyAccept
+	LatePolicy string
+	// GracePeriodMinutes is how long uploads are accepted after the deadline with LatePolicyGracePeriod
+	GracePeriodMinutes int
+	// LateSubfolder stores the late uploads in the LateFolder sub folder
+	LateSubfolder bool
+}
+
+// LinkSettings holds the optional settings of a link, nil fields are left untouched on update.
+// Passing an empty (non-nil) list removes the restriction
+type LinkSettings struct {
+	MaxFileSize        *int64
+	AllowedExtensions  []string
+	AllowedMimeTypes   []string
+	NotificationMode   *string
+	FormFields         FormFields
+	FileNameTemplate   *string
+	LatePolicy         *string
+	GracePeriodMinutes *int
+	LateSubfolder      *bool
 }
 
 // IsProtected checks if the link is protected with password
@@ -33,11 +121,69 @@ func (l *Link) IsProtected() bool {
 	return l.Password != ""
 }
 
+// Status tells whether the link accepts uploads at the given time,
+// uploads after the deadline are accepted following the late policy
+func (l *Link) Status(at time.Time) string {
+	if l.OpensAt != nil && at.Before(*l.OpensAt) {
+		return LinkStatusNotYetOpen
+	}
+
+	if l.Deadline == nil || !at.After(*l.Deadline) {
+		return LinkStatusOpen
+	}
+
+	switch l.LatePolicy {
+	case LatePolicyAccept:
+		return LinkStatusOpen
+	case LatePolicyGracePeriod:
+		if at.Sub(*l.Deadline) <= time.Duration(l.GracePeriodMinutes)*time.Minute {
+			return LinkStatusOpen
+		}
+	}
+
+	return LinkStatusClosed
+}
+
+// ClosesAt returns the last moment the link accepts uploads following its late policy,
+// it is nil when the link never closes
+func (l *Link) ClosesAt() *time.Time {
+	if l.Deadline == nil || l.LatePolicy == LatePolicyAccept {
+		return nil
+	}
+
+	closesAt := *l.Deadline
+	if l.LatePolicy == LatePolicyGracePeriod {
+		closesAt = closesAt.Add(time.Duration(l.GracePeriodMinutes) * time.Minute)
+	}
+	return &closesAt
+}
+
+// AllowedExtensionList returns the allowed extensions as slice
+func (l *Link) AllowedExtensionList() []string {
+	return splitList(l.AllowedExtensions)
+}
+
+// AllowedMimeTypeList returns the allowed MIME types as slice
+func (l *Link) AllowedMimeTypeList() []string {
+	return splitList(l.AllowedMimeTypes)
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
+
 // LinkService abstraction
 type LinkService interface {
 	CheckLinkPassword(l *Link, password string) bool
-	CreateLink(title, slug, description string, deadline *time.Time, password *string, user *User, providerID *uint) (*Link, error)
-	UpdateLink(id uint, title, slug string, description *string, deadline *time.Time, password *string, providerID *uint) (*Link, error)
+	CheckFileConstraints(l *Link, fileName string, size int64, head []byte) error
+	ValidateFormAnswers(l *Link, answers map[string]string) (FormAnswers, error)
+	StoredFileName(l *Link, sub *Submission) string
+	CheckUploadTime(l *Link, at time.Time) (lateness time.Duration, err error)
+	CreateLink(title, slug, description string, deadline, opensAt *time.Time, password *string, user *User, providerID *uint, settings LinkSettings) (*Link, error)
+	UpdateLink(id uint, title, slug string, description *string, deadline, opensAt *time.Time, password *string, providerID *uint, settings LinkSettings) (*Link, error)
 	DeleteLink(id uint) error
 	FetchLink(id uint) (*Link, error)
 	FindLinkBySlug(slug string) (*Link, error)
@@ -50,6 +196,10 @@ type LinkRepository interface {
 	Delete(l *Link) error
 	FindByID(id uint) (*Link, error)
 	FindBySlug(slug string) (*Link, error)
+	ListByDeadlineBetween(from, to time.Time) ([]Link, error)
+	// ListClosingBetween returns the links whose ClosesAt is from the start of the range until before its end
+	ListClosingBetween(from, to time.Time) ([]Link, error)
+	ListByNotificationMode(mode string) ([]Link, error)
 	ListByUser(userID uint) ([]Link, error)
 	Update(l *Link) (*Link, error)
 }
@@ -0,0 +1,142 @@
+package link
+
+import (
+	"bytes"
+	"path"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// fileNameData is the data of the link's file name template
+type fileNameData struct {
+	// Field maps the form field name to the uploader's answer
+	Field map[string]string
+	// Name is the original file name without the extension
+	Name string
+	// Ext is the original file extension including the dot, e.g. ".pdf"
+	Ext string
+	// FileName is the original file name
+	FileName string
+	// Identifier is the uploader's identifier in the link's roster
+	Identifier    string
+	UploaderName  string
+	UploaderEmail string
+	Slug          string
+	// Timestamp is the upload time formatted as 20060102-150405
+	Timestamp string
+	// Date is the upload date formatted as 2006-01-02
+	Date string
+}
+
+// maxFileNameTemplateLength follows the column size
+const maxFileNameTemplateLength = 255
+
+var fileNameFuncs = template.FuncMap{
+	"lower": strings.ToLower,
+	"upper": strings.ToUpper,
+	"trim":  strings.TrimSpace,
+}
+
+// pathSeparatorReplacer prevents the answers and the template from creating a sub folder
+var pathSeparatorReplacer = strings.NewReplacer("/", "-", `\`, "-")
+
+// StoredFileName returns the name of the file on the storage provider. The link's file name
+// template is used when it is set, otherwise the answers of fields marked with UseInFileName
+// are prepended to the original file name. The original file name is kept when the template
+// fails or renders an empty name. Each storage provider sanitizes the name for its own rules
+func (s *service) StoredFileName(l *domain.Link, sub *domain.Submission) string {
+	if l.FileNameTemplate != "" {
+		uploadedAt := sub.CreatedAt
+		if uploadedAt.IsZero() {
+			uploadedAt = time.Now()
+		}
+
+		name, err := renderFileName(l.FileNameTemplate, newFileNameData(l, sub, uploadedAt))
+		if err != nil || name == "" {
+			return sub.FileName
+		}
+		return name
+	}
+
+	parts := make([]string, 0, len(l.FormFields)+1)
+	for _, field := range l.FormFields {
+		if answer := sub.FormAnswers[field.Name]; field.UseInFileName && answer != "" {
+			parts = append(parts, pathSeparatorReplacer.Replace(answer))
+		}
+	}
+
+	return strings.Join(append(parts, sub.FileName), " - ")
+}
+
+func newFileNameData(l *domain.Link, sub *domain.Submission, uploadedAt time.Time) fileNameData {
+	field := make(map[string]string, len(sub.FormAnswers))
+	for name, answer := range sub.FormAnswers {
+		field[name] = pathSeparatorReplacer.Replace(answer)
+	}
+
+	ext := path.Ext(sub.FileName)
+	return fileNameData{
+		Field:         field,
+		Name:          strings.TrimSuffix(sub.FileName, ext),
+		Ext:           ext,
+		FileName:      sub.FileName,
+		Identifier:    sub.RosterIdentifier,
+		UploaderName:  sub.UploaderName,
+		UploaderEmail: sub.UploaderEmail,
+		Slug:          l.Slug,
+		Timestamp:     uploadedAt.Format("20060102-150405"),
+		Date:          uploadedAt.Format("2006-01-02"),
+	}
+}
+
+func renderFileName(text string, data fileNameData) (string, error) {
+	tmpl, err := parseFileNameTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	name := &bytes.Buffer{}
+	if err = tmpl.Execute(name, data); err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(pathSeparatorReplacer.Replace(name.String())), nil
+}
+
+// parseFileNameTemplate parses the template, a missing answer renders as empty string
+func parseFileNameTemplate(text string) (*template.Template, error) {
+	return template.New("fileName").
+		Funcs(fileNameFuncs).
+		Option("missingkey=zero").
+		Parse(text)
+}
+
+// normalizeFileNameTemplate validates the template by rendering it with sample data,
+// so an unknown field or function is rejected when the link is saved
+func normalizeFileNameTemplate(text string, formFields domain.FormFields) (string, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return "", nil
+	}
+
+	if len(text) > maxFileNameTemplateLength {
+		return "", domain.ErrLinkInvalidFileNameTemplate
+	}
+
+	sample := &domain.Submission{
+		FileName:    "sample.pdf",
+		FormAnswers: domain.FormAnswers{},
+	}
+	for _, field := range formFields {
+		sample.FormAnswers[field.Name] = field.Name
+	}
+
+	if _, err := renderFileName(text, newFileNameData(&domain.Link{}, sample, time.Now())); err != nil {
+		return "", domain.ErrLinkInvalidFileNameTemplate
+	}
+
+	return text, nil
+}
@@ -0,0 +1,136 @@
+package link
+
+import (
+	"bytes"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// oleMagic is the signature of legacy Microsoft Office documents (doc, xls, ppt)
+var oleMagic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+
+// containerMimeTypes maps the detected container format to the document types built on top of it.
+// The content sniffer only recognizes the container, so the extension decides the document type
+var containerMimeTypes = map[string]map[string]string{
+	"application/zip": {
+		"docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+		"xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		"pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+		"odt":  "application/vnd.oasis.opendocument.text",
+		"ods":  "application/vnd.oasis.opendocument.spreadsheet",
+		"odp":  "application/vnd.oasis.opendocument.presentation",
+		"epub": "application/epub+zip",
+	},
+	"application/x-ole-storage": {
+		"doc": "application/msword",
+		"xls": "application/vnd.ms-excel",
+		"ppt": "application/vnd.ms-powerpoint",
+	},
+	"text/plain": {
+		"csv":  "text/csv",
+		"md":   "text/markdown",
+		"json": "application/json",
+		"xml":  "application/xml",
+		"py":   "text/x-python",
+		"go":   "text/x-go",
+		"java": "text/x-java",
+		"c":    "text/x-c",
+		"cpp":  "text/x-c++",
+	},
+}
+
+// CheckFileConstraints checks the file against the link's max size, allowed extensions and allowed MIME types.
+// The MIME type is detected from head (the leading bytes of the file), the check is skipped when head is nil
+func (s *service) CheckFileConstraints(l *domain.Link, fileName string, size int64, head []byte) error {
+	if l.MaxFileSize > 0 && size > l.MaxFileSize {
+		return domain.ErrLinkFileTooLarge
+	}
+
+	ext := normalizeExtension(path.Ext(strings.ReplaceAll(fileName, `\`, "/")))
+
+	allowedExtensions := l.AllowedExtensionList()
+	if len(allowedExtensions) > 0 && !containsString(allowedExtensions, ext) {
+		return domain.ErrLinkFileExtensionNotAllowed
+	}
+
+	allowedMimeTypes := l.AllowedMimeTypeList()
+	if len(allowedMimeTypes) < 1 || head == nil {
+		return nil
+	}
+
+	for _, mimeType := range detectMimeTypes(head, ext) {
+		for _, allowed := range allowedMimeTypes {
+			if matchMimeType(allowed, mimeType) {
+				return nil
+			}
+		}
+	}
+
+	return domain.ErrLinkFileTypeNotAllowed
+}
+
+// detectMimeTypes returns the MIME types the content is recognized as
+func detectMimeTypes(head []byte, ext string) []string {
+	detected := normalizeMimeType(http.DetectContentType(head))
+	if bytes.HasPrefix(head, oleMagic) {
+		detected = "application/x-ole-storage"
+	}
+
+	mimeTypes := []string{detected}
+	if documentType, ok := containerMimeTypes[detected][ext]; ok {
+		mimeTypes = append(mimeTypes, documentType)
+	}
+
+	return mimeTypes
+}
+
+// matchMimeType matches the MIME type against pattern which may contain wildcard, e.g. "image/*"
+func matchMimeType(pattern, mimeType string) bool {
+	if pattern == "*/*" || pattern == mimeType {
+		return true
+	}
+
+	if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mimeType, strings.TrimSuffix(pattern, "*"))
+	}
+
+	return false
+}
+
+func normalizeExtension(ext string) string {
+	return strings.ToLower(strings.TrimLeft(strings.TrimSpace(ext), "."))
+}
+
+func normalizeMimeType(mimeType string) string {
+	if i := strings.Index(mimeType, ";"); i >= 0 {
+		mimeType = mimeType[:i]
+	}
+	return strings.ToLower(strings.TrimSpace(mimeType))
+}
+
+// joinList normalizes each item and joins them with comma, empty and duplicated items are dropped
+func joinList(items []string, normalize func(string) string) string {
+	normalized := make([]string, 0, len(items))
+	for _, item := range items {
+		for _, part := range strings.Split(item, ",") {
+			part = normalize(part)
+			if part != "" && !containsString(normalized, part) {
+				normalized = append(normalized, part)
+			}
+		}
+	}
+
+	return strings.Join(normalized, ",")
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
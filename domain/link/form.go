@@ -0,0 +1,108 @@
+package link
+
+import (
+	"net/mail"
+	"strconv"
+	"strings"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// ValidateFormAnswers checks the uploader's answers against the link's form fields,
+// answers to unknown fields are dropped
+func (s *service) ValidateFormAnswers(l *domain.Link, answers map[string]string) (domain.FormAnswers, error) {
+	validAnswers := domain.FormAnswers{}
+
+	for _, field := range l.FormFields {
+		answer := strings.TrimSpace(answers[field.Name])
+		if answer == "" {
+			if field.Required {
+				return nil, &domain.FormAnswerError{Field: field, Err: domain.ErrFormAnswerRequired}
+			}
+			continue
+		}
+
+		switch field.Type {
+		case domain.FormFieldEmail:
+			addr, err := mail.ParseAddress(answer)
+			if err != nil || addr.Address != answer {
+				return nil, &domain.FormAnswerError{Field: field, Err: domain.ErrFormAnswerInvalidEmail}
+			}
+
+		case domain.FormFieldNumber:
+			if _, err := strconv.ParseFloat(answer, 64); err != nil {
+				return nil, &domain.FormAnswerError{Field: field, Err: domain.ErrFormAnswerInvalidNumber}
+			}
+
+		case domain.FormFieldSelect:
+			if !containsString(field.Options, answer) {
+				return nil, &domain.FormAnswerError{Field: field, Err: domain.ErrFormAnswerInvalidOption}
+			}
+		}
+
+		validAnswers[field.Name] = answer
+	}
+
+	return validAnswers, nil
+}
+
+// normalizeFormFields validates the form schema
+func normalizeFormFields(fields domain.FormFields) (domain.FormFields, error) {
+	normalized := make(domain.FormFields, 0, len(fields))
+	names := make([]string, 0, len(fields))
+
+	for _, field := range fields {
+		field.Name = strings.TrimSpace(field.Name)
+		if !isValidFieldName(field.Name) || containsString(names, field.Name) {
+			return nil, domain.ErrLinkInvalidFormField
+		}
+		names = append(names, field.Name)
+
+		field.Label = strings.TrimSpace(field.Label)
+		if field.Label == "" {
+			field.Label = field.Name
+		}
+
+		field.Type = strings.ToLower(strings.TrimSpace(field.Type))
+		switch field.Type {
+		case domain.FormFieldText, domain.FormFieldEmail, domain.FormFieldNumber:
+			field.Options = nil
+
+		case domain.FormFieldSelect:
+			options := make([]string, 0, len(field.Options))
+			for _, option := range field.Options {
+				option = strings.TrimSpace(option)
+				if option != "" && !containsString(options, option) {
+					options = append(options, option)
+				}
+			}
+
+			if len(options) < 1 {
+				return nil, domain.ErrLinkInvalidFormField
+			}
+			field.Options = options
+
+		default:
+			return nil, domain.ErrLinkInvalidFormField
+		}
+
+		normalized = append(normalized, field)
+	}
+
+	return normalized, nil
+}
+
+// isValidFieldName allows letters, digits and underscore, so the name
+// can be used as form value key and tus metadata key
+func isValidFieldName(name string) bool {
+	if name == "" {
+		return false
+	}
+
+	for _, c := range name {
+		if !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') && c != '_' {
+			return false
+		}
+	}
+	return true
+}
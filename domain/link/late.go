@@ -0,0 +1,26 @@
+package link
+
+import (
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// CheckUploadTime checks whether the link accepts an upload at the given time and returns how long
+// after the deadline the upload is. ErrLinkNotYetOpen is returned before the opening time, and
+// ErrLinkExpired is returned when the link's late policy does not accept the upload.
+// Links created before the late policies existed are closed after the deadline
+func (s *service) CheckUploadTime(l *domain.Link, at time.Time) (time.Duration, error) {
+	switch l.Status(at) {
+	case domain.LinkStatusNotYetOpen:
+		return 0, domain.ErrLinkNotYetOpen
+	case domain.LinkStatusClosed:
+		return 0, domain.ErrLinkExpired
+	}
+
+	if l.Deadline == nil || !at.After(*l.Deadline) {
+		return 0, nil
+	}
+
+	return at.Sub(*l.Deadline), nil
+}
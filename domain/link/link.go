@@ -6,10 +6,17 @@ import (
 	"github.com/bccfilkom/drophere-go/domain"
 )
 
+// Config holds the link creation policy
+type Config struct {
+	// RequireVerifiedEmail blocks the users who have not verified their email from creating links
+	RequireVerifiedEmail bool
+}
+
 type service struct {
 	linkRepo       domain.LinkRepository
 	uscRepo        domain.UserStorageCredentialRepository
 	passwordHasher domain.Hasher
+	config         Config
 }
 
 // NewService returns new service instance
@@ -17,11 +24,13 @@ func NewService(
 	linkRepo domain.LinkRepository,
 	uscRepo domain.UserStorageCredentialRepository,
 	passwordHasher domain.Hasher,
+	config Config,
 ) domain.LinkService {
 	return &service{
 		linkRepo:       linkRepo,
 		uscRepo:        uscRepo,
 		passwordHasher: passwordHasher,
+		config:         config,
 	}
 }
 
@@ -36,7 +45,11 @@ func (s *service) CheckLinkPassword(l *domain.Link, password string) bool {
 }
 
 // CreateLink creates new Link and store it to repository
-func (s *service) CreateLink(title, slug, description string, deadline *time.Time, password *string, user *domain.User, providerID *uint) (*domain.Link, error) {
+func (s *service) CreateLink(title, slug, description string, deadline, opensAt *time.Time, password *string, user *domain.User, providerID *uint, settings domain.LinkSettings) (*domain.Link, error) {
+	if s.config.RequireVerifiedEmail && !user.IsEmailVerified() {
+		return nil, domain.ErrUserEmailNotVerified
+	}
+
 	l, err := s.linkRepo.FindBySlug(slug)
 	if err != nil && err != domain.ErrLinkNotFound {
 		return nil, err
@@ -46,12 +59,24 @@ func (s *service) CreateLink(title, slug, description string, deadline *time.Tim
 		return nil, domain.ErrLinkDuplicatedSlug
 	}
 
+	if err = checkOpeningTime(deadline, opensAt); err != nil {
+		return nil, err
+	}
+
 	l = &domain.Link{
 		UserID:      user.ID,
 		Title:       title,
 		Slug:        slug,
 		Description: description,
 		Deadline:    deadline,
+		OpensAt:     opensAt,
+
+		NotificationMode: domain.LinkNotificationNone,
+		LatePolicy:       domain.LatePolicyClose,
+	}
+
+	if err = applySettings(l, settings); err != nil {
+		return nil, err
 	}
 
 	if password != nil && *password != "" {
@@ -84,7 +109,7 @@ func (s *service) CreateLink(title, slug, description string, deadline *time.Tim
 }
 
 // UpdateLink updates existing Link and save it to repository
-func (s *service) UpdateLink(linkID uint, title, slug string, description *string, deadline *time.Time, password *string, providerID *uint) (*domain.Link, error) {
+func (s *service) UpdateLink(linkID uint, title, slug string, description *string, deadline, opensAt *time.Time, password *string, providerID *uint, settings domain.LinkSettings) (*domain.Link, error) {
 	l, err := s.linkRepo.FindByID(linkID)
 	if err != nil {
 		return nil, err
@@ -100,9 +125,18 @@ func (s *service) UpdateLink(linkID uint, title, slug string, description *strin
 		return nil, domain.ErrLinkDuplicatedSlug
 	}
 
+	if err = checkOpeningTime(deadline, opensAt); err != nil {
+		return nil, err
+	}
+
+	if err = applySettings(l, settings); err != nil {
+		return nil, err
+	}
+
 	l.Title = title
 	l.Slug = slug
 	l.Deadline = deadline // set null if the user want to remove the deadline
+	l.OpensAt = opensAt   // set null to open the link immediately
 	if description != nil {
 		l.Description = *description
 	}
@@ -148,6 +182,80 @@ func (s *service) UpdateLink(linkID uint, title, slug string, description *strin
 	return s.linkRepo.Update(l)
 }
 
+// checkOpeningTime makes sure the link is open for a while before the deadline
+func checkOpeningTime(deadline, opensAt *time.Time) error {
+	if deadline != nil && opensAt != nil && !opensAt.Before(*deadline) {
+		return domain.ErrLinkInvalidOpeningTime
+	}
+
+	return nil
+}
+
+// applySettings copies the non-nil settings to the link
+func applySettings(l *domain.Link, settings domain.LinkSettings) error {
+	if settings.MaxFileSize != nil {
+		if *settings.MaxFileSize < 0 {
+			return domain.ErrLinkInvalidMaxFileSize
+		}
+		l.MaxFileSize = *settings.MaxFileSize
+	}
+
+	if settings.AllowedExtensions != nil {
+		l.AllowedExtensions = joinList(settings.AllowedExtensions, normalizeExtension)
+	}
+
+	if settings.AllowedMimeTypes != nil {
+		l.AllowedMimeTypes = joinList(settings.AllowedMimeTypes, normalizeMimeType)
+	}
+
+	if settings.FormFields != nil {
+		formFields, err := normalizeFormFields(settings.FormFields)
+		if err != nil {
+			return err
+		}
+		l.FormFields = formFields
+	}
+
+	if settings.FileNameTemplate != nil {
+		fileNameTemplate, err := normalizeFileNameTemplate(*settings.FileNameTemplate, l.FormFields)
+		if err != nil {
+			return err
+		}
+		l.FileNameTemplate = fileNameTemplate
+	}
+
+	if settings.LatePolicy != nil {
+		switch *settings.LatePolicy {
+		case domain.LatePolicyClose, domain.LatePolicyGracePeriod, domain.LatePolicyAccept:
+			l.LatePolicy = *settings.LatePolicy
+		default:
+			return domain.ErrLinkInvalidLatePolicy
+		}
+	}
+
+	if settings.GracePeriodMinutes != nil {
+		if *settings.GracePeriodMinutes < 0 {
+			return domain.ErrLinkInvalidGracePeriod
+		}
+		l.GracePeriodMinutes = *settings.GracePeriodMinutes
+	}
+
+	if settings.LateSubfolder != nil {
+		l.LateSubfolder = *settings.LateSubfolder
+	}
+
+	if settings.NotificationMode != nil {
+		switch *settings.NotificationMode {
+		case domain.LinkNotificationNone, domain.LinkNotificationImmediate, domain.LinkNotificationDaily:
+			l.NotificationMode = *settings.NotificationMode
+		default:
+			return domain.ErrLinkInvalidNotificationMode
+		}
+	}
+
+	return nil
+}
+
 // DeleteLink delete existing Link specified by its ID
 func (s *service) DeleteLink(id uint) error {
 	l, err := s.linkRepo.FindByID(id)
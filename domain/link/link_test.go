@@ -36,6 +36,14 @@ func uint2ptr(u uint) *uint {
 	return &u
 }
 
+func int642ptr(i int64) *int64 {
+	return &i
+}
+
+func int2ptr(i int) *int {
+	return &i
+}
+
 func TestCheckLinkPassword(t *testing.T) {
 	type test struct {
 		link       *domain.Link
@@ -76,7 +84,7 @@ func TestCheckLinkPassword(t *testing.T) {
 		},
 	}
 
-	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher)
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
 
 	for i, tc := range tests {
 		gotResult := linkSvc.CheckLinkPassword(tc.link, tc.password)
@@ -93,9 +101,11 @@ func TestCreateLink(t *testing.T) {
 		slug        string
 		description string
 		deadline    *time.Time
+		opensAt     *time.Time
 		password    *string
 		user        *domain.User
 		providerID  *uint
+		settings    domain.LinkSettings
 		wantLink    *domain.Link
 		wantErr     error
 	}
@@ -125,6 +135,9 @@ func TestCreateLink(t *testing.T) {
 				Title:       "Drop CV",
 				Slug:        "yoursummerintern",
 				Description: "Drop your CV for summer internship",
+
+				NotificationMode: domain.LinkNotificationNone,
+				LatePolicy:       domain.LatePolicyClose,
 			},
 			wantErr: nil,
 		},
@@ -151,6 +164,8 @@ func TestCreateLink(t *testing.T) {
 				Password:                "",
 				UserStorageCredentialID: uint2ptr(2000),
 				UserStorageCredential:   &uscUser1,
+				NotificationMode:        domain.LinkNotificationNone,
+				LatePolicy:              domain.LatePolicyClose,
 			},
 			wantErr: nil,
 		},
@@ -172,15 +187,119 @@ func TestCreateLink(t *testing.T) {
 				Password:                "abcdef",
 				UserStorageCredentialID: uint2ptr(2000),
 				UserStorageCredential:   &uscUser1,
+				NotificationMode:        domain.LinkNotificationNone,
+				LatePolicy:              domain.LatePolicyClose,
+			},
+			wantErr: nil,
+		},
+		{
+			title:    "Link with negative max file size",
+			slug:     "negative-max-size",
+			user:     user,
+			settings: domain.LinkSettings{MaxFileSize: int642ptr(-1)},
+			wantErr:  domain.ErrLinkInvalidMaxFileSize,
+		},
+		{
+			title: "Link with duplicated form field",
+			slug:  "duplicated-form-field",
+			user:  user,
+			settings: domain.LinkSettings{FormFields: domain.FormFields{
+				{Name: "studentId", Type: domain.FormFieldText},
+				{Name: "studentId", Type: domain.FormFieldNumber},
+			}},
+			wantErr: domain.ErrLinkInvalidFormField,
+		},
+		{
+			title: "Link with select field without option",
+			slug:  "select-without-option",
+			user:  user,
+			settings: domain.LinkSettings{FormFields: domain.FormFields{
+				{Name: "class", Type: domain.FormFieldSelect, Options: []string{" "}},
+			}},
+			wantErr: domain.ErrLinkInvalidFormField,
+		},
+		{
+			title:    "Link with unknown file name template field",
+			slug:     "unknown-template-field",
+			user:     user,
+			settings: domain.LinkSettings{FileNameTemplate: str2ptr("{{.StudentID}}{{.Ext}}")},
+			wantErr:  domain.ErrLinkInvalidFileNameTemplate,
+		},
+		{
+			title:    "Link with unclosed file name template action",
+			slug:     "unclosed-template-action",
+			user:     user,
+			settings: domain.LinkSettings{FileNameTemplate: str2ptr("{{.Field.nim")},
+			wantErr:  domain.ErrLinkInvalidFileNameTemplate,
+		},
+		{
+			title:    "Link opening after the deadline",
+			slug:     "opens-after-deadline",
+			user:     user,
+			deadline: &linkDeadline,
+			opensAt:  time2ptr(linkDeadline.Add(time.Hour)),
+			wantErr:  domain.ErrLinkInvalidOpeningTime,
+		},
+		{
+			title:    "Link with unknown late policy",
+			slug:     "unknown-late-policy",
+			user:     user,
+			settings: domain.LinkSettings{LatePolicy: str2ptr("never")},
+			wantErr:  domain.ErrLinkInvalidLatePolicy,
+		},
+		{
+			title:    "Link with negative grace period",
+			slug:     "negative-grace-period",
+			user:     user,
+			settings: domain.LinkSettings{LatePolicy: str2ptr(domain.LatePolicyGracePeriod), GracePeriodMinutes: int2ptr(-5)},
+			wantErr:  domain.ErrLinkInvalidGracePeriod,
+		},
+		{
+			title:    "Link with unknown notification mode",
+			slug:     "weekly-notification",
+			user:     user,
+			settings: domain.LinkSettings{NotificationMode: str2ptr("weekly")},
+			wantErr:  domain.ErrLinkInvalidNotificationMode,
+		},
+		{
+			title: "Link with upload constraints",
+			slug:  "pdf-only",
+			user:  user,
+			settings: domain.LinkSettings{
+				MaxFileSize:       int642ptr(1024),
+				AllowedExtensions: []string{".PDF", "docx, pdf", ""},
+				AllowedMimeTypes:  []string{"Application/PDF; charset=binary", "image/*"},
+				NotificationMode:  str2ptr(domain.LinkNotificationDaily),
+				FormFields: domain.FormFields{
+					{Name: " studentId ", Type: "NUMBER", Required: true, Options: []string{"ignored"}},
+					{Name: "class", Label: "Class", Type: domain.FormFieldSelect, Options: []string{"A", " B", "A"}},
+				},
+				FileNameTemplate: str2ptr(" {{.Field.studentId}}_{{.Field.class}}{{.Ext}} "),
+			},
+			wantLink: &domain.Link{
+				ID:                7,
+				UserID:            user.ID,
+				Title:             "Link with upload constraints",
+				Slug:              "pdf-only",
+				MaxFileSize:       1024,
+				AllowedExtensions: "pdf,docx",
+				AllowedMimeTypes:  "application/pdf,image/*",
+				NotificationMode:  domain.LinkNotificationDaily,
+				LatePolicy:        domain.LatePolicyClose,
+				FormFields: domain.FormFields{
+					{Name: "studentId", Label: "studentId", Type: domain.FormFieldNumber, Required: true},
+					{Name: "class", Label: "Class", Type: domain.FormFieldSelect, Options: []string{"A", "B"}},
+				},
+				FileNameTemplate: "{{.Field.studentId}}_{{.Field.class}}{{.Ext}}",
 			},
 			wantErr: nil,
 		},
 	}
 
-	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher)
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
 
 	for _, tc := range tests {
-		gotLink, gotErr := linkSvc.CreateLink(tc.title, tc.slug, tc.description, tc.deadline, tc.password, tc.user, tc.providerID)
+		gotLink, gotErr := linkSvc.CreateLink(tc.title, tc.slug, tc.description, tc.deadline, tc.opensAt, tc.password, tc.user, tc.providerID, tc.settings)
 
 		assert.Equal(t, tc.wantErr, gotErr)
 		assert.Equal(t, tc.wantLink, gotLink)
@@ -195,8 +314,10 @@ func TestUpdateLink(t *testing.T) {
 		slug        string
 		description *string
 		deadline    *time.Time
+		opensAt     *time.Time
 		password    *string
 		providerID  *uint
+		settings    domain.LinkSettings
 		wantLink    *domain.Link
 		wantErr     error
 	}
@@ -269,12 +390,36 @@ func TestUpdateLink(t *testing.T) {
 				UserStorageCredential:   &uscUser1,
 			},
 		},
+		{
+			linkID: 1,
+			title:  "Drop CV 2 With MockBox",
+			slug:   "yoursummerintern2mockbox",
+			settings: domain.LinkSettings{
+				MaxFileSize:       int642ptr(2048),
+				AllowedExtensions: []string{"pdf"},
+				AllowedMimeTypes:  []string{},
+			},
+			wantErr: nil,
+			wantLink: &domain.Link{
+				ID:                      1,
+				Title:                   "Drop CV 2 With MockBox",
+				Slug:                    "yoursummerintern2mockbox",
+				Description:             "Drop your CV for summer internship 2019",
+				Password:                "123098",
+				UserID:                  user.ID,
+				User:                    user,
+				UserStorageCredentialID: uint2ptr(uscUser1.ID),
+				UserStorageCredential:   &uscUser1,
+				MaxFileSize:             2048,
+				AllowedExtensions:       "pdf",
+			},
+		},
 	}
 
-	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher)
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
 
 	for _, tc := range tests {
-		gotLink, gotErr := linkSvc.UpdateLink(tc.linkID, tc.title, tc.slug, tc.description, tc.deadline, tc.password, tc.providerID)
+		gotLink, gotErr := linkSvc.UpdateLink(tc.linkID, tc.title, tc.slug, tc.description, tc.deadline, tc.opensAt, tc.password, tc.providerID, tc.settings)
 
 		assert.Equal(t, tc.wantErr, gotErr)
 		assert.Equal(t, tc.wantLink, gotLink)
@@ -301,7 +446,7 @@ func TestDeleteLink(t *testing.T) {
 		},
 	}
 
-	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher)
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
 
 	for i, tc := range tests {
 		gotErr := linkSvc.DeleteLink(tc.linkID)
@@ -343,7 +488,7 @@ func TestFetchLink(t *testing.T) {
 		},
 	}
 
-	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher)
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
 
 	for i, tc := range tests {
 		gotLink, gotErr := linkSvc.FetchLink(tc.linkID)
@@ -389,7 +534,7 @@ func TestFindLinkBySlug(t *testing.T) {
 		},
 	}
 
-	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher)
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
 
 	for i, tc := range tests {
 		gotLink, gotErr := linkSvc.FindLinkBySlug(tc.slug)
@@ -447,7 +592,7 @@ func TestListLinks(t *testing.T) {
 		},
 	}
 
-	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher)
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
 
 	for _, tc := range tests {
 		gotLinks, gotErr := linkSvc.ListLinks(tc.userID)
@@ -458,3 +603,246 @@ func TestListLinks(t *testing.T) {
 	}
 
 }
+
+func TestCheckFileConstraints(t *testing.T) {
+	type test struct {
+		link     *domain.Link
+		fileName string
+		size     int64
+		head     []byte
+		wantErr  error
+	}
+
+	pdfHead := []byte("%PDF-1.5\n%\xb5\xed\xae\xfb\n")
+	zipHead := []byte("PK\x03\x04\x14\x00\x06\x00")
+	exeHead := []byte("MZ\x90\x00\x03\x00\x00\x00")
+
+	unrestricted := &domain.Link{}
+	pdfOnly := &domain.Link{
+		MaxFileSize:       1024,
+		AllowedExtensions: "pdf,docx",
+		AllowedMimeTypes:  "application/pdf,application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	}
+	images := &domain.Link{AllowedMimeTypes: "image/*"}
+
+	tests := []test{
+		{link: unrestricted, fileName: "setup.exe", size: 1 << 30, head: exeHead},
+		{link: pdfOnly, fileName: "cv.pdf", size: 1024, head: pdfHead},
+		{link: pdfOnly, fileName: "CV.PDF", size: 512, head: pdfHead},
+		{link: pdfOnly, fileName: "cv.pdf", size: 1025, head: pdfHead, wantErr: domain.ErrLinkFileTooLarge},
+		{link: pdfOnly, fileName: "cv", size: 512, head: pdfHead, wantErr: domain.ErrLinkFileExtensionNotAllowed},
+		{link: pdfOnly, fileName: "setup.exe", size: 512, head: exeHead, wantErr: domain.ErrLinkFileExtensionNotAllowed},
+		// the file is renamed, but the content is not a pdf
+		{link: pdfOnly, fileName: "setup.pdf", size: 512, head: exeHead, wantErr: domain.ErrLinkFileTypeNotAllowed},
+		// the content type is not known yet
+		{link: pdfOnly, fileName: "setup.pdf", size: 512, head: nil},
+		// docx is detected as zip archive, so the extension decides the document type
+		{link: pdfOnly, fileName: "cv.docx", size: 512, head: zipHead},
+		{link: images, fileName: "photo.png", size: 512, head: []byte("\x89PNG\r\n\x1a\n")},
+		{link: images, fileName: "photo.png", size: 512, head: pdfHead, wantErr: domain.ErrLinkFileTypeNotAllowed},
+	}
+
+	linkRepo, _, uscRepo := newRepo()
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
+
+	for i, tc := range tests {
+		gotErr := linkSvc.CheckFileConstraints(tc.link, tc.fileName, tc.size, tc.head)
+		assert.Equal(t, tc.wantErr, gotErr, "test %d", i)
+	}
+}
+
+func TestValidateFormAnswers(t *testing.T) {
+	type test struct {
+		answers     map[string]string
+		wantAnswers domain.FormAnswers
+		wantErr     error
+	}
+
+	l := &domain.Link{
+		FormFields: domain.FormFields{
+			{Name: "name", Label: "Name", Type: domain.FormFieldText, Required: true},
+			{Name: "studentId", Label: "Student ID", Type: domain.FormFieldNumber, Required: true},
+			{Name: "email", Label: "Email", Type: domain.FormFieldEmail},
+			{Name: "class", Label: "Class", Type: domain.FormFieldSelect, Options: []string{"A", "B"}},
+		},
+	}
+
+	tests := []test{
+		{
+			answers: map[string]string{"name": "Alice", "studentId": "123", "unknown": "dropped"},
+			wantAnswers: domain.FormAnswers{
+				"name":      "Alice",
+				"studentId": "123",
+			},
+		},
+		{
+			answers: map[string]string{"name": " Bob ", "studentId": "456", "email": "bob@drophere.link", "class": "B"},
+			wantAnswers: domain.FormAnswers{
+				"name":      "Bob",
+				"studentId": "456",
+				"email":     "bob@drophere.link",
+				"class":     "B",
+			},
+		},
+		{
+			answers: map[string]string{"name": " ", "studentId": "123"},
+			wantErr: &domain.FormAnswerError{Field: l.FormFields[0], Err: domain.ErrFormAnswerRequired},
+		},
+		{
+			answers: map[string]string{"name": "Alice", "studentId": "12a"},
+			wantErr: &domain.FormAnswerError{Field: l.FormFields[1], Err: domain.ErrFormAnswerInvalidNumber},
+		},
+		{
+			answers: map[string]string{"name": "Alice", "studentId": "123", "email": "Alice <alice@drophere.link>"},
+			wantErr: &domain.FormAnswerError{Field: l.FormFields[2], Err: domain.ErrFormAnswerInvalidEmail},
+		},
+		{
+			answers: map[string]string{"name": "Alice", "studentId": "123", "class": "C"},
+			wantErr: &domain.FormAnswerError{Field: l.FormFields[3], Err: domain.ErrFormAnswerInvalidOption},
+		},
+	}
+
+	linkRepo, _, uscRepo := newRepo()
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
+
+	for i, tc := range tests {
+		gotAnswers, gotErr := linkSvc.ValidateFormAnswers(l, tc.answers)
+		assert.Equal(t, tc.wantErr, gotErr, "test %d", i)
+		assert.Equal(t, tc.wantAnswers, gotAnswers, "test %d", i)
+	}
+}
+
+func TestStoredFileName(t *testing.T) {
+	l := &domain.Link{
+		FormFields: domain.FormFields{
+			{Name: "studentId", Type: domain.FormFieldNumber, UseInFileName: true},
+			{Name: "name", Type: domain.FormFieldText, UseInFileName: true},
+			{Name: "class", Type: domain.FormFieldText},
+		},
+	}
+
+	linkRepo, _, uscRepo := newRepo()
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
+
+	assert.Equal(t, "123 - Alice - report.pdf", linkSvc.StoredFileName(l, &domain.Submission{
+		FileName: "report.pdf",
+		FormAnswers: domain.FormAnswers{
+			"studentId": "123",
+			"name":      "Alice",
+			"class":     "A",
+		},
+	}))
+	assert.Equal(t, "..-etc - report.pdf", linkSvc.StoredFileName(l, &domain.Submission{
+		FileName:    "report.pdf",
+		FormAnswers: domain.FormAnswers{"name": "../etc"},
+	}))
+	assert.Equal(t, "report.pdf", linkSvc.StoredFileName(&domain.Link{}, &domain.Submission{FileName: "report.pdf"}))
+}
+
+func TestStoredFileNameTemplate(t *testing.T) {
+	type test struct {
+		fileNameTemplate string
+		wantFileName     string
+	}
+
+	linkRepo, _, uscRepo := newRepo()
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
+
+	sub := &domain.Submission{
+		FileName:     "tugas.final.pdf",
+		UploaderName: "Alice",
+		FormAnswers: domain.FormAnswers{
+			"nim":  "165150",
+			"name": "Alice/Bob",
+		},
+		CreatedAt: time.Date(2019, time.July, 14, 10, 5, 9, 0, time.UTC),
+	}
+
+	tests := []test{
+		{
+			fileNameTemplate: "{{.Field.nim}}_{{.Field.name}}_{{.Timestamp}}{{.Ext}}",
+			wantFileName:     "165150_Alice-Bob_20190714-100509.pdf",
+		},
+		{
+			fileNameTemplate: "{{.Slug}} {{.Date}} {{upper .Name}}{{.Ext}}",
+			wantFileName:     "drop-here 2019-07-14 TUGAS.FINAL.pdf",
+		},
+		{
+			// the missing answer renders as empty string
+			fileNameTemplate: "{{.Field.class}}{{.FileName}}",
+			wantFileName:     "tugas.final.pdf",
+		},
+		{
+			// the template must not create a sub folder
+			fileNameTemplate: "../{{.UploaderName}}/{{.FileName}}",
+			wantFileName:     "..-Alice-tugas.final.pdf",
+		},
+		{
+			// the original name is kept when nothing is rendered
+			fileNameTemplate: "{{.Field.class}}",
+			wantFileName:     "tugas.final.pdf",
+		},
+		{
+			// the original name is kept when the template fails
+			fileNameTemplate: "{{.Unknown}}",
+			wantFileName:     "tugas.final.pdf",
+		},
+	}
+
+	for i, tc := range tests {
+		l := &domain.Link{Slug: "drop-here", FileNameTemplate: tc.fileNameTemplate}
+		assert.Equal(t, tc.wantFileName, linkSvc.StoredFileName(l, sub), "test %d", i)
+	}
+}
+
+func TestCheckUploadTime(t *testing.T) {
+	type test struct {
+		latePolicy   string
+		at           time.Time
+		wantLateness time.Duration
+		wantErr      error
+	}
+
+	linkRepo, _, uscRepo := newRepo()
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{})
+
+	opensAt := time.Date(2019, time.July, 19, 8, 0, 0, 0, time.UTC)
+	deadline := time.Date(2019, time.July, 20, 0, 0, 0, 0, time.UTC)
+
+	tests := []test{
+		{latePolicy: domain.LatePolicyClose, at: opensAt.Add(-time.Second), wantErr: domain.ErrLinkNotYetOpen},
+		{latePolicy: domain.LatePolicyClose, at: opensAt, wantLateness: 0},
+		{latePolicy: domain.LatePolicyClose, at: deadline, wantLateness: 0},
+		{latePolicy: domain.LatePolicyClose, at: deadline.Add(time.Second), wantErr: domain.ErrLinkExpired},
+		{latePolicy: "", at: deadline.Add(time.Second), wantErr: domain.ErrLinkExpired},
+		{latePolicy: domain.LatePolicyGracePeriod, at: deadline.Add(30 * time.Minute), wantLateness: 30 * time.Minute},
+		{latePolicy: domain.LatePolicyGracePeriod, at: deadline.Add(31 * time.Minute), wantErr: domain.ErrLinkExpired},
+		{latePolicy: domain.LatePolicyAccept, at: deadline.Add(72 * time.Hour), wantLateness: 72 * time.Hour},
+	}
+
+	for i, tc := range tests {
+		l := &domain.Link{OpensAt: &opensAt, Deadline: &deadline, LatePolicy: tc.latePolicy, GracePeriodMinutes: 30}
+
+		gotLateness, gotErr := linkSvc.CheckUploadTime(l, tc.at)
+		assert.Equal(t, tc.wantErr, gotErr, "test %d", i)
+		assert.Equal(t, tc.wantLateness, gotLateness, "test %d", i)
+	}
+
+	// a link without opening time and deadline is always open
+	lateness, err := linkSvc.CheckUploadTime(&domain.Link{}, deadline)
+	assert.Nil(t, err)
+	assert.Equal(t, time.Duration(0), lateness)
+}
+
+func TestCreateLinkRequireVerifiedEmail(t *testing.T) {
+	linkRepo, userRepo, uscRepo := newRepo()
+	linkSvc := link.NewService(linkRepo, uscRepo, dummyHasher, link.Config{RequireVerifiedEmail: true})
+
+	user, _ := userRepo.FindByID(1)
+	_, err := linkSvc.CreateLink("Drop CV", "drop-cv", "", nil, nil, nil, user, nil, domain.LinkSettings{})
+	assert.Equal(t, domain.ErrUserEmailNotVerified, err)
+
+	user.EmailVerifiedAt = time2ptr(time.Now())
+	_, err = linkSvc.CreateLink("Drop CV", "drop-cv", "", nil, nil, nil, user, nil, domain.LinkSettings{})
+	assert.Nil(t, err)
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// NotificationService abstraction
+type NotificationService interface {
+	NotifyUpload(l *Link, s *Submission) error
+	SendReceipt(l *Link, s *Submission) error
+	SendDailyDigests() error
+	// SubscribeReminder emails the confirmation to the uploader, the reminders are sent after the confirmation
+	SubscribeReminder(l *Link, email, name string) error
+	ConfirmReminderSubscription(token string) error
+	SendDeadlineReminders(now time.Time) error
+}
@@ -0,0 +1,308 @@
+package notification
+
+import (
+	"bytes"
+	"fmt"
+	htmlTemplate "html/template"
+	"strings"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// Config of the notification service
+type Config struct {
+	MailerEmail string
+	MailerName  string
+	// ReminderOffsets is how long before the deadline the reminders are sent, e.g. 24h and 1h
+	ReminderOffsets []time.Duration
+	// ConfirmReminderWebURL is the page confirming the reminder subscription, the token is appended to it
+	ConfirmReminderWebURL string
+	// ConfirmationResendInterval is how long subscribing again waits before resending the confirmation email
+	ConfirmationResendInterval time.Duration
+}
+
+const defaultConfirmationResendInterval = time.Hour
+
+type service struct {
+	linkRepo        domain.LinkRepository
+	submissionRepo  domain.SubmissionRepository
+	reminderRepo    domain.ReminderRepository
+	userRepo        domain.UserRepository
+	mailer          domain.Mailer
+	stringGenerator domain.StringGenerator
+	htmlTemplates   *htmlTemplate.Template
+	textTemplates   *textTemplate.Template
+	config          Config
+}
+
+// uploadedFile is the template data of a submission
+type uploadedFile struct {
+	FileName      string
+	Size          string
+	UploaderName  string
+	UploaderEmail string
+	UploaderIP    string
+	UploadedAt    string
+}
+
+// NewService returns new service instance
+func NewService(
+	linkRepo domain.LinkRepository,
+	submissionRepo domain.SubmissionRepository,
+	reminderRepo domain.ReminderRepository,
+	userRepo domain.UserRepository,
+	mailer domain.Mailer,
+	stringGenerator domain.StringGenerator,
+	htmlTemplates *htmlTemplate.Template,
+	textTemplates *textTemplate.Template,
+	config Config,
+) domain.NotificationService {
+	if config.ConfirmationResendInterval <= 0 {
+		config.ConfirmationResendInterval = defaultConfirmationResendInterval
+	}
+
+	return &service{
+		linkRepo:        linkRepo,
+		submissionRepo:  submissionRepo,
+		reminderRepo:    reminderRepo,
+		userRepo:        userRepo,
+		mailer:          mailer,
+		stringGenerator: stringGenerator,
+		htmlTemplates:   htmlTemplates,
+		textTemplates:   textTemplates,
+		config:          config,
+	}
+}
+
+// NotifyUpload sends email to the link owner if the link notifies on every upload.
+// Submissions of links with daily notification are left for the digest
+func (s *service) NotifyUpload(l *domain.Link, sub *domain.Submission) error {
+	switch l.NotificationMode {
+	case domain.LinkNotificationDaily:
+		return nil
+
+	case domain.LinkNotificationImmediate:
+		err := s.sendNotification(
+			l,
+			fmt.Sprintf("New file uploaded to %s", l.Title),
+			[]domain.Submission{*sub},
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	// the submission is marked even when the owner does not want to be notified,
+	// so it is not sent later when the owner turns on the daily digest
+	return s.markNotified([]domain.Submission{*sub})
+}
+
+// SendDailyDigests sends the summary of the submissions which are not notified yet
+// to the owner of each link with daily notification. A failing link does not stop the others,
+// its submissions stay unnotified so they are included in the next digest
+func (s *service) SendDailyDigests() error {
+	links, err := s.linkRepo.ListByNotificationMode(domain.LinkNotificationDaily)
+	if err != nil {
+		return err
+	}
+
+	var errs errorList
+	for i := range links {
+		if err = s.sendDailyDigest(&links[i]); err != nil {
+			errs = append(errs, fmt.Errorf("link %d: %v", links[i].ID, err))
+		}
+	}
+
+	return errs.err()
+}
+
+func (s *service) sendDailyDigest(l *domain.Link) error {
+	subs, err := s.submissionRepo.ListUnnotifiedByLink(l.ID)
+	if err != nil {
+		return err
+	}
+
+	// failed uploads are not stored, so there is nothing to tell the owner
+	succeeded := make([]domain.Submission, 0, len(subs))
+	for _, sub := range subs {
+		if sub.Status == domain.SubmissionStatusSucceeded {
+			succeeded = append(succeeded, sub)
+		}
+	}
+
+	if len(succeeded) > 0 {
+		err = s.sendNotification(
+			l,
+			fmt.Sprintf("Daily summary: %d new file(s) uploaded to %s", len(succeeded), l.Title),
+			succeeded,
+		)
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.markNotified(subs)
+}
+
+func (s *service) markNotified(subs []domain.Submission) error {
+	now := time.Now()
+	for i := range subs {
+		subs[i].NotifiedAt = &now
+		if _, err := s.submissionRepo.Update(&subs[i]); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// SendReceipt sends the receipt of the submission to the uploader
+func (s *service) SendReceipt(l *domain.Link, sub *domain.Submission) error {
+	if sub.UploaderEmail == "" {
+		return nil
+	}
+
+	return s.send(
+		domain.MailAddress{
+			Address: sub.UploaderEmail,
+			Name:    sub.UploaderName,
+		},
+		fmt.Sprintf("Receipt of your upload to %s", l.Title),
+		"upload_receipt",
+		map[string]interface{}{
+			"UploaderName": sub.UploaderName,
+			"LinkTitle":    l.Title,
+			"ReceiptID":    sub.ReceiptID,
+			"SHA256":       sub.SHA256,
+			"File":         formatSubmission(*sub),
+		},
+	)
+}
+
+func (s *service) sendNotification(l *domain.Link, subject string, subs []domain.Submission) error {
+	owner := l.User
+	if owner == nil {
+		var err error
+		owner, err = s.userRepo.FindByID(l.UserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	// preparing template content
+	files := make([]uploadedFile, len(subs))
+	for i, sub := range subs {
+		files[i] = formatSubmission(sub)
+	}
+
+	return s.send(
+		domain.MailAddress{
+			Address: owner.Email,
+			Name:    owner.Name,
+		},
+		subject,
+		"upload_notification",
+		map[string]interface{}{
+			"OwnerName": owner.Name,
+			"LinkTitle": l.Title,
+			"LinkSlug":  l.Slug,
+			"Files":     files,
+		},
+	)
+}
+
+// send renders both html and text version of the template, the template names
+// are suffixed with _html and _text
+func (s *service) send(to domain.MailAddress, subject, templateName string, messageData map[string]interface{}) error {
+	// preparing template
+	htmlTmpl := s.htmlTemplates.Lookup(templateName + "_html")
+	if htmlTmpl == nil {
+		return domain.ErrTemplateNotFound
+	}
+
+	textTmpl := s.textTemplates.Lookup(templateName + "_text")
+	if textTmpl == nil {
+		return domain.ErrTemplateNotFound
+	}
+
+	// injecting data to template
+	htmlMessage := &bytes.Buffer{}
+	if err := htmlTmpl.Execute(htmlMessage, messageData); err != nil {
+		return err
+	}
+
+	textMessage := &bytes.Buffer{}
+	if err := textTmpl.Execute(textMessage, messageData); err != nil {
+		return err
+	}
+
+	from := domain.MailAddress{
+		Address: "admin@drophere.link",
+		Name:    "Drophere Bot",
+	}
+
+	if s.config.MailerEmail != "" {
+		from.Address = s.config.MailerEmail
+	}
+
+	if s.config.MailerName != "" {
+		from.Name = s.config.MailerName
+	}
+
+	// send email
+	return s.mailer.Send(
+		from,
+		to,
+		subject,
+		textMessage.String(),
+		htmlMessage.String(),
+	)
+}
+
+// errorList combines the errors of the links and the recipients which are skipped
+type errorList []error
+
+func (e errorList) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// err returns nil when there is no error
+func (e errorList) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+func formatSubmission(sub domain.Submission) uploadedFile {
+	return uploadedFile{
+		FileName:      sub.FileName,
+		Size:          formatSize(sub.Size),
+		UploaderName:  sub.UploaderName,
+		UploaderEmail: sub.UploaderEmail,
+		UploaderIP:    sub.UploaderIP,
+		UploadedAt:    sub.CreatedAt.Format("02 Jan 2006 15:04 MST"),
+	}
+}
+
+// formatSize formats the file size in human readable unit, e.g. 1.5 MB
+func formatSize(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(size)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,442 @@
+package notification_test
+
+import (
+	"errors"
+	htmlTemplate "html/template"
+	"testing"
+	textTemplate "text/template"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/domain/notification"
+	"github.com/bccfilkom/drophere-go/infrastructure/database/inmemory"
+	"github.com/bccfilkom/drophere-go/infrastructure/mailer"
+	"github.com/bccfilkom/drophere-go/infrastructure/stringgenerator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	htmlTemplates *htmlTemplate.Template
+	textTemplates *textTemplate.Template
+)
+
+func init() {
+	var err error
+
+	htmlTemplates, err = htmlTemplate.
+		New("upload_notification_html").
+		Parse("{{range .Files}}{{.FileName}};{{end}}")
+	if err == nil {
+		_, err = htmlTemplates.
+			New("upload_receipt_html").
+			Parse("{{.ReceiptID}}")
+	}
+	if err == nil {
+		_, err = htmlTemplates.
+			New("deadline_reminder_html").
+			Parse("{{.LinkTitle}}")
+	}
+	if err == nil {
+		_, err = htmlTemplates.
+			New("reminder_confirmation_html").
+			Parse("{{.LinkTitle}}")
+	}
+	if err != nil {
+		panic(err)
+	}
+
+	textTemplates, err = textTemplate.
+		New("upload_notification_text").
+		Parse("{{.OwnerName}}:{{range .Files}}{{.FileName}} ({{.Size}});{{end}}")
+	if err == nil {
+		_, err = textTemplates.
+			New("upload_receipt_text").
+			Parse("{{.ReceiptID}}:{{.File.FileName}}:{{.SHA256}}")
+	}
+	if err == nil {
+		_, err = textTemplates.
+			New("deadline_reminder_text").
+			Parse("{{.RecipientName}}:{{.TimeLeft}}:{{.IsOwner}}:{{.SubmissionCount}}")
+	}
+	if err == nil {
+		_, err = textTemplates.
+			New("reminder_confirmation_text").
+			Parse("{{.RecipientName}}:{{.ConfirmLink}}")
+	}
+	if err != nil {
+		panic(err)
+	}
+}
+
+func newService() (domain.NotificationService, domain.LinkRepository, domain.SubmissionRepository) {
+	return newServiceWithConfig(notification.Config{
+		ReminderOffsets:       []time.Duration{time.Hour, 24 * time.Hour},
+		ConfirmReminderWebURL: "http://localhost:3000/confirm-reminder",
+	})
+}
+
+func newServiceWithConfig(config notification.Config) (domain.NotificationService, domain.LinkRepository, domain.SubmissionRepository) {
+	memdb := inmemory.New()
+	linkRepo := inmemory.NewLinkRepository(memdb)
+	submissionRepo := inmemory.NewSubmissionRepository(memdb)
+
+	notificationSvc := notification.NewService(
+		linkRepo,
+		submissionRepo,
+		inmemory.NewReminderRepository(memdb),
+		inmemory.NewUserRepository(memdb),
+		mailer.NewMockMailer(),
+		stringgenerator.NewMock(),
+		htmlTemplates,
+		textTemplates,
+		config,
+	)
+
+	return notificationSvc, linkRepo, submissionRepo
+}
+
+func TestNotifyUpload(t *testing.T) {
+	type test struct {
+		notificationMode string
+		wantMessages     []mailer.MockMessage
+		wantNotified     bool
+	}
+
+	tests := []test{
+		{
+			notificationMode: domain.LinkNotificationImmediate,
+			wantMessages: []mailer.MockMessage{
+				{
+					From:         "admin@drophere.link",
+					To:           "user_357@drophere.link",
+					Title:        "New file uploaded to Another link",
+					MessagePlain: "User 357:essay.docx (1.5 MB);",
+					MessageHTML:  "essay.docx;",
+				},
+			},
+			wantNotified: true,
+		},
+		{
+			notificationMode: domain.LinkNotificationNone,
+			wantMessages:     []mailer.MockMessage{},
+			wantNotified:     true,
+		},
+		{
+			notificationMode: domain.LinkNotificationDaily,
+			wantMessages:     []mailer.MockMessage{},
+			wantNotified:     false,
+		},
+	}
+
+	for i, tc := range tests {
+		mailer.ClearMessages()
+		notificationSvc, linkRepo, submissionRepo := newService()
+
+		l, _ := linkRepo.FindByID(3)
+		l.NotificationMode = tc.notificationMode
+
+		sub, _ := submissionRepo.Create(&domain.Submission{
+			LinkID:    l.ID,
+			FileName:  "essay.docx",
+			Size:      1536 * 1024,
+			Status:    domain.SubmissionStatusSucceeded,
+			CreatedAt: time.Now(),
+		})
+
+		err := notificationSvc.NotifyUpload(l, sub)
+		assert.Nil(t, err, "test %d", i)
+		assert.Equal(t, tc.wantMessages, mailer.MockMessages, "test %d", i)
+
+		sub, _ = submissionRepo.FindByID(sub.ID)
+		assert.Equal(t, tc.wantNotified, sub.NotifiedAt != nil, "test %d", i)
+	}
+}
+
+func TestSendDailyDigests(t *testing.T) {
+	mailer.ClearMessages()
+	notificationSvc, linkRepo, submissionRepo := newService()
+
+	l, _ := linkRepo.FindByID(1)
+	l.NotificationMode = domain.LinkNotificationDaily
+	linkRepo.Update(l)
+
+	// failed uploads are not included in the digest
+	submissionRepo.Create(&domain.Submission{
+		LinkID:   1,
+		FileName: "broken.pdf",
+		Status:   domain.SubmissionStatusFailed,
+	})
+
+	err := notificationSvc.SendDailyDigests()
+	assert.Nil(t, err)
+	assert.Equal(t, []mailer.MockMessage{
+		{
+			From:         "admin@drophere.link",
+			To:           "user@drophere.link",
+			Title:        "Daily summary: 2 new file(s) uploaded to Drop file here",
+			MessagePlain: "User:report.pdf (1.0 KB);report (revised).pdf (2.0 KB);",
+			MessageHTML:  "report.pdf;report (revised).pdf;",
+		},
+	}, mailer.MockMessages)
+
+	subs, _ := submissionRepo.ListUnnotifiedByLink(1)
+	assert.Len(t, subs, 0)
+
+	// the notified submissions are not sent again
+	mailer.ClearMessages()
+	err = notificationSvc.SendDailyDigests()
+	assert.Nil(t, err)
+	assert.Len(t, mailer.MockMessages, 0)
+}
+
+func TestSendDailyDigestsContinuesAfterFailure(t *testing.T) {
+	mailer.ClearMessages()
+	notificationSvc, linkRepo, submissionRepo := newService()
+
+	for _, id := range []uint{1, 3} {
+		l, _ := linkRepo.FindByID(id)
+		l.NotificationMode = domain.LinkNotificationDaily
+		linkRepo.Update(l)
+	}
+	submissionRepo.Create(&domain.Submission{
+		LinkID:   3,
+		FileName: "essay.pdf",
+		Status:   domain.SubmissionStatusSucceeded,
+	})
+
+	mailer.SetMockError("user@drophere.link", errors.New("mailbox unavailable"))
+	defer mailer.SetMockError("user@drophere.link", nil)
+
+	err := notificationSvc.SendDailyDigests()
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "mailbox unavailable")
+	}
+
+	// the failed link does not stop the others, and its submissions are sent later
+	if assert.Len(t, mailer.MockMessages, 1) {
+		assert.Equal(t, "user_357@drophere.link", mailer.MockMessages[0].To)
+	}
+	subs, _ := submissionRepo.ListUnnotifiedByLink(1)
+	assert.Len(t, subs, 2)
+	subs, _ = submissionRepo.ListUnnotifiedByLink(3)
+	assert.Len(t, subs, 0)
+
+	mailer.SetMockError("user@drophere.link", nil)
+	mailer.ClearMessages()
+	err = notificationSvc.SendDailyDigests()
+	assert.Nil(t, err)
+	if assert.Len(t, mailer.MockMessages, 1) {
+		assert.Equal(t, "user@drophere.link", mailer.MockMessages[0].To)
+	}
+	subs, _ = submissionRepo.ListUnnotifiedByLink(1)
+	assert.Len(t, subs, 0)
+}
+
+func TestSendReceipt(t *testing.T) {
+	mailer.ClearMessages()
+	notificationSvc, linkRepo, submissionRepo := newService()
+
+	l, _ := linkRepo.FindByID(1)
+
+	// the receipt is not sent without the uploader's email
+	sub, _ := submissionRepo.FindByID(1)
+	err := notificationSvc.SendReceipt(l, sub)
+	assert.Nil(t, err)
+	assert.Len(t, mailer.MockMessages, 0)
+
+	sub, _ = submissionRepo.FindByID(2)
+	sub.ReceiptID = "receipt_2"
+	sub.SHA256 = "0123abcd"
+	err = notificationSvc.SendReceipt(l, sub)
+	assert.Nil(t, err)
+	assert.Equal(t, []mailer.MockMessage{
+		{
+			From:         "admin@drophere.link",
+			To:           "user_357@drophere.link",
+			Title:        "Receipt of your upload to Drop file here",
+			MessagePlain: "receipt_2:report (revised).pdf:0123abcd",
+			MessageHTML:  "receipt_2",
+		},
+	}, mailer.MockMessages)
+}
+
+func TestSubscribeReminder(t *testing.T) {
+	mailer.ClearMessages()
+	defer stringgenerator.SetMockResult("this_is_not_a_random_string")
+	notificationSvc, linkRepo, _ := newService()
+
+	l, _ := linkRepo.FindByID(1)
+
+	err := notificationSvc.SubscribeReminder(l, "alice@example.com", "Alice")
+	assert.Equal(t, domain.ErrReminderNoUpcomingDeadline, err)
+
+	passed := time.Now().Add(-time.Hour)
+	l.Deadline = &passed
+	err = notificationSvc.SubscribeReminder(l, "alice@example.com", "Alice")
+	assert.Equal(t, domain.ErrReminderNoUpcomingDeadline, err)
+
+	upcoming := time.Now().Add(time.Hour)
+	l.Deadline = &upcoming
+	err = notificationSvc.SubscribeReminder(l, "Alice <alice@example.com>", "Alice")
+	assert.Equal(t, domain.ErrReminderInvalidEmail, err)
+
+	stringgenerator.SetMockResult("confirmation_token")
+	err = notificationSvc.SubscribeReminder(l, " alice@example.com ", "Alice")
+	assert.Nil(t, err)
+
+	// subscribing again right away does not resend the email
+	err = notificationSvc.SubscribeReminder(l, "Alice@Example.com", "Alice")
+	assert.Nil(t, err)
+	assert.Equal(t, []mailer.MockMessage{
+		{
+			From:         "admin@drophere.link",
+			To:           "alice@example.com",
+			Title:        "Confirm the reminders of Drop file here",
+			MessagePlain: "Alice:http://localhost:3000/confirm-reminder?token=confirmation_token",
+			MessageHTML:  "Drop file here",
+		},
+	}, mailer.MockMessages)
+
+	err = notificationSvc.ConfirmReminderSubscription("unknown_token")
+	assert.Equal(t, domain.ErrReminderSubscriptionNotFound, err)
+
+	err = notificationSvc.ConfirmReminderSubscription("confirmation_token")
+	assert.Nil(t, err)
+
+	// the token can only be used once
+	err = notificationSvc.ConfirmReminderSubscription("confirmation_token")
+	assert.Equal(t, domain.ErrReminderSubscriptionNotFound, err)
+
+	// subscribing after the confirmation does nothing
+	mailer.ClearMessages()
+	err = notificationSvc.SubscribeReminder(l, "alice@example.com", "Alice")
+	assert.Nil(t, err)
+	assert.Len(t, mailer.MockMessages, 0)
+}
+
+func TestSubscribeReminderResendsAfterInterval(t *testing.T) {
+	mailer.ClearMessages()
+	notificationSvc, linkRepo, _ := newServiceWithConfig(notification.Config{
+		ConfirmationResendInterval: 10 * time.Millisecond,
+	})
+
+	l, _ := linkRepo.FindByID(1)
+	upcoming := time.Now().Add(time.Hour)
+	l.Deadline = &upcoming
+
+	assert.Nil(t, notificationSvc.SubscribeReminder(l, "alice@example.com", "Alice"))
+	assert.Nil(t, notificationSvc.SubscribeReminder(l, "alice@example.com", "Alice"))
+	assert.Len(t, mailer.MockMessages, 1)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Nil(t, notificationSvc.SubscribeReminder(l, "alice@example.com", "Alice"))
+	assert.Len(t, mailer.MockMessages, 2)
+}
+
+// subscribe subscribes the email to the link's reminders and confirms it
+func subscribe(notificationSvc domain.NotificationService, l *domain.Link, email, name string) {
+	stringgenerator.SetMockResult("token_" + email)
+	notificationSvc.SubscribeReminder(l, email, name)
+	notificationSvc.ConfirmReminderSubscription("token_" + email)
+}
+
+func TestSendDeadlineReminders(t *testing.T) {
+	defer stringgenerator.SetMockResult("this_is_not_a_random_string")
+	notificationSvc, linkRepo, _ := newService()
+
+	now := time.Now()
+
+	deadline := now.Add(23*time.Hour + 30*time.Minute)
+	l, _ := linkRepo.FindByID(1)
+	l.Deadline = &deadline
+	linkRepo.Update(l)
+
+	subscribe(notificationSvc, l, "alice@example.com", "Alice")
+	// the uploader who has submitted is not reminded
+	subscribe(notificationSvc, l, "user_357@drophere.link", "User 357")
+	// the unconfirmed subscription is not reminded
+	stringgenerator.SetMockResult("token_bob")
+	notificationSvc.SubscribeReminder(l, "bob@example.com", "Bob")
+
+	// the deadlines beyond the largest offset and the passed deadlines are not reminded yet
+	later := now.Add(48 * time.Hour)
+	l, _ = linkRepo.FindByID(3)
+	l.Deadline = &later
+	linkRepo.Update(l)
+
+	passed := now.Add(-time.Hour)
+	l, _ = linkRepo.FindByID(2)
+	l.Deadline = &passed
+	linkRepo.Update(l)
+
+	mailer.ClearMessages()
+	err := notificationSvc.SendDeadlineReminders(now)
+	assert.Nil(t, err)
+	assert.Equal(t, []mailer.MockMessage{
+		{
+			From:         "admin@drophere.link",
+			To:           "user@drophere.link",
+			Title:        "Reminder: Drop file here closes in 24 hours",
+			MessagePlain: "User:24 hours:true:2",
+			MessageHTML:  "Drop file here",
+		},
+		{
+			From:         "admin@drophere.link",
+			To:           "alice@example.com",
+			Title:        "Reminder: Drop file here closes in 24 hours",
+			MessagePlain: "Alice:24 hours:false:2",
+			MessageHTML:  "Drop file here",
+		},
+	}, mailer.MockMessages)
+
+	// the sent reminders are not sent again
+	mailer.ClearMessages()
+	err = notificationSvc.SendDeadlineReminders(now.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Len(t, mailer.MockMessages, 0)
+
+	// the next offset is reminded
+	err = notificationSvc.SendDeadlineReminders(deadline.Add(-50 * time.Minute))
+	assert.Nil(t, err)
+	assert.Len(t, mailer.MockMessages, 2)
+	if assert.NotEmpty(t, mailer.MockMessages) {
+		assert.Equal(t, "Reminder: Drop file here closes in 1 hour", mailer.MockMessages[0].Title)
+	}
+}
+
+func TestSendDeadlineRemindersContinuesAfterFailure(t *testing.T) {
+	defer stringgenerator.SetMockResult("this_is_not_a_random_string")
+	notificationSvc, linkRepo, _ := newService()
+
+	now := time.Now()
+
+	deadline := now.Add(30 * time.Minute)
+	l, _ := linkRepo.FindByID(1)
+	l.Deadline = &deadline
+	linkRepo.Update(l)
+
+	subscribe(notificationSvc, l, "alice@example.com", "Alice")
+
+	mailer.SetMockError("user@drophere.link", errors.New("mailbox unavailable"))
+	defer mailer.SetMockError("user@drophere.link", nil)
+
+	mailer.ClearMessages()
+	err := notificationSvc.SendDeadlineReminders(now)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "mailbox unavailable")
+	}
+
+	// the failed reminder does not stop the others, and it is retried later
+	if assert.Len(t, mailer.MockMessages, 1) {
+		assert.Equal(t, "alice@example.com", mailer.MockMessages[0].To)
+	}
+
+	mailer.SetMockError("user@drophere.link", nil)
+	mailer.ClearMessages()
+	err = notificationSvc.SendDeadlineReminders(now.Add(time.Minute))
+	assert.Nil(t, err)
+	if assert.Len(t, mailer.MockMessages, 1) {
+		assert.Equal(t, "user@drophere.link", mailer.MockMessages[0].To)
+	}
+}
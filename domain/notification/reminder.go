@@ -0,0 +1,280 @@
+package notification
+
+import (
+	"fmt"
+	"log"
+	"net/mail"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// reminderRecipient is the owner or an uploader who subscribed to the link's reminders
+type reminderRecipient struct {
+	Email   string
+	Name    string
+	IsOwner bool
+}
+
+// SubscribeReminder lets an uploader be reminded before the link's deadline once the subscription
+// is confirmed from the email. Subscribing again before the confirmation resends the email,
+// at most once per ConfirmationResendInterval so the address can not be flooded
+func (s *service) SubscribeReminder(l *domain.Link, email, name string) error {
+	email = strings.TrimSpace(email)
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email {
+		return domain.ErrReminderInvalidEmail
+	}
+
+	if l.Deadline == nil || !l.Deadline.After(time.Now()) {
+		return domain.ErrReminderNoUpcomingDeadline
+	}
+
+	subscriptions, err := s.reminderRepo.ListSubscriptionsByLink(l.ID)
+	if err != nil {
+		return err
+	}
+
+	var subscription *domain.ReminderSubscription
+	for i := range subscriptions {
+		if strings.EqualFold(subscriptions[i].Email, email) {
+			subscription = &subscriptions[i]
+			break
+		}
+	}
+
+	now := time.Now()
+	switch {
+	case subscription == nil:
+		token := s.stringGenerator.Generate()
+		subscription, err = s.reminderRepo.CreateSubscription(&domain.ReminderSubscription{
+			LinkID:             l.ID,
+			Email:              email,
+			Name:               strings.TrimSpace(name),
+			ConfirmationToken:  &token,
+			ConfirmationSentAt: &now,
+			CreatedAt:          now,
+		})
+
+	case subscription.IsConfirmed():
+		return nil
+
+	case subscription.ConfirmationToken == nil:
+		token := s.stringGenerator.Generate()
+		subscription.ConfirmationToken = &token
+		subscription.ConfirmationSentAt = &now
+		subscription, err = s.reminderRepo.UpdateSubscription(subscription)
+
+	case subscription.ConfirmationSentAt != nil && now.Sub(*subscription.ConfirmationSentAt) < s.config.ConfirmationResendInterval:
+		// the email has been sent recently, the caller is not told so the subscriptions stay private
+		return nil
+
+	default:
+		subscription.ConfirmationSentAt = &now
+		subscription, err = s.reminderRepo.UpdateSubscription(subscription)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.send(
+		domain.MailAddress{
+			Address: subscription.Email,
+			Name:    subscription.Name,
+		},
+		fmt.Sprintf("Confirm the reminders of %s", l.Title),
+		"reminder_confirmation",
+		map[string]interface{}{
+			"RecipientName": subscription.Name,
+			"LinkTitle":     l.Title,
+			"Deadline":      l.Deadline.Format("02 Jan 2006 15:04 MST"),
+			"Token":         *subscription.ConfirmationToken,
+			"ConfirmLink": fmt.Sprintf(
+				"%s?token=%s",
+				s.config.ConfirmReminderWebURL,
+				url.QueryEscape(*subscription.ConfirmationToken),
+			),
+		},
+	)
+}
+
+// ConfirmReminderSubscription confirms the subscription with the token from the confirmation email
+func (s *service) ConfirmReminderSubscription(token string) error {
+	if token == "" {
+		return domain.ErrReminderSubscriptionNotFound
+	}
+
+	subscription, err := s.reminderRepo.FindSubscriptionByConfirmationToken(token)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	subscription.ConfirmationToken = nil
+	subscription.ConfirmedAt = &now
+
+	_, err = s.reminderRepo.UpdateSubscription(subscription)
+	return err
+}
+
+// SendDeadlineReminders reminds the owner and the subscribed uploaders of the links whose deadline
+// is within the configured offsets. Only the reminder of the nearest offset is sent, so a link
+// created an hour before its deadline does not get the day-before reminder. Uploaders who have
+// submitted are not reminded. The sent reminders are recorded to avoid sending them twice.
+// A failed reminder does not stop the others, the errors are logged and returned together
+func (s *service) SendDeadlineReminders(now time.Time) error {
+	if len(s.config.ReminderOffsets) < 1 {
+		return nil
+	}
+
+	offsets := append([]time.Duration{}, s.config.ReminderOffsets...)
+	sort.Slice(offsets, func(i, j int) bool { return offsets[i] < offsets[j] })
+
+	links, err := s.linkRepo.ListByDeadlineBetween(now, now.Add(offsets[len(offsets)-1]))
+	if err != nil {
+		return err
+	}
+
+	var errs errorList
+	for i := range links {
+		l := &links[i]
+		timeLeft := l.Deadline.Sub(now)
+
+		offset := offsets[sort.Search(len(offsets), func(i int) bool { return offsets[i] >= timeLeft })]
+		errs = append(errs, s.sendDeadlineReminder(l, int(offset/time.Minute), timeLeft)...)
+	}
+
+	for _, err := range errs {
+		log.Println("deadline reminder: ", err)
+	}
+
+	return errs.err()
+}
+
+func (s *service) sendDeadlineReminder(l *domain.Link, offsetMinutes int, timeLeft time.Duration) errorList {
+	sentReminders, err := s.reminderRepo.ListSentRemindersByLink(l.ID)
+	if err != nil {
+		return errorList{fmt.Errorf("link %d: %v", l.ID, err)}
+	}
+
+	sent := make(map[string]bool, len(sentReminders))
+	for _, r := range sentReminders {
+		if r.OffsetMinutes == offsetMinutes {
+			sent[strings.ToLower(r.Email)] = true
+		}
+	}
+
+	recipients, submissionCount, err := s.reminderRecipients(l)
+	if err != nil {
+		return errorList{fmt.Errorf("link %d: %v", l.ID, err)}
+	}
+
+	var errs errorList
+	for _, recipient := range recipients {
+		if sent[strings.ToLower(recipient.Email)] {
+			continue
+		}
+
+		err = s.send(
+			domain.MailAddress{
+				Address: recipient.Email,
+				Name:    recipient.Name,
+			},
+			fmt.Sprintf("Reminder: %s closes in %s", l.Title, formatTimeLeft(timeLeft)),
+			"deadline_reminder",
+			map[string]interface{}{
+				"RecipientName":   recipient.Name,
+				"IsOwner":         recipient.IsOwner,
+				"LinkTitle":       l.Title,
+				"LinkSlug":        l.Slug,
+				"Deadline":        l.Deadline.Format("02 Jan 2006 15:04 MST"),
+				"TimeLeft":        formatTimeLeft(timeLeft),
+				"SubmissionCount": submissionCount,
+			},
+		)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("link %d: %s: %v", l.ID, recipient.Email, err))
+			continue
+		}
+
+		_, err = s.reminderRepo.CreateSentReminder(&domain.SentReminder{
+			LinkID:        l.ID,
+			OffsetMinutes: offsetMinutes,
+			Email:         recipient.Email,
+			SentAt:        time.Now(),
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("link %d: %s: %v", l.ID, recipient.Email, err))
+		}
+	}
+
+	return errs
+}
+
+// reminderRecipients returns the owner and the confirmed subscribers who have not submitted,
+// along with the number of succeeded submissions of the link
+func (s *service) reminderRecipients(l *domain.Link) ([]reminderRecipient, int, error) {
+	owner := l.User
+	if owner == nil {
+		var err error
+		owner, err = s.userRepo.FindByID(l.UserID)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	subs, err := s.submissionRepo.ListByLink(l.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	submissionCount := 0
+	submitted := make(map[string]bool, len(subs))
+	for _, sub := range subs {
+		if sub.Status == domain.SubmissionStatusSucceeded {
+			submissionCount++
+			submitted[strings.ToLower(sub.UploaderEmail)] = true
+		}
+	}
+
+	subscriptions, err := s.reminderRepo.ListSubscriptionsByLink(l.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	recipients := []reminderRecipient{{Email: owner.Email, Name: owner.Name, IsOwner: true}}
+	for _, subscription := range subscriptions {
+		if subscription.IsConfirmed() && !submitted[strings.ToLower(subscription.Email)] && !strings.EqualFold(subscription.Email, owner.Email) {
+			recipients = append(recipients, reminderRecipient{Email: subscription.Email, Name: subscription.Name})
+		}
+	}
+
+	return recipients, submissionCount, nil
+}
+
+// formatTimeLeft formats the duration in the largest whole unit, e.g. 3 hours
+func formatTimeLeft(d time.Duration) string {
+	hours := int((d + time.Hour/2) / time.Hour)
+	switch {
+	case hours >= 48:
+		return pluralize(int((d+12*time.Hour)/(24*time.Hour)), "day")
+	case hours >= 1:
+		return pluralize(hours, "hour")
+	}
+
+	minutes := int((d + time.Minute/2) / time.Minute)
+	if minutes < 1 {
+		minutes = 1
+	}
+	return pluralize(minutes, "minute")
+}
+
+func pluralize(n int, unit string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, unit)
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
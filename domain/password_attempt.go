@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// TooManyAttemptsError is returned while the client is locked out after too many wrong passwords
+type TooManyAttemptsError struct {
+	RetryAfter time.Duration
+}
+
+// RetryAfterSeconds rounds the lockout up to whole seconds
+func (e *TooManyAttemptsError) RetryAfterSeconds() int {
+	return int((e.RetryAfter + time.Second - 1) / time.Second)
+}
+
+func (e *TooManyAttemptsError) Error() string {
+	return fmt.Sprintf("Too many failed password attempts, try again in %d seconds", e.RetryAfterSeconds())
+}
+
+// Extensions are included in the GraphQL error
+func (e *TooManyAttemptsError) Extensions() map[string]interface{} {
+	return map[string]interface{}{
+		"code":       "TOO_MANY_ATTEMPTS",
+		"retryAfter": e.RetryAfterSeconds(),
+	}
+}
+
+// PasswordAttempts counts the password attempts of a client which have not succeeded
+type PasswordAttempts struct {
+	// Count includes the current attempt
+	Count int
+	// PreviousAttemptAt is the time of the attempt before the current one, it is zero for the first attempt
+	PreviousAttemptAt time.Time
+}
+
+// PasswordAttemptStore keeps the attempts until they expire
+type PasswordAttemptStore interface {
+	// Increment adds delta to the attempts of the key and returns them, a positive delta also
+	// renews their expiry. It is atomic, so the concurrent attempts are all counted
+	Increment(key string, delta int, now time.Time, ttl time.Duration) (PasswordAttempts, error)
+	Delete(key string) error
+}
+
+// PasswordAttemptService abstraction
+type PasswordAttemptService interface {
+	// Attempt counts the attempt before the password is checked,
+	// it returns TooManyAttemptsError while the client is locked out
+	Attempt(l *Link, clientIP string, now time.Time) error
+	// RecordSuccess forgets the attempts of the client after the correct password
+	RecordSuccess(l *Link, clientIP string) error
+}
@@ -0,0 +1,99 @@
+package passwordattempt
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+const (
+	defaultMaxAttempts = 5
+	defaultWindow      = 15 * time.Minute
+	defaultBaseLockout = 30 * time.Second
+	defaultMaxLockout  = time.Hour
+)
+
+// Config holds the lockout policy
+type Config struct {
+	// MaxAttempts is the number of wrong passwords allowed before the client is locked out
+	MaxAttempts int
+	// Window is how long the wrong passwords are remembered after the last one, on top of MaxLockout
+	Window time.Duration
+	// BaseLockout is the first lockout, it doubles on each wrong password afterward
+	BaseLockout time.Duration
+	// MaxLockout caps the lockout
+	MaxLockout time.Duration
+}
+
+type service struct {
+	store  domain.PasswordAttemptStore
+	config Config
+}
+
+// NewService returns new service instance, zero config values are replaced with the defaults
+func NewService(store domain.PasswordAttemptStore, config Config) domain.PasswordAttemptService {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.Window <= 0 {
+		config.Window = defaultWindow
+	}
+	if config.BaseLockout <= 0 {
+		config.BaseLockout = defaultBaseLockout
+	}
+	if config.MaxLockout <= 0 {
+		config.MaxLockout = defaultMaxLockout
+	}
+
+	return &service{
+		store:  store,
+		config: config,
+	}
+}
+
+// clientKey identifies the attempts of the client on the link
+func clientKey(l *domain.Link, clientIP string) string {
+	return fmt.Sprintf("link:%d:%s", l.ID, clientIP)
+}
+
+// Attempt counts the attempt of the client. Once the maximum attempts is reached, the client is
+// locked out and every further attempt doubles the lockout, so the attempts made while locked out
+// keep it locked out. The link itself is never locked out, since the wrong passwords of some
+// clients would refuse the others with the correct password
+func (s *service) Attempt(l *domain.Link, clientIP string, now time.Time) error {
+	a, err := s.store.Increment(clientKey(l, clientIP), 1, now, s.ttl())
+	if err != nil {
+		return err
+	}
+
+	// the attempts before the current one are the wrong passwords
+	excess := a.Count - 1 - s.config.MaxAttempts
+	if excess < 0 {
+		return nil
+	}
+
+	lockout := s.config.MaxLockout
+	// the shift is bounded to avoid overflow
+	if excess < 32 {
+		if doubled := s.config.BaseLockout << uint(excess); doubled > 0 && doubled < lockout {
+			lockout = doubled
+		}
+	}
+
+	if lockedUntil := a.PreviousAttemptAt.Add(lockout); now.Before(lockedUntil) {
+		return &domain.TooManyAttemptsError{RetryAfter: lockedUntil.Sub(now)}
+	}
+
+	return nil
+}
+
+// ttl keeps the attempts through the longest lockout
+func (s *service) ttl() time.Duration {
+	return s.config.Window + s.config.MaxLockout
+}
+
+// RecordSuccess forgets the wrong passwords of the client
+func (s *service) RecordSuccess(l *domain.Link, clientIP string) error {
+	return s.store.Delete(clientKey(l, clientIP))
+}
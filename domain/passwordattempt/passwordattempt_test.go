@@ -0,0 +1,92 @@
+package passwordattempt_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/domain/passwordattempt"
+	"github.com/bccfilkom/drophere-go/infrastructure/attemptstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newService() domain.PasswordAttemptService {
+	return passwordattempt.NewService(attemptstore.NewMemory(), passwordattempt.Config{
+		MaxAttempts: 3,
+		Window:      15 * time.Minute,
+		BaseLockout: 30 * time.Second,
+		MaxLockout:  2 * time.Minute,
+	})
+}
+
+func TestLockout(t *testing.T) {
+	attemptSvc := newService()
+
+	l := &domain.Link{ID: 1}
+	now := time.Now()
+
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, attemptSvc.Attempt(l, "10.0.0.1", now), "attempt %d", i)
+	}
+
+	// the client is locked out after the third wrong password
+	assert.Equal(t, &domain.TooManyAttemptsError{RetryAfter: 30 * time.Second}, attemptSvc.Attempt(l, "10.0.0.1", now))
+
+	// other clients and other links are not locked out
+	assert.Nil(t, attemptSvc.Attempt(l, "10.0.0.2", now))
+	assert.Nil(t, attemptSvc.Attempt(&domain.Link{ID: 2}, "10.0.0.1", now))
+
+	// the lockout doubles on each attempt afterward, up to the maximum
+	now = now.Add(30 * time.Second)
+	assert.Equal(t, &domain.TooManyAttemptsError{RetryAfter: 30 * time.Second}, attemptSvc.Attempt(l, "10.0.0.1", now))
+
+	now = now.Add(2 * time.Minute)
+	assert.Nil(t, attemptSvc.Attempt(l, "10.0.0.1", now))
+	assert.Equal(t, &domain.TooManyAttemptsError{RetryAfter: 2 * time.Minute}, attemptSvc.Attempt(l, "10.0.0.1", now))
+
+	// the correct password resets the attempts
+	assert.Nil(t, attemptSvc.RecordSuccess(l, "10.0.0.1"))
+	assert.Nil(t, attemptSvc.Attempt(l, "10.0.0.1", now))
+}
+
+func TestLinkIsNotLockedOut(t *testing.T) {
+	attemptSvc := newService()
+
+	l := &domain.Link{ID: 1}
+	now := time.Now()
+
+	// many wrong passwords from many addresses, each client up to its lockout
+	for i := 0; i < 50; i++ {
+		for j := 0; j < 4; j++ {
+			attemptSvc.Attempt(l, fmt.Sprintf("10.0.0.%d", i), now)
+		}
+	}
+
+	// the other clients still enter the correct password
+	assert.Nil(t, attemptSvc.Attempt(l, "10.0.1.1", now))
+	assert.Nil(t, attemptSvc.RecordSuccess(l, "10.0.1.1"))
+	assert.Nil(t, attemptSvc.Attempt(l, "10.0.1.1", now))
+}
+
+func TestFailuresAreForgottenAfterWindow(t *testing.T) {
+	attemptSvc := newService()
+
+	l := &domain.Link{ID: 1}
+	now := time.Now()
+
+	attemptSvc.Attempt(l, "10.0.0.1", now)
+	attemptSvc.Attempt(l, "10.0.0.1", now)
+
+	// the failures before the window and the longest lockout are not counted
+	now = now.Add(18 * time.Minute)
+	attemptSvc.Attempt(l, "10.0.0.1", now)
+	assert.Nil(t, attemptSvc.Attempt(l, "10.0.0.1", now))
+}
+
+func TestTooManyAttemptsError(t *testing.T) {
+	err := &domain.TooManyAttemptsError{RetryAfter: 1500 * time.Millisecond}
+	assert.Equal(t, "Too many failed password attempts, try again in 2 seconds", err.Error())
+	assert.Equal(t, map[string]interface{}{"code": "TOO_MANY_ATTEMPTS", "retryAfter": 2}, err.Extensions())
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrReminderInvalidEmail error
+	ErrReminderInvalidEmail = errors.New("Invalid email address")
+	// ErrReminderNoUpcomingDeadline error
+	ErrReminderNoUpcomingDeadline = errors.New("The link has no upcoming deadline")
+	// ErrReminderSubscriptionNotFound error
+	ErrReminderSubscriptionNotFound = errors.New("Reminder subscription not found")
+)
+
+// ReminderSubscription is an uploader who wants to be reminded before the link's deadline
+type ReminderSubscription struct {
+	ID     uint
+	LinkID uint
+	Email  string
+	Name   string
+	// ConfirmationToken is sent to the email, no reminder is sent until the subscription is confirmed
+	ConfirmationToken *string
+	// ConfirmationSentAt limits how often the confirmation email is resent
+	ConfirmationSentAt *time.Time
+	ConfirmedAt        *time.Time
+	CreatedAt          time.Time
+}
+
+// IsConfirmed checks whether the uploader has confirmed the subscription from the email
+func (s *ReminderSubscription) IsConfirmed() bool {
+	return s.ConfirmedAt != nil
+}
+
+// SentReminder records a reminder sent to a recipient, so it is not sent again
+type SentReminder struct {
+	ID     uint
+	LinkID uint
+	// OffsetMinutes is how long before the deadline the reminder is scheduled
+	OffsetMinutes int
+	Email         string
+	SentAt        time.Time
+}
+
+// ReminderRepository abstraction
+type ReminderRepository interface {
+	CreateSubscription(s *ReminderSubscription) (*ReminderSubscription, error)
+	ListSubscriptionsByLink(linkID uint) ([]ReminderSubscription, error)
+	FindSubscriptionByConfirmationToken(token string) (*ReminderSubscription, error)
+	UpdateSubscription(s *ReminderSubscription) (*ReminderSubscription, error)
+	CreateSentReminder(r *SentReminder) (*SentReminder, error)
+	ListSentRemindersByLink(linkID uint) ([]SentReminder, error)
+}
@@ -0,0 +1,45 @@
+package domain
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrResumableUploadNotFound error
+	ErrResumableUploadNotFound = errors.New("Upload not found")
+	// ErrResumableUploadOffsetMismatch error
+	ErrResumableUploadOffsetMismatch = errors.New("Upload offset does not match")
+	// ErrResumableUploadFinishing error
+	ErrResumableUploadFinishing = errors.New("Upload is already being finished")
+)
+
+// ResumableUpload stores the state of a file which is uploaded in several requests
+type ResumableUpload struct {
+	ID        string
+	Length    int64
+	Offset    int64
+	Metadata  map[string]string
+	CreatedAt time.Time
+}
+
+// IsComplete checks if every byte of the file has been received
+func (u *ResumableUpload) IsComplete() bool {
+	return u.Offset >= u.Length
+}
+
+// ResumableUploadStore keeps partially uploaded files until they are complete
+type ResumableUploadStore interface {
+	Create(u ResumableUpload) (ResumableUpload, error)
+	Find(id string) (ResumableUpload, error)
+	WriteChunk(id string, offset int64, chunk io.Reader) (ResumableUpload, error)
+	Open(id string) (io.ReadSeekCloser, error)
+	Delete(id string) error
+	// BeginFinish marks the complete upload as being forwarded to the storage provider,
+	// it fails with ErrResumableUploadFinishing when another request is already finishing it
+	BeginFinish(id string) (ResumableUpload, error)
+	// EndFinish allows finishing the upload again, e.g. after the storage provider failed
+	EndFinish(id string)
+	DeleteCreatedBefore(t time.Time) error
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrRosterInvalidCSV error
+	ErrRosterInvalidCSV = errors.New("Invalid roster CSV")
+	// ErrRosterDuplicatedIdentifier error
+	ErrRosterDuplicatedIdentifier = errors.New("Duplicated identifier in the roster")
+	// ErrRosterIdentifierRequired error
+	ErrRosterIdentifierRequired = errors.New("Identifier is required")
+	// ErrRosterIdentifierNotFound error
+	ErrRosterIdentifierNotFound = errors.New("Identifier is not in the roster")
+)
+
+const (
+	// RosterStatusSubmitted marks a person who uploaded a file before the deadline
+	RosterStatusSubmitted = "submitted"
+	// RosterStatusLate marks a person who only uploaded files after the deadline
+	RosterStatusLate = "late"
+	// RosterStatusMissing marks a person who has not uploaded any file
+	RosterStatusMissing = "missing"
+)
+
+// RosterEntry is a person expected to upload to a link
+type RosterEntry struct {
+	ID     uint
+	LinkID uint
+	// Identifier is matched against the identifier entered by the uploader, e.g. student ID.
+	// The email is used as identifier when the roster does not have one
+	Identifier string
+	Name       string
+	Email      string
+}
+
+// RosterEntryStatus tells whether the person in the roster has uploaded to the link
+type RosterEntryStatus struct {
+	Entry  RosterEntry
+	Status string
+	// SubmittedAt is the time of the latest succeeded submission
+	SubmittedAt     *time.Time
+	SubmissionCount int
+}
+
+// RosterService abstraction
+type RosterService interface {
+	ImportRoster(l *Link, csv io.Reader) ([]RosterEntry, error)
+	ListRoster(linkID uint) ([]RosterEntry, error)
+	MatchIdentifier(l *Link, identifier string) (string, error)
+	RosterStatus(l *Link) ([]RosterEntryStatus, error)
+	ExportRosterStatus(l *Link, w io.Writer) error
+}
+
+// RosterRepository abstraction
+type RosterRepository interface {
+	ListByLink(linkID uint) ([]RosterEntry, error)
+	ReplaceByLink(linkID uint, entries []RosterEntry) ([]RosterEntry, error)
+}
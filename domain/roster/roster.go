@@ -0,0 +1,260 @@
+package roster
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// headerColumns maps the accepted CSV header names to the roster entry fields
+var headerColumns = map[string]string{
+	"identifier": "identifier",
+	"id":         "identifier",
+	"nim":        "identifier",
+	"student id": "identifier",
+	"student_id": "identifier",
+	"name":       "name",
+	"email":      "email",
+	"e-mail":     "email",
+}
+
+type service struct {
+	rosterRepo     domain.RosterRepository
+	submissionRepo domain.SubmissionRepository
+}
+
+// NewService returns new service instance
+func NewService(rosterRepo domain.RosterRepository, submissionRepo domain.SubmissionRepository) domain.RosterService {
+	return &service{
+		rosterRepo:     rosterRepo,
+		submissionRepo: submissionRepo,
+	}
+}
+
+// ImportRoster replaces the roster of the link with the entries in the CSV. The columns are
+// read from the header row (identifier, name and email), or in that order when there is no header.
+// An empty CSV removes the roster
+func (s *service) ImportRoster(l *domain.Link, r io.Reader) ([]domain.RosterEntry, error) {
+	entries, err := parseRosterCSV(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range entries {
+		entries[i].LinkID = l.ID
+	}
+
+	return s.rosterRepo.ReplaceByLink(l.ID, entries)
+}
+
+// ListRoster returns the roster of a link
+func (s *service) ListRoster(linkID uint) ([]domain.RosterEntry, error) {
+	return s.rosterRepo.ListByLink(linkID)
+}
+
+// MatchIdentifier finds the roster entry of the identifier entered by the uploader, either by
+// its identifier or email, and returns the entry's identifier. Any identifier is accepted when
+// the link has no roster
+func (s *service) MatchIdentifier(l *domain.Link, identifier string) (string, error) {
+	identifier = strings.TrimSpace(identifier)
+
+	entries, err := s.rosterRepo.ListByLink(l.ID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(entries) < 1 {
+		return identifier, nil
+	}
+
+	if identifier == "" {
+		return "", domain.ErrRosterIdentifierRequired
+	}
+
+	for _, entry := range entries {
+		if strings.EqualFold(entry.Identifier, identifier) || strings.EqualFold(entry.Email, identifier) {
+			return entry.Identifier, nil
+		}
+	}
+
+	return "", domain.ErrRosterIdentifierNotFound
+}
+
+// RosterStatus tells who has submitted, submitted late or not submitted to the link
+func (s *service) RosterStatus(l *domain.Link) ([]domain.RosterEntryStatus, error) {
+	entries, err := s.rosterRepo.ListByLink(l.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := s.submissionRepo.ListByLink(l.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the submission may be recorded with the email when it is uploaded before the roster is imported
+	entryIndexes := make(map[string]int, len(entries)*2)
+	for i, entry := range entries {
+		if entry.Email != "" {
+			entryIndexes[strings.ToLower(entry.Email)] = i
+		}
+	}
+	for i, entry := range entries {
+		entryIndexes[strings.ToLower(entry.Identifier)] = i
+	}
+
+	statuses := make([]domain.RosterEntryStatus, len(entries))
+	for i, entry := range entries {
+		statuses[i] = domain.RosterEntryStatus{Entry: entry, Status: domain.RosterStatusMissing}
+	}
+
+	for _, sub := range subs {
+		i, ok := entryIndexes[strings.ToLower(sub.RosterIdentifier)]
+		if !ok || sub.RosterIdentifier == "" || sub.Status != domain.SubmissionStatusSucceeded {
+			continue
+		}
+
+		status := &statuses[i]
+		status.SubmissionCount++
+
+		if status.SubmittedAt == nil || sub.CreatedAt.After(*status.SubmittedAt) {
+			submittedAt := sub.CreatedAt
+			status.SubmittedAt = &submittedAt
+		}
+
+		// a single submission before the deadline is enough
+		if l.Deadline == nil || !sub.CreatedAt.After(*l.Deadline) {
+			status.Status = domain.RosterStatusSubmitted
+		} else if status.Status == domain.RosterStatusMissing {
+			status.Status = domain.RosterStatusLate
+		}
+	}
+
+	return statuses, nil
+}
+
+// ExportRosterStatus writes the roster status of the link as CSV
+func (s *service) ExportRosterStatus(l *domain.Link, w io.Writer) error {
+	statuses, err := s.RosterStatus(l)
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(w)
+	if err = cw.Write([]string{"identifier", "name", "email", "status", "submitted_at", "submission_count"}); err != nil {
+		return err
+	}
+
+	for _, status := range statuses {
+		submittedAt := ""
+		if status.SubmittedAt != nil {
+			submittedAt = status.SubmittedAt.Format(time.RFC3339)
+		}
+
+		err = cw.Write([]string{
+			status.Entry.Identifier,
+			status.Entry.Name,
+			status.Entry.Email,
+			status.Status,
+			submittedAt,
+			strconv.Itoa(status.SubmissionCount),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+func parseRosterCSV(r io.Reader) ([]domain.RosterEntry, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, domain.ErrRosterInvalidCSV
+	}
+
+	entries := make([]domain.RosterEntry, 0, len(records))
+	if len(records) < 1 {
+		return entries, nil
+	}
+
+	// spreadsheet applications may prepend the byte order mark
+	if len(records[0]) > 0 {
+		records[0][0] = strings.TrimPrefix(records[0][0], "\ufeff")
+	}
+
+	columns, hasHeader := parseRosterHeader(records[0])
+	if hasHeader {
+		records = records[1:]
+	}
+
+	identifiers := make(map[string]bool, len(records))
+	for _, record := range records {
+		entry := domain.RosterEntry{}
+		for i, value := range record {
+			if i >= len(columns) {
+				break
+			}
+
+			value = strings.TrimSpace(value)
+			switch columns[i] {
+			case "identifier":
+				entry.Identifier = value
+			case "name":
+				entry.Name = value
+			case "email":
+				entry.Email = value
+			}
+		}
+
+		if entry.Identifier == "" {
+			entry.Identifier = entry.Email
+		}
+
+		if entry.Identifier == "" {
+			// blank lines are skipped, but a person must be identifiable
+			if entry.Name == "" {
+				continue
+			}
+			return nil, domain.ErrRosterInvalidCSV
+		}
+
+		key := strings.ToLower(entry.Identifier)
+		if identifiers[key] {
+			return nil, domain.ErrRosterDuplicatedIdentifier
+		}
+		identifiers[key] = true
+
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// parseRosterHeader returns the field of each column, the first row is a header
+// when any of its cells is a known column name
+func parseRosterHeader(record []string) ([]string, bool) {
+	columns := make([]string, len(record))
+	hasHeader := false
+	for i, value := range record {
+		if column, ok := headerColumns[strings.ToLower(strings.TrimSpace(value))]; ok {
+			columns[i] = column
+			hasHeader = true
+		}
+	}
+
+	if hasHeader {
+		return columns, true
+	}
+
+	return []string{"identifier", "name", "email"}, false
+}
@@ -0,0 +1,177 @@
+package roster_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/domain/roster"
+	"github.com/bccfilkom/drophere-go/infrastructure/database/inmemory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newService() (domain.RosterService, domain.LinkRepository, domain.SubmissionRepository) {
+	memdb := inmemory.New()
+	submissionRepo := inmemory.NewSubmissionRepository(memdb)
+
+	rosterSvc := roster.NewService(inmemory.NewRosterRepository(memdb), submissionRepo)
+	return rosterSvc, inmemory.NewLinkRepository(memdb), submissionRepo
+}
+
+func TestImportRoster(t *testing.T) {
+	type test struct {
+		csv         string
+		wantEntries []domain.RosterEntry
+		wantErr     error
+	}
+
+	tests := []test{
+		{
+			csv: "\ufeffNIM,Name,Email\n165150, Alice ,alice@example.com\n\n165151,Bob,\n",
+			wantEntries: []domain.RosterEntry{
+				{ID: 1, LinkID: 1, Identifier: "165150", Name: "Alice", Email: "alice@example.com"},
+				{ID: 2, LinkID: 1, Identifier: "165151", Name: "Bob"},
+			},
+		},
+		{
+			// without header, the columns are identifier, name and email
+			csv: "165150,Alice,alice@example.com\n",
+			wantEntries: []domain.RosterEntry{
+				{ID: 3, LinkID: 1, Identifier: "165150", Name: "Alice", Email: "alice@example.com"},
+			},
+		},
+		{
+			// the email is the identifier when there is no identifier column
+			csv: "email,name\ncarol@example.com,Carol\n",
+			wantEntries: []domain.RosterEntry{
+				{ID: 4, LinkID: 1, Identifier: "carol@example.com", Name: "Carol", Email: "carol@example.com"},
+			},
+		},
+		{
+			csv:     "identifier,name\nA1,Alice\na1,Another Alice\n",
+			wantErr: domain.ErrRosterDuplicatedIdentifier,
+		},
+		{
+			csv:     "identifier,name\n,Nobody\n",
+			wantErr: domain.ErrRosterInvalidCSV,
+		},
+		{
+			csv:     "identifier,name\n\"unclosed,Alice\n",
+			wantErr: domain.ErrRosterInvalidCSV,
+		},
+		{
+			// empty CSV removes the roster
+			csv:         "",
+			wantEntries: []domain.RosterEntry{},
+		},
+	}
+
+	rosterSvc, linkRepo, _ := newService()
+	l, _ := linkRepo.FindByID(1)
+
+	for i, tc := range tests {
+		gotEntries, gotErr := rosterSvc.ImportRoster(l, strings.NewReader(tc.csv))
+		assert.Equal(t, tc.wantErr, gotErr, "test %d", i)
+		if tc.wantErr != nil {
+			continue
+		}
+
+		assert.Equal(t, tc.wantEntries, gotEntries, "test %d", i)
+
+		entries, _ := rosterSvc.ListRoster(l.ID)
+		assert.Equal(t, tc.wantEntries, entries, "test %d", i)
+	}
+}
+
+func TestMatchIdentifier(t *testing.T) {
+	type test struct {
+		identifier     string
+		wantIdentifier string
+		wantErr        error
+	}
+
+	rosterSvc, linkRepo, _ := newService()
+	l, _ := linkRepo.FindByID(1)
+
+	// any identifier is accepted without roster
+	identifier, err := rosterSvc.MatchIdentifier(l, " anyone ")
+	assert.Nil(t, err)
+	assert.Equal(t, "anyone", identifier)
+
+	rosterSvc.ImportRoster(l, strings.NewReader("id,name,email\nA1,Alice,alice@example.com\n"))
+
+	tests := []test{
+		{identifier: "a1", wantIdentifier: "A1"},
+		{identifier: "Alice@Example.com", wantIdentifier: "A1"},
+		{identifier: " ", wantErr: domain.ErrRosterIdentifierRequired},
+		{identifier: "B2", wantErr: domain.ErrRosterIdentifierNotFound},
+	}
+
+	for i, tc := range tests {
+		gotIdentifier, gotErr := rosterSvc.MatchIdentifier(l, tc.identifier)
+		assert.Equal(t, tc.wantErr, gotErr, "test %d", i)
+		assert.Equal(t, tc.wantIdentifier, gotIdentifier, "test %d", i)
+	}
+}
+
+func TestRosterStatus(t *testing.T) {
+	rosterSvc, linkRepo, submissionRepo := newService()
+
+	deadline := time.Date(2019, time.July, 20, 0, 0, 0, 0, time.UTC)
+	l, _ := linkRepo.FindByID(3)
+	l.Deadline = &deadline
+
+	rosterSvc.ImportRoster(l, strings.NewReader(
+		"id,name,email\nA1,Alice,alice@example.com\nB2,Bob,bob@example.com\nC3,Carol,\nD4,Dave,\n",
+	))
+
+	onTime := time.Date(2019, time.July, 19, 10, 0, 0, 0, time.UTC)
+	late := time.Date(2019, time.July, 21, 10, 0, 0, 0, time.UTC)
+	for _, sub := range []domain.Submission{
+		{LinkID: 3, RosterIdentifier: "A1", Status: domain.SubmissionStatusSucceeded, CreatedAt: onTime},
+		{LinkID: 3, RosterIdentifier: "A1", Status: domain.SubmissionStatusSucceeded, CreatedAt: late},
+		// recorded with the email before the roster was imported
+		{LinkID: 3, RosterIdentifier: "bob@example.com", Status: domain.SubmissionStatusSucceeded, CreatedAt: late},
+		{LinkID: 3, RosterIdentifier: "C3", Status: domain.SubmissionStatusFailed, CreatedAt: onTime},
+		{LinkID: 1, RosterIdentifier: "D4", Status: domain.SubmissionStatusSucceeded, CreatedAt: onTime},
+	} {
+		submissionRepo.Create(&sub)
+	}
+
+	statuses, err := rosterSvc.RosterStatus(l)
+	assert.Nil(t, err)
+	assert.Equal(t, []domain.RosterEntryStatus{
+		{
+			Entry:           domain.RosterEntry{ID: 1, LinkID: 3, Identifier: "A1", Name: "Alice", Email: "alice@example.com"},
+			Status:          domain.RosterStatusSubmitted,
+			SubmittedAt:     &late,
+			SubmissionCount: 2,
+		},
+		{
+			Entry:           domain.RosterEntry{ID: 2, LinkID: 3, Identifier: "B2", Name: "Bob", Email: "bob@example.com"},
+			Status:          domain.RosterStatusLate,
+			SubmittedAt:     &late,
+			SubmissionCount: 1,
+		},
+		{
+			Entry:  domain.RosterEntry{ID: 3, LinkID: 3, Identifier: "C3", Name: "Carol"},
+			Status: domain.RosterStatusMissing,
+		},
+		{
+			Entry:  domain.RosterEntry{ID: 4, LinkID: 3, Identifier: "D4", Name: "Dave"},
+			Status: domain.RosterStatusMissing,
+		},
+	}, statuses)
+
+	csv := &bytes.Buffer{}
+	err = rosterSvc.ExportRosterStatus(l, csv)
+	assert.Nil(t, err)
+	assert.Equal(t, "identifier,name,email,status,submitted_at,submission_count\n"+
+		"A1,Alice,alice@example.com,submitted,2019-07-21T10:00:00Z,2\n"+
+		"B2,Bob,bob@example.com,late,2019-07-21T10:00:00Z,1\n"+
+		"C3,Carol,,missing,,0\n"+
+		"D4,Dave,,missing,,0\n", csv.String())
+}
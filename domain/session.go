@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSessionNotFound error
+	ErrSessionNotFound = errors.New("Session not found")
+	// ErrSessionInvalidRefreshToken error
+	ErrSessionInvalidRefreshToken = errors.New("Invalid or expired refresh token")
+)
+
+// Session is a signed-in device of a user. The access tokens carry the session ID,
+// so revoking the session rejects them before they expire
+type Session struct {
+	ID     uint
+	UserID uint
+	// RefreshTokenHash is the SHA-256 hash of the current refresh token
+	RefreshTokenHash string
+	// PreviousRefreshTokenHash is the hash of the rotated refresh token. Using it again means
+	// the token is stolen, so the session is revoked
+	PreviousRefreshTokenHash string
+	UserAgent                string
+	IP                       string
+	CreatedAt                time.Time
+	LastUsedAt               time.Time
+	ExpiresAt                time.Time
+	RevokedAt                *time.Time
+}
+
+// IsActiveAt checks whether the session is neither revoked nor expired
+func (s *Session) IsActiveAt(at time.Time) bool {
+	return s.RevokedAt == nil && at.Before(s.ExpiresAt)
+}
+
+// SessionDevice describes the client signing in
+type SessionDevice struct {
+	UserAgent string
+	IP        string
+}
+
+// SessionRepository abstraction
+type SessionRepository interface {
+	Create(s *Session) (*Session, error)
+	FindByID(id uint) (*Session, error)
+	// FindByRefreshTokenHash matches either the current or the previous refresh token
+	FindByRefreshTokenHash(hash string) (*Session, error)
+	Update(s *Session) (*Session, error)
+	// Rotate updates the session only while it is not revoked and its refresh token is still
+	// the one with the previous hash, it reports whether the session was updated
+	Rotate(s *Session, previousHash string) (bool, error)
+	ListActiveByUser(userID uint, at time.Time) ([]Session, error)
+	RevokeAllByUser(userID uint, at time.Time) error
+}
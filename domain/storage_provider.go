@@ -3,11 +3,20 @@ package domain
 import (
 	"errors"
 	"io"
+	"time"
 )
 
 var (
 	// ErrStorageProviderInvalid error
 	ErrStorageProviderInvalid = errors.New("Invalid Storage Provider ID")
+	// ErrStorageProviderFileNotFound error
+	ErrStorageProviderFileNotFound = errors.New("File not found on the Storage Provider")
+	// ErrStorageProviderCredentialExpired error
+	ErrStorageProviderCredentialExpired = errors.New("Storage Provider credential is expired, please reconnect the Storage Provider")
+	// ErrStorageProviderAuthorizationUnsupported error
+	ErrStorageProviderAuthorizationUnsupported = errors.New("The Storage Provider does not support authorization")
+	// ErrStorageProviderInvalidAuthorizationState error
+	ErrStorageProviderInvalidAuthorizationState = errors.New("Invalid or expired authorization state")
 )
 
 // StorageProvider domain model
@@ -20,6 +29,8 @@ var (
 // storage provider API
 type StorageProviderCredential struct {
 	UserAccessToken string
+	RefreshToken    string
+	Expiry          *time.Time
 }
 
 // StorageProviderAccountInfo domain model
@@ -28,11 +39,31 @@ type StorageProviderAccountInfo struct {
 	Photo string
 }
 
-// StorageProviderService abstraction
+// StorageProviderService abstraction. The file is uploaded to the slug folder inside the remote
+// directory, the slug may contain a sub folder separated by slash, e.g. "my-link/late"
 type StorageProviderService interface {
 	ID() uint
 	AccountInfo(creds StorageProviderCredential) (StorageProviderAccountInfo, error)
-	Upload(creds StorageProviderCredential, file io.Reader, fileName, slug string) error
+	Upload(creds StorageProviderCredential, file io.Reader, fileName, slug string) (storedPath string, err error)
+}
+
+// StorageProviderDownloader is implemented by Storage Provider Services
+// which are able to serve the stored files back (e.g. local file system)
+type StorageProviderDownloader interface {
+	Download(creds StorageProviderCredential, storedPath string) (io.ReadCloser, error)
+}
+
+// StorageProviderAuthorizer is implemented by Storage Provider Services
+// which support OAuth2 authorization code flow
+type StorageProviderAuthorizer interface {
+	AuthorizationURL(state, redirectURL string) (string, error)
+	ExchangeCode(code, redirectURL string) (StorageProviderCredential, error)
+}
+
+// StorageProviderCredentialRefresher is implemented by Storage Provider Services
+// which issue short-lived access tokens along with refresh tokens
+type StorageProviderCredentialRefresher interface {
+	RefreshCredential(creds StorageProviderCredential) (StorageProviderCredential, error)
 }
 
 // StorageProviderPool stores a collection of Storage Provider Service
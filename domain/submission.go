@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrSubmissionNotFound error
+	ErrSubmissionNotFound = errors.New("Submission not found")
+	// ErrReceiptNotFound error
+	ErrReceiptNotFound = errors.New("Receipt not found")
+)
+
+const (
+	// SubmissionStatusSucceeded marks a file that is stored successfully on the storage provider
+	SubmissionStatusSucceeded = "succeeded"
+	// SubmissionStatusFailed marks a file that is failed to be stored on the storage provider
+	SubmissionStatusFailed = "failed"
+)
+
+// Submission domain model, records a file uploaded to a link
+type Submission struct {
+	ID            uint
+	LinkID        uint
+	FileName      string
+	StoredPath    string
+	Size          int64
+	ContentType   string
+	UploaderName  string
+	UploaderEmail string
+	UploaderIP    string
+	ProviderID    uint
+	Status        string
+	CreatedAt     time.Time
+	// NotifiedAt is the time the link owner is notified about the submission
+	NotifiedAt *time.Time
+	// ReceiptID is given to the uploader to verify the submission later
+	ReceiptID string
+	// SHA256 is the hex-encoded SHA-256 hash of the received file
+	SHA256 string
+	// FormAnswers holds the uploader's answers to the link's form fields
+	FormAnswers FormAnswers
+	// RosterIdentifier is the identifier entered by the uploader to match the link's roster
+	RosterIdentifier string
+	// Late marks a file uploaded after the link's deadline
+	Late bool
+	// LatenessSeconds is how long after the deadline the file is uploaded
+	LatenessSeconds int64
+}
+
+// SubmissionService abstraction
+type SubmissionService interface {
+	RecordSubmission(s *Submission) (*Submission, error)
+	FetchSubmission(id uint) (*Submission, error)
+	ListSubmissions(linkID uint) ([]Submission, error)
+	VerifyReceipt(receiptID string) (*Submission, error)
+}
+
+// SubmissionRepository abstraction
+type SubmissionRepository interface {
+	Create(s *Submission) (*Submission, error)
+	FindByID(id uint) (*Submission, error)
+	FindByReceiptID(receiptID string) (*Submission, error)
+	ListByLink(linkID uint) ([]Submission, error)
+	ListUnnotifiedByLink(linkID uint) ([]Submission, error)
+	Update(s *Submission) (*Submission, error)
+}
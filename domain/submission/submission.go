@@ -0,0 +1,62 @@
+package submission
+
+import (
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+type service struct {
+	submissionRepo  domain.SubmissionRepository
+	stringGenerator domain.StringGenerator
+}
+
+// NewService returns new service instance
+func NewService(submissionRepo domain.SubmissionRepository, stringGenerator domain.StringGenerator) domain.SubmissionService {
+	return &service{
+		submissionRepo:  submissionRepo,
+		stringGenerator: stringGenerator,
+	}
+}
+
+// RecordSubmission stores the information of an uploaded file to repository
+func (s *service) RecordSubmission(sub *domain.Submission) (*domain.Submission, error) {
+	if sub.Status == "" {
+		sub.Status = domain.SubmissionStatusSucceeded
+	}
+
+	if sub.CreatedAt.IsZero() {
+		sub.CreatedAt = time.Now()
+	}
+
+	// only stored files have a receipt
+	if sub.Status == domain.SubmissionStatusSucceeded && sub.ReceiptID == "" {
+		sub.ReceiptID = s.stringGenerator.Generate()
+	}
+
+	return s.submissionRepo.Create(sub)
+}
+
+// FetchSubmission returns single Submission identified by its ID
+func (s *service) FetchSubmission(id uint) (*domain.Submission, error) {
+	return s.submissionRepo.FindByID(id)
+}
+
+// ListSubmissions returns list of Submission which belongs to a link
+func (s *service) ListSubmissions(linkID uint) ([]domain.Submission, error) {
+	return s.submissionRepo.ListByLink(linkID)
+}
+
+// VerifyReceipt returns the Submission identified by the receipt ID
+func (s *service) VerifyReceipt(receiptID string) (*domain.Submission, error) {
+	if receiptID == "" {
+		return nil, domain.ErrReceiptNotFound
+	}
+
+	sub, err := s.submissionRepo.FindByReceiptID(receiptID)
+	if err == domain.ErrSubmissionNotFound {
+		return nil, domain.ErrReceiptNotFound
+	}
+
+	return sub, err
+}
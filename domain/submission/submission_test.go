@@ -0,0 +1,189 @@
+package submission_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/domain/submission"
+	"github.com/bccfilkom/drophere-go/infrastructure/database/inmemory"
+	"github.com/bccfilkom/drophere-go/infrastructure/stringgenerator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var strGen domain.StringGenerator
+
+func init() {
+	strGen = stringgenerator.NewMock()
+	stringgenerator.SetMockResult("this_is_not_a_random_string")
+}
+
+func newRepo() domain.SubmissionRepository {
+	memdb := inmemory.New()
+	return inmemory.NewSubmissionRepository(memdb)
+}
+
+func TestRecordSubmission(t *testing.T) {
+	type test struct {
+		submission     *domain.Submission
+		wantSubmission *domain.Submission
+		wantErr        error
+	}
+
+	createdAt := time.Date(2019, time.July, 20, 1, 2, 3, 0, time.UTC)
+
+	tests := []test{
+		{
+			submission: &domain.Submission{
+				LinkID:      2,
+				FileName:    "cv.pdf",
+				StoredPath:  "/drophere/test-link-2/cv.pdf",
+				Size:        100,
+				ContentType: "application/pdf",
+				ProviderID:  1,
+				CreatedAt:   createdAt,
+			},
+			wantSubmission: &domain.Submission{
+				ID:          3,
+				LinkID:      2,
+				FileName:    "cv.pdf",
+				StoredPath:  "/drophere/test-link-2/cv.pdf",
+				Size:        100,
+				ContentType: "application/pdf",
+				ProviderID:  1,
+				Status:      domain.SubmissionStatusSucceeded,
+				CreatedAt:   createdAt,
+				ReceiptID:   "this_is_not_a_random_string",
+			},
+		},
+		{
+			submission: &domain.Submission{
+				LinkID:     2,
+				FileName:   "cv.pdf",
+				ProviderID: 1,
+				Status:     domain.SubmissionStatusFailed,
+				CreatedAt:  createdAt,
+			},
+			wantSubmission: &domain.Submission{
+				ID:         4,
+				LinkID:     2,
+				FileName:   "cv.pdf",
+				ProviderID: 1,
+				Status:     domain.SubmissionStatusFailed,
+				CreatedAt:  createdAt,
+			},
+		},
+	}
+
+	submissionSvc := submission.NewService(newRepo(), strGen)
+
+	for _, tc := range tests {
+		gotSubmission, gotErr := submissionSvc.RecordSubmission(tc.submission)
+
+		assert.Equal(t, tc.wantErr, gotErr)
+		assert.Equal(t, tc.wantSubmission, gotSubmission)
+	}
+
+	// the creation time is filled when it is not set
+	gotSubmission, gotErr := submissionSvc.RecordSubmission(&domain.Submission{LinkID: 2})
+	assert.Nil(t, gotErr)
+	assert.False(t, gotSubmission.CreatedAt.IsZero())
+}
+
+func TestFetchSubmission(t *testing.T) {
+	type test struct {
+		submissionID uint
+		wantFileName string
+		wantErr      error
+	}
+
+	tests := []test{
+		{
+			submissionID: 123,
+			wantErr:      domain.ErrSubmissionNotFound,
+		},
+		{
+			submissionID: 2,
+			wantFileName: "report (revised).pdf",
+		},
+	}
+
+	submissionSvc := submission.NewService(newRepo(), strGen)
+
+	for _, tc := range tests {
+		gotSubmission, gotErr := submissionSvc.FetchSubmission(tc.submissionID)
+
+		assert.Equal(t, tc.wantErr, gotErr)
+		if tc.wantErr == nil {
+			assert.Equal(t, tc.wantFileName, gotSubmission.FileName)
+		}
+	}
+}
+
+func TestListSubmissions(t *testing.T) {
+	type test struct {
+		linkID  uint
+		wantIDs []uint
+		wantErr error
+	}
+
+	tests := []test{
+		{
+			linkID:  123,
+			wantIDs: []uint{},
+		},
+		{
+			linkID:  1,
+			wantIDs: []uint{2, 1},
+		},
+	}
+
+	submissionSvc := submission.NewService(newRepo(), strGen)
+
+	for _, tc := range tests {
+		gotSubmissions, gotErr := submissionSvc.ListSubmissions(tc.linkID)
+
+		gotIDs := make([]uint, len(gotSubmissions))
+		for i, s := range gotSubmissions {
+			gotIDs[i] = s.ID
+		}
+
+		assert.Equal(t, tc.wantErr, gotErr)
+		assert.Equal(t, tc.wantIDs, gotIDs)
+	}
+}
+
+func TestVerifyReceipt(t *testing.T) {
+	type test struct {
+		receiptID        string
+		wantSubmissionID uint
+		wantErr          error
+	}
+
+	tests := []test{
+		{
+			receiptID: "",
+			wantErr:   domain.ErrReceiptNotFound,
+		},
+		{
+			receiptID: "unknown_receipt",
+			wantErr:   domain.ErrReceiptNotFound,
+		},
+		{
+			receiptID:        "receipt_1",
+			wantSubmissionID: 1,
+		},
+	}
+
+	submissionSvc := submission.NewService(newRepo(), strGen)
+
+	for i, tc := range tests {
+		gotSubmission, gotErr := submissionSvc.VerifyReceipt(tc.receiptID)
+
+		assert.Equal(t, tc.wantErr, gotErr, "test %d", i)
+		if tc.wantErr == nil {
+			assert.Equal(t, tc.wantSubmissionID, gotSubmission.ID, "test %d", i)
+		}
+	}
+}
@@ -14,6 +14,12 @@ var (
 	ErrUserNotFound = errors.New("User not found")
 	// ErrUserPasswordRecoveryTokenExpired error
 	ErrUserPasswordRecoveryTokenExpired = errors.New("Password recovery token is expired")
+	// ErrUserEmailVerificationTokenExpired error
+	ErrUserEmailVerificationTokenExpired = errors.New("Email verification token is expired")
+	// ErrUserEmailAlreadyVerified error
+	ErrUserEmailAlreadyVerified = errors.New("Email is already verified")
+	// ErrUserEmailNotVerified error
+	ErrUserEmailNotVerified = errors.New("Please verify your email first")
 )
 
 // User model
@@ -26,25 +32,50 @@ type User struct {
 	DriveToken                 *string
 	RecoverPasswordToken       *string
 	RecoverPasswordTokenExpiry *time.Time
+	// TokenVersion is increased on every password change, the tokens minted with an older version are rejected
+	TokenVersion                 uint
+	EmailVerifiedAt              *time.Time
+	EmailVerificationToken       *string
+	EmailVerificationTokenExpiry *time.Time
+	// StorageProviderAuthorizationNonce is inside the state of the pending storage provider authorization,
+	// it is cleared on the callback so the state can only be used once
+	StorageProviderAuthorizationNonce *string
+}
+
+// IsEmailVerified checks whether the user has verified the email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
 }
 
 // UserCredentials model
 type UserCredentials struct {
 	Token  string
 	Expiry *time.Time
+	// RefreshToken is exchanged for new credentials once the token expires
+	RefreshToken       string
+	RefreshTokenExpiry *time.Time
 }
 
 // UserService abstraction
 type UserService interface {
 	Register(email, name, password string) (*User, error)
-	Auth(email, password string) (*UserCredentials, error)
+	Auth(email, password string, device SessionDevice) (*UserCredentials, error)
+	RefreshSession(refreshToken string, device SessionDevice) (*UserCredentials, error)
+	Logout(userID, sessionID uint) error
+	LogoutAllSessions(userID uint) error
+	ListSessions(userID uint) ([]Session, error)
 	Update(userID uint, name, password, oldPassword *string) (*User, error)
 	ConnectStorageProvider(userID, providerID uint, providerCredential string) error
+	StartStorageProviderAuthorization(userID, providerID uint) (string, error)
+	CompleteStorageProviderAuthorization(providerID uint, state, code string) error
 	DisconnectStorageProvider(userID, providerID uint) error
+	RefreshStorageProviderCredential(cred UserStorageCredential) (UserStorageCredential, error)
 	ListStorageProviders(userID uint) ([]UserStorageCredential, error)
 	UpdateStorageToken(userID uint, dropboxToken *string) (*User, error)
 	RequestPasswordRecovery(email string) error
 	RecoverPassword(email, token, newPassword string) error
+	RequestEmailVerification(userID uint) error
+	VerifyEmail(email, token string) error
 }
 
 // UserRepository abstraction
@@ -55,7 +86,7 @@ type UserRepository interface {
 	Update(u *User) (*User, error)
 }
 
-// Authenticator is external authentication service
+// Authenticator is external authentication service, it issues short-lived access token of the session
 type Authenticator interface {
-	Authenticate(u *User) (*UserCredentials, error)
+	Authenticate(u *User, sessionID uint) (*UserCredentials, error)
 }
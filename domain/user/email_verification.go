@@ -0,0 +1,84 @@
+package user
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// RequestEmailVerification implementation, the previous verification token is replaced
+func (s *service) RequestEmailVerification(userID uint) error {
+	u, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	if u.IsEmailVerified() {
+		return domain.ErrUserEmailAlreadyVerified
+	}
+
+	tokenExpiryDuration := defaultEmailVerificationTokenExpiryDuration
+	if s.config.EmailVerificationTokenExpiryDuration > 0 {
+		tokenExpiryDuration = s.config.EmailVerificationTokenExpiryDuration
+	}
+
+	token := s.stringGenerator.Generate()
+	tokenExpiry := time.Now().Add(time.Minute * time.Duration(tokenExpiryDuration))
+	u.EmailVerificationToken = &token
+	u.EmailVerificationTokenExpiry = &tokenExpiry
+
+	// save the user
+	u, err = s.userRepo.Update(u)
+	if err != nil {
+		return err
+	}
+
+	// send email
+	return s.sendTemplateMail(
+		domain.MailAddress{
+			Address: u.Email,
+			Name:    u.Name,
+		},
+		"Verify Your Email",
+		"email_verification",
+		map[string]string{
+			"VerifyEmailLink": fmt.Sprintf(
+				"%s?token=%s&email=%s",
+				s.config.VerifyEmailWebURL,
+				url.QueryEscape(token),
+				url.QueryEscape(u.Email),
+			),
+			"Token": token,
+			"Name":  u.Name,
+		},
+	)
+}
+
+// VerifyEmail implementation
+func (s *service) VerifyEmail(email, token string) error {
+	u, err := s.userRepo.FindByEmail(email)
+	if err != nil {
+		return err
+	}
+
+	if u.IsEmailVerified() {
+		return domain.ErrUserEmailAlreadyVerified
+	}
+
+	if token == "" || u.EmailVerificationToken == nil || *u.EmailVerificationToken != token {
+		return domain.ErrUserNotFound
+	}
+
+	if u.EmailVerificationTokenExpiry == nil || time.Now().After(*u.EmailVerificationTokenExpiry) {
+		return domain.ErrUserEmailVerificationTokenExpired
+	}
+
+	now := time.Now()
+	u.EmailVerifiedAt = &now
+	u.EmailVerificationToken, u.EmailVerificationTokenExpiry = nil, nil
+
+	_, err = s.userRepo.Update(u)
+	return err
+}
@@ -0,0 +1,153 @@
+package user
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// startSession creates a session for the device and issues its first token pair
+func (s *service) startSession(u *domain.User, device domain.SessionDevice) (*domain.UserCredentials, error) {
+	refreshToken := s.stringGenerator.Generate()
+	now := time.Now()
+
+	session, err := s.sessionRepo.Create(&domain.Session{
+		UserID:           u.ID,
+		RefreshTokenHash: hashRefreshToken(refreshToken),
+		UserAgent:        device.UserAgent,
+		IP:               device.IP,
+		CreatedAt:        now,
+		LastUsedAt:       now,
+		ExpiresAt:        now.Add(s.config.RefreshTokenDuration),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.issueCredentials(u, session, refreshToken)
+}
+
+// issueCredentials mints an access token bound to the session
+func (s *service) issueCredentials(u *domain.User, session *domain.Session, refreshToken string) (*domain.UserCredentials, error) {
+	creds, err := s.authenticator.Authenticate(u, session.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshTokenExpiry := session.ExpiresAt
+	creds.RefreshToken = refreshToken
+	creds.RefreshTokenExpiry = &refreshTokenExpiry
+
+	return creds, nil
+}
+
+// RefreshSession implementation
+func (s *service) RefreshSession(refreshToken string, device domain.SessionDevice) (*domain.UserCredentials, error) {
+	if refreshToken == "" {
+		return nil, domain.ErrSessionInvalidRefreshToken
+	}
+
+	hash := hashRefreshToken(refreshToken)
+	session, err := s.sessionRepo.FindByRefreshTokenHash(hash)
+	if err == domain.ErrSessionNotFound {
+		return nil, domain.ErrSessionInvalidRefreshToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if !session.IsActiveAt(now) {
+		return nil, domain.ErrSessionInvalidRefreshToken
+	}
+
+	// the refresh token has been rotated, so whoever presents it again is not the owner of the session
+	if session.RefreshTokenHash != hash {
+		return nil, s.revokeReusedSession(session.ID, now)
+	}
+
+	u, err := s.userRepo.FindByID(session.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken = s.stringGenerator.Generate()
+	session.PreviousRefreshTokenHash = session.RefreshTokenHash
+	session.RefreshTokenHash = hashRefreshToken(refreshToken)
+	session.LastUsedAt = now
+	session.ExpiresAt = now.Add(s.config.RefreshTokenDuration)
+	if device.UserAgent != "" {
+		session.UserAgent = device.UserAgent
+	}
+	if device.IP != "" {
+		session.IP = device.IP
+	}
+
+	// the token is only rotated if no concurrent refresh has rotated it first,
+	// otherwise the same refresh token has been used twice
+	rotated, err := s.sessionRepo.Rotate(session, hash)
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		return nil, s.revokeReusedSession(session.ID, now)
+	}
+
+	return s.issueCredentials(u, session, refreshToken)
+}
+
+// revokeReusedSession revokes the session whose refresh token has been used again,
+// it returns ErrSessionInvalidRefreshToken once the session is revoked
+func (s *service) revokeReusedSession(sessionID uint, now time.Time) error {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		return err
+	}
+
+	session.RevokedAt = &now
+	if _, err = s.sessionRepo.Update(session); err != nil {
+		return err
+	}
+
+	return domain.ErrSessionInvalidRefreshToken
+}
+
+// Logout implementation
+func (s *service) Logout(userID, sessionID uint) error {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil {
+		return err
+	}
+
+	if session.UserID != userID {
+		return domain.ErrSessionNotFound
+	}
+
+	if session.RevokedAt != nil {
+		return nil
+	}
+
+	now := time.Now()
+	session.RevokedAt = &now
+	_, err = s.sessionRepo.Update(session)
+	return err
+}
+
+// LogoutAllSessions implementation
+func (s *service) LogoutAllSessions(userID uint) error {
+	return s.sessionRepo.RevokeAllByUser(userID, time.Now())
+}
+
+// ListSessions implementation
+func (s *service) ListSessions(userID uint) ([]domain.Session, error) {
+	return s.sessionRepo.ListActiveByUser(userID, time.Now())
+}
+
+// hashRefreshToken returns the hex-encoded SHA-256 of the refresh token,
+// only the hash is stored so a leaked database does not leak the tokens
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,183 @@
+package user_test
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/domain/user"
+	"github.com/bccfilkom/drophere-go/infrastructure/stringgenerator"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newSessionService() domain.UserService {
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
+	return user.NewService(
+		userRepo,
+		userStorageCredRepo,
+		sessionRepo,
+		authenticator,
+		mockMailer,
+		dummyHasher,
+		strGen,
+		storageProviderPool,
+		htmlTemplates,
+		textTemplates,
+		user.Config{RefreshTokenDuration: time.Hour},
+	)
+}
+
+func TestRefreshSession(t *testing.T) {
+	defer stringgenerator.SetMockResult("this_is_not_a_random_string")
+	userSvc := newSessionService()
+
+	stringgenerator.SetMockResult("refresh_token_1")
+	creds, err := userSvc.Auth("user@drophere.link", "123456", domain.SessionDevice{UserAgent: "Firefox", IP: "10.0.0.1"})
+	assert.Nil(t, err)
+	assert.Equal(t, "refresh_token_1", creds.RefreshToken)
+	if assert.NotNil(t, creds.RefreshTokenExpiry) {
+		assert.WithinDuration(t, time.Now().Add(time.Hour), *creds.RefreshTokenExpiry, time.Minute)
+	}
+
+	_, err = userSvc.RefreshSession("unknown_refresh_token", domain.SessionDevice{})
+	assert.Equal(t, domain.ErrSessionInvalidRefreshToken, err)
+
+	// the refresh token is rotated
+	stringgenerator.SetMockResult("refresh_token_2")
+	creds, err = userSvc.RefreshSession("refresh_token_1", domain.SessionDevice{UserAgent: "Firefox", IP: "10.0.0.2"})
+	assert.Nil(t, err)
+	assert.Equal(t, "user_token_1", creds.Token)
+	assert.Equal(t, "refresh_token_2", creds.RefreshToken)
+
+	sessions, _ := userSvc.ListSessions(1)
+	if assert.Len(t, sessions, 1) {
+		assert.Equal(t, "10.0.0.2", sessions[0].IP)
+	}
+
+	// using the rotated refresh token again revokes the session
+	_, err = userSvc.RefreshSession("refresh_token_1", domain.SessionDevice{})
+	assert.Equal(t, domain.ErrSessionInvalidRefreshToken, err)
+
+	_, err = userSvc.RefreshSession("refresh_token_2", domain.SessionDevice{})
+	assert.Equal(t, domain.ErrSessionInvalidRefreshToken, err)
+
+	sessions, _ = userSvc.ListSessions(1)
+	assert.Len(t, sessions, 0)
+}
+
+func TestConcurrentRefreshSession(t *testing.T) {
+	defer stringgenerator.SetMockResult("this_is_not_a_random_string")
+	userSvc := newSessionService()
+
+	stringgenerator.SetMockResult("refresh_token_1")
+	userSvc.Auth("user@drophere.link", "123456", domain.SessionDevice{})
+	stringgenerator.SetMockResult("refresh_token_2")
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		refreshed int
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := userSvc.RefreshSession("refresh_token_1", domain.SessionDevice{}); err == nil {
+				mu.Lock()
+				refreshed++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	// only one refresh rotates the token, the others are reuses which revoke the session
+	assert.Equal(t, 1, refreshed)
+	_, err := userSvc.RefreshSession("refresh_token_2", domain.SessionDevice{})
+	assert.Equal(t, domain.ErrSessionInvalidRefreshToken, err)
+
+	sessions, _ := userSvc.ListSessions(1)
+	assert.Len(t, sessions, 0)
+}
+
+func TestLogout(t *testing.T) {
+	defer stringgenerator.SetMockResult("this_is_not_a_random_string")
+	userSvc := newSessionService()
+
+	stringgenerator.SetMockResult("refresh_token_1")
+	userSvc.Auth("user@drophere.link", "123456", domain.SessionDevice{UserAgent: "Firefox"})
+	stringgenerator.SetMockResult("refresh_token_2")
+	userSvc.Auth("user@drophere.link", "123456", domain.SessionDevice{UserAgent: "Chrome"})
+	stringgenerator.SetMockResult("refresh_token_3")
+	userSvc.Auth("user_357@drophere.link", "123456", domain.SessionDevice{})
+
+	sessions, err := userSvc.ListSessions(1)
+	assert.Nil(t, err)
+	assert.Len(t, sessions, 2)
+
+	// the session of another user can not be logged out
+	err = userSvc.Logout(357, 1)
+	assert.Equal(t, domain.ErrSessionNotFound, err)
+
+	err = userSvc.Logout(1, 1)
+	assert.Nil(t, err)
+
+	remaining, _ := userSvc.ListSessions(1)
+	if assert.Len(t, remaining, 1) {
+		assert.Equal(t, "Chrome", remaining[0].UserAgent)
+	}
+
+	_, err = userSvc.RefreshSession("refresh_token_1", domain.SessionDevice{})
+	assert.Equal(t, domain.ErrSessionInvalidRefreshToken, err)
+
+	err = userSvc.LogoutAllSessions(1)
+	assert.Nil(t, err)
+
+	remaining, _ = userSvc.ListSessions(1)
+	assert.Len(t, remaining, 0)
+
+	// the sessions of other users stay active
+	remaining, _ = userSvc.ListSessions(357)
+	assert.Len(t, remaining, 1)
+}
+
+func TestPasswordChangeRevokesSessions(t *testing.T) {
+	defer stringgenerator.SetMockResult("this_is_not_a_random_string")
+	userSvc := newSessionService()
+
+	stringgenerator.SetMockResult("refresh_token_1")
+	userSvc.Auth("user@drophere.link", "123456", domain.SessionDevice{})
+
+	u, err := userSvc.Update(1, nil, str2ptr("654321"), str2ptr("123456"))
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), u.TokenVersion)
+
+	sessions, _ := userSvc.ListSessions(1)
+	assert.Len(t, sessions, 0)
+
+	_, err = userSvc.RefreshSession("refresh_token_1", domain.SessionDevice{})
+	assert.Equal(t, domain.ErrSessionInvalidRefreshToken, err)
+
+	// changing only the name keeps the sessions
+	stringgenerator.SetMockResult("refresh_token_2")
+	userSvc.Auth("user@drophere.link", "654321", domain.SessionDevice{})
+
+	u, err = userSvc.Update(1, str2ptr("New Name"), nil, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(1), u.TokenVersion)
+
+	sessions, _ = userSvc.ListSessions(1)
+	assert.Len(t, sessions, 1)
+
+	// recovering the password revokes the sessions as well
+	stringgenerator.SetMockResult("refresh_token_3")
+	userSvc.Auth("reset+pwd@drophere.link", "123456", domain.SessionDevice{})
+
+	err = userSvc.RecoverPassword("reset+pwd@drophere.link", "recover_password_token", "654321")
+	assert.Nil(t, err)
+
+	sessions, _ = userSvc.ListSessions(12368)
+	assert.Len(t, sessions, 0)
+}
@@ -1,6 +1,23 @@
 package user
 
-import "github.com/bccfilkom/drophere-go/domain"
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// authorizationStateDuration is how long the user has to complete
+// the authorization on the storage provider's consent page
+const authorizationStateDuration = 10 * time.Minute
+
+var errEmptyAuthorizationStateSecret = errors.New("The storage provider authorization is not configured")
 
 // ConnectStorageProvider implementation
 func (s *service) ConnectStorageProvider(userID, providerID uint, providerCredential string) error {
@@ -14,11 +31,121 @@ func (s *service) ConnectStorageProvider(userID, providerID uint, providerCreden
 		return err
 	}
 
-	storageProviderAccount, err := storageProvider.AccountInfo(
+	return s.saveStorageCredential(
+		u,
+		storageProvider,
 		domain.StorageProviderCredential{
 			UserAccessToken: providerCredential,
 		},
 	)
+}
+
+// StartStorageProviderAuthorization returns the URL of storage provider's consent page
+func (s *service) StartStorageProviderAuthorization(userID, providerID uint) (string, error) {
+	storageProvider, err := s.storageProviderPool.Get(providerID)
+	if err != nil {
+		return "", err
+	}
+
+	authorizer, ok := storageProvider.(domain.StorageProviderAuthorizer)
+	if !ok {
+		return "", domain.ErrStorageProviderAuthorizationUnsupported
+	}
+
+	u, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return "", err
+	}
+
+	if s.config.StorageProviderAuthorizationSecret == "" {
+		return "", errEmptyAuthorizationStateSecret
+	}
+
+	// starting another authorization invalidates the earlier one
+	nonce := s.stringGenerator.Generate()
+	u.StorageProviderAuthorizationNonce = &nonce
+	if _, err = s.userRepo.Update(u); err != nil {
+		return "", err
+	}
+
+	state := s.signAuthorizationState(u.ID, providerID, nonce, time.Now().Add(authorizationStateDuration))
+
+	return authorizer.AuthorizationURL(state, s.authorizationRedirectURL(providerID))
+}
+
+// CompleteStorageProviderAuthorization exchanges the authorization code and
+// connects the storage provider to the user who started the authorization
+func (s *service) CompleteStorageProviderAuthorization(providerID uint, state, code string) error {
+	userID, stateProviderID, nonce, err := s.verifyAuthorizationState(state)
+	if err != nil {
+		return err
+	}
+
+	if stateProviderID != providerID {
+		return domain.ErrStorageProviderInvalidAuthorizationState
+	}
+
+	storageProvider, err := s.storageProviderPool.Get(providerID)
+	if err != nil {
+		return err
+	}
+
+	authorizer, ok := storageProvider.(domain.StorageProviderAuthorizer)
+	if !ok {
+		return domain.ErrStorageProviderAuthorizationUnsupported
+	}
+
+	u, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+
+	// the state is used up even when the code exchange fails
+	if u.StorageProviderAuthorizationNonce == nil || !hmac.Equal([]byte(*u.StorageProviderAuthorizationNonce), []byte(nonce)) {
+		return domain.ErrStorageProviderInvalidAuthorizationState
+	}
+	u.StorageProviderAuthorizationNonce = nil
+	if _, err = s.userRepo.Update(u); err != nil {
+		return err
+	}
+
+	cred, err := authorizer.ExchangeCode(code, s.authorizationRedirectURL(providerID))
+	if err != nil {
+		return err
+	}
+
+	return s.saveStorageCredential(u, storageProvider, cred)
+}
+
+// RefreshStorageProviderCredential requests new access token to the storage provider
+// and saves it, so the next upload does not need to refresh the token again
+func (s *service) RefreshStorageProviderCredential(cred domain.UserStorageCredential) (domain.UserStorageCredential, error) {
+	storageProvider, err := s.storageProviderPool.Get(cred.ProviderID)
+	if err != nil {
+		return cred, err
+	}
+
+	refresher, ok := storageProvider.(domain.StorageProviderCredentialRefresher)
+	if !ok || cred.ProviderRefreshToken == "" {
+		return cred, domain.ErrStorageProviderCredentialExpired
+	}
+
+	providerCred, err := refresher.RefreshCredential(cred.StorageProviderCredential())
+	if err != nil {
+		return cred, err
+	}
+
+	cred.ProviderCredential = providerCred.UserAccessToken
+	cred.ProviderRefreshToken = providerCred.RefreshToken
+	cred.ProviderCredentialExpiry = providerCred.Expiry
+
+	return s.userStorageCredRepo.Update(cred)
+}
+
+// saveStorageCredential fetches the account info from the storage provider and
+// creates or updates the user's credential for the storage provider
+func (s *service) saveStorageCredential(u *domain.User, storageProvider domain.StorageProviderService, providerCred domain.StorageProviderCredential) error {
+	storageProviderAccount, err := storageProvider.AccountInfo(providerCred)
 	if err != nil {
 		return err
 	}
@@ -27,7 +154,7 @@ func (s *service) ConnectStorageProvider(userID, providerID uint, providerCreden
 
 	creds, err := s.userStorageCredRepo.Find(domain.UserStorageCredentialFilters{
 		UserIDs:     []uint{u.ID},
-		ProviderIDs: []uint{providerID},
+		ProviderIDs: []uint{storageProvider.ID()},
 	}, false)
 	if err != nil {
 		return err
@@ -35,17 +162,21 @@ func (s *service) ConnectStorageProvider(userID, providerID uint, providerCreden
 
 	if len(creds) > 0 {
 		cred = creds[0]
-		cred.ProviderCredential = providerCredential
+		cred.ProviderCredential = providerCred.UserAccessToken
+		cred.ProviderRefreshToken = providerCred.RefreshToken
+		cred.ProviderCredentialExpiry = providerCred.Expiry
 		cred.Email = storageProviderAccount.Email
 		cred.Photo = storageProviderAccount.Photo
 		cred, err = s.userStorageCredRepo.Update(cred)
 	} else {
 		cred, err = s.userStorageCredRepo.Create(domain.UserStorageCredential{
-			UserID:             u.ID,
-			ProviderID:         providerID,
-			ProviderCredential: providerCredential,
-			Email:              storageProviderAccount.Email,
-			Photo:              storageProviderAccount.Photo,
+			UserID:                   u.ID,
+			ProviderID:               storageProvider.ID(),
+			ProviderCredential:       providerCred.UserAccessToken,
+			ProviderRefreshToken:     providerCred.RefreshToken,
+			ProviderCredentialExpiry: providerCred.Expiry,
+			Email:                    storageProviderAccount.Email,
+			Photo:                    storageProviderAccount.Photo,
 		})
 	}
 
@@ -53,6 +184,72 @@ func (s *service) ConnectStorageProvider(userID, providerID uint, providerCreden
 
 }
 
+func (s *service) authorizationRedirectURL(providerID uint) string {
+	return fmt.Sprintf(
+		"%s/%d",
+		strings.TrimRight(s.config.StorageProviderAuthorizationCallbackURL, "/"),
+		providerID,
+	)
+}
+
+// signAuthorizationState creates an OAuth2 state parameter which can only be
+// produced by this server, so the callback can trust the user ID inside it
+func (s *service) signAuthorizationState(userID, providerID uint, nonce string, expiry time.Time) string {
+	payload := fmt.Sprintf(
+		"%d.%d.%d.%s",
+		userID,
+		providerID,
+		expiry.Unix(),
+		nonce,
+	)
+
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) +
+		"." +
+		base64.RawURLEncoding.EncodeToString(s.authorizationStateMAC(payload))
+}
+
+func (s *service) verifyAuthorizationState(state string) (userID, providerID uint, nonce string, err error) {
+	// an empty secret would let anyone sign the state
+	if s.config.StorageProviderAuthorizationSecret == "" {
+		return 0, 0, "", domain.ErrStorageProviderInvalidAuthorizationState
+	}
+
+	parts := strings.Split(state, ".")
+	if len(parts) != 2 {
+		return 0, 0, "", domain.ErrStorageProviderInvalidAuthorizationState
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return 0, 0, "", domain.ErrStorageProviderInvalidAuthorizationState
+	}
+
+	mac, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(mac, s.authorizationStateMAC(string(payload))) {
+		return 0, 0, "", domain.ErrStorageProviderInvalidAuthorizationState
+	}
+
+	fields := strings.SplitN(string(payload), ".", 4)
+	if len(fields) != 4 || fields[3] == "" {
+		return 0, 0, "", domain.ErrStorageProviderInvalidAuthorizationState
+	}
+
+	parsedUserID, err1 := strconv.ParseUint(fields[0], 10, 64)
+	parsedProviderID, err2 := strconv.ParseUint(fields[1], 10, 64)
+	expiry, err3 := strconv.ParseInt(fields[2], 10, 64)
+	if err1 != nil || err2 != nil || err3 != nil || time.Now().Unix() > expiry {
+		return 0, 0, "", domain.ErrStorageProviderInvalidAuthorizationState
+	}
+
+	return uint(parsedUserID), uint(parsedProviderID), fields[3], nil
+}
+
+func (s *service) authorizationStateMAC(payload string) []byte {
+	h := hmac.New(sha256.New, []byte(s.config.StorageProviderAuthorizationSecret))
+	h.Write([]byte(payload))
+	return h.Sum(nil)
+}
+
 // DisconnectStorageProvider implementation
 func (s *service) DisconnectStorageProvider(userID, providerID uint) error {
 	storageProvider, err := s.storageProviderPool.Get(providerID)
@@ -11,19 +11,29 @@ import (
 	"github.com/bccfilkom/drophere-go/domain"
 )
 
-const defaultTokenExpiryDuration int = 5
+const (
+	defaultTokenExpiryDuration                  int = 5
+	defaultEmailVerificationTokenExpiryDuration int = 24 * 60
+	defaultRefreshTokenDuration                     = 30 * 24 * time.Hour
+)
 
 // Config model
 type Config struct {
-	PasswordRecoveryTokenExpiryDuration int
-	RecoverPasswordWebURL               string
-	MailerEmail                         string
-	MailerName                          string
+	PasswordRecoveryTokenExpiryDuration     int
+	RecoverPasswordWebURL                   string
+	MailerEmail                             string
+	MailerName                              string
+	StorageProviderAuthorizationSecret      string
+	StorageProviderAuthorizationCallbackURL string
+	RefreshTokenDuration                    time.Duration
+	EmailVerificationTokenExpiryDuration    int
+	VerifyEmailWebURL                       string
 }
 
 type service struct {
 	userRepo            domain.UserRepository
 	userStorageCredRepo domain.UserStorageCredentialRepository
+	sessionRepo         domain.SessionRepository
 	authenticator       domain.Authenticator
 	mailer              domain.Mailer
 	passwordHasher      domain.Hasher
@@ -41,6 +51,7 @@ type service struct {
 func NewService(
 	userRepo domain.UserRepository,
 	userStorageCredRepo domain.UserStorageCredentialRepository,
+	sessionRepo domain.SessionRepository,
 	authenticator domain.Authenticator,
 	mailer domain.Mailer,
 	passwordHasher domain.Hasher,
@@ -50,9 +61,14 @@ func NewService(
 	textTemplates *textTemplate.Template,
 	config Config,
 ) domain.UserService {
+	if config.RefreshTokenDuration <= 0 {
+		config.RefreshTokenDuration = defaultRefreshTokenDuration
+	}
+
 	return &service{
 		userRepo:            userRepo,
 		userStorageCredRepo: userStorageCredRepo,
+		sessionRepo:         sessionRepo,
 		authenticator:       authenticator,
 		mailer:              mailer,
 		passwordHasher:      passwordHasher,
@@ -92,7 +108,7 @@ func (s *service) Register(email, name, password string) (*domain.User, error) {
 }
 
 // Auth implementation
-func (s *service) Auth(email, password string) (*domain.UserCredentials, error) {
+func (s *service) Auth(email, password string, device domain.SessionDevice) (*domain.UserCredentials, error) {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
 		return nil, err
@@ -102,7 +118,7 @@ func (s *service) Auth(email, password string) (*domain.UserCredentials, error)
 		return nil, domain.ErrUserInvalidPassword
 	}
 
-	return s.authenticator.Authenticate(user)
+	return s.startSession(user, device)
 }
 
 // Update implementation
@@ -117,8 +133,7 @@ func (s *service) Update(userID uint, name, newPassword, oldPassword *string) (*
 			return nil, domain.ErrUserInvalidPassword
 		}
 
-		u.Password, err = s.passwordHasher.Hash(*newPassword)
-		if err != nil {
+		if err = s.changePassword(u, *newPassword); err != nil {
 			return nil, err
 		}
 	}
@@ -127,7 +142,32 @@ func (s *service) Update(userID uint, name, newPassword, oldPassword *string) (*
 		u.Name = *name
 	}
 
-	return s.userRepo.Update(u)
+	u, err = s.userRepo.Update(u)
+	if err != nil {
+		return nil, err
+	}
+
+	// the sessions signed in with the old password must not be refreshed
+	if newPassword != nil {
+		if err = s.sessionRepo.RevokeAllByUser(u.ID, time.Now()); err != nil {
+			return nil, err
+		}
+	}
+
+	return u, nil
+}
+
+// changePassword sets the new password and increases the token version,
+// so the tokens minted before are rejected
+func (s *service) changePassword(u *domain.User, newPassword string) error {
+	password, err := s.passwordHasher.Hash(newPassword)
+	if err != nil {
+		return err
+	}
+
+	u.Password = password
+	u.TokenVersion++
+	return nil
 }
 
 // UpdateStorageToken implementation
@@ -184,29 +224,31 @@ func (s *service) RequestPasswordRecovery(email string) error {
 }
 
 func (s *service) sendPasswordRecoveryTokenToEmail(to domain.MailAddress, subject, email, token string) error {
+	return s.sendTemplateMail(to, subject, "request_password_recovery", map[string]string{
+		"ResetPasswordLink": fmt.Sprintf(
+			"%s?token=%s&email=%s",
+			s.config.RecoverPasswordWebURL,
+			token,
+			email,
+		),
+		"Token": token,
+	})
+}
+
+// sendTemplateMail sends the email rendered from the <templateName>_html and <templateName>_text templates
+func (s *service) sendTemplateMail(to domain.MailAddress, subject, templateName string, messageData map[string]string) error {
 
 	// preparing template
-	htmlTmpl := s.htmlTemplates.Lookup("request_password_recovery_html")
+	htmlTmpl := s.htmlTemplates.Lookup(templateName + "_html")
 	if htmlTmpl == nil {
 		return domain.ErrTemplateNotFound
 	}
 
-	textTmpl := s.textTemplates.Lookup("request_password_recovery_text")
+	textTmpl := s.textTemplates.Lookup(templateName + "_text")
 	if textTmpl == nil {
 		return domain.ErrTemplateNotFound
 	}
 
-	// preparing template content
-	messageData := map[string]string{
-		"ResetPasswordLink": fmt.Sprintf(
-			"%s?token=%s&email=%s",
-			s.config.RecoverPasswordWebURL,
-			token,
-			email,
-		),
-		"Token": token,
-	}
-
 	// injecting data to template
 	htmlMessage := &bytes.Buffer{}
 	htmlTmpl.Execute(htmlMessage, messageData)
@@ -251,8 +293,7 @@ func (s *service) RecoverPassword(email, token, newPassword string) error {
 		return domain.ErrUserPasswordRecoveryTokenExpired
 	}
 
-	u.Password, err = s.passwordHasher.Hash(newPassword)
-	if err != nil {
+	if err = s.changePassword(u, newPassword); err != nil {
 		return err
 	}
 
@@ -263,5 +304,5 @@ func (s *service) RecoverPassword(email, token, newPassword string) error {
 		return err
 	}
 
-	return nil
+	return s.sessionRepo.RevokeAllByUser(u.ID, time.Now())
 }
@@ -2,6 +2,7 @@ package user_test
 
 import (
 	"bytes"
+	"net/url"
 	"reflect"
 	"testing"
 	"time"
@@ -56,11 +57,21 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+
+	if _, err = htmlTemplates.New("email_verification_html").Parse("{{.VerifyEmailLink}}"); err != nil {
+		panic(err)
+	}
+
+	if _, err = textTemplates.New("email_verification_text").Parse("{{.VerifyEmailLink}}"); err != nil {
+		panic(err)
+	}
 }
 
-func newRepo() (domain.UserRepository, domain.UserStorageCredentialRepository) {
+func newRepo() (domain.UserRepository, domain.UserStorageCredentialRepository, domain.SessionRepository) {
 	memdb := inmemory.New()
-	return inmemory.NewUserRepository(memdb), inmemory.NewUserStorageCredentialRepository(memdb)
+	return inmemory.NewUserRepository(memdb),
+		inmemory.NewUserStorageCredentialRepository(memdb),
+		inmemory.NewSessionRepository(memdb)
 }
 
 func str2ptr(s string) *string {
@@ -85,10 +96,11 @@ func TestRegister(t *testing.T) {
 		{email: "new_user@drophere.link", name: "New User", password: "123456", wantErr: nil},
 	}
 
-	userRepo, userStorageCredRepo := newRepo()
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		mockMailer,
 		dummyHasher,
@@ -121,10 +133,11 @@ func TestAuth(t *testing.T) {
 		{email: "user@drophere.link", password: "123456", wantCreds: &domain.UserCredentials{Token: "user_token_1"}},
 	}
 
-	userRepo, userStorageCredRepo := newRepo()
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		mockMailer,
 		dummyHasher,
@@ -136,7 +149,7 @@ func TestAuth(t *testing.T) {
 	)
 
 	for i, tc := range tests {
-		gotCreds, gotErr := userSvc.Auth(tc.email, tc.password)
+		gotCreds, gotErr := userSvc.Auth(tc.email, tc.password, domain.SessionDevice{})
 		if gotErr != tc.wantErr {
 			t.Fatalf("test %d: expected: %v, got: %v", i, tc.wantErr, gotErr)
 		}
@@ -154,7 +167,7 @@ func TestUpdateStorageToken(t *testing.T) {
 		wantErr      error
 	}
 
-	userRepo, userStorageCredRepo := newRepo()
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
 	u, _ := userRepo.FindByID(1)
 
 	tests := []test{
@@ -175,6 +188,7 @@ func TestUpdateStorageToken(t *testing.T) {
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		mockMailer,
 		dummyHasher,
@@ -206,7 +220,7 @@ func TestUpdate(t *testing.T) {
 		wantErr     error
 	}
 
-	userRepo, userStorageCredRepo := newRepo()
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
 	u, _ := userRepo.FindByID(1)
 
 	tests := []test{
@@ -219,10 +233,11 @@ func TestUpdate(t *testing.T) {
 			password:    str2ptr("new_password123"),
 			oldPassword: str2ptr("123456"),
 			wantUser: &domain.User{
-				ID:       u.ID,
-				Email:    u.Email,
-				Name:     "new name 123",
-				Password: "new_password123",
+				ID:           u.ID,
+				Email:        u.Email,
+				Name:         "new name 123",
+				Password:     "new_password123",
+				TokenVersion: 1,
 			},
 		},
 	}
@@ -230,6 +245,7 @@ func TestUpdate(t *testing.T) {
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		mockMailer,
 		dummyHasher,
@@ -257,7 +273,7 @@ func TestRequestPasswordRecovery(t *testing.T) {
 		wantErr error
 	}
 
-	userRepo, userStorageCredRepo := newRepo()
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
 	u, _ := userRepo.FindByEmail("reset+pwd@drophere.link")
 	expectedToken := str2ptr("this_is_not_a_random_string")
 	emailHTMLTemplate := htmlTemplates.Lookup("request_password_recovery_html")
@@ -287,6 +303,7 @@ func TestRequestPasswordRecovery(t *testing.T) {
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		mockMailer,
 		dummyHasher,
@@ -330,7 +347,7 @@ func TestRecoverPassword(t *testing.T) {
 
 	recoverPasswordToken := "this_is_a_recover_password_token"
 
-	userRepo, userStorageCredRepo := newRepo()
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
 	u, _ := userRepo.FindByEmail("reset+pwd@drophere.link")
 	u.RecoverPasswordToken = str2ptr(recoverPasswordToken)
 	u.RecoverPasswordTokenExpiry = time2ptr(time.Now().Add(30 * time.Minute))
@@ -359,6 +376,7 @@ func TestRecoverPassword(t *testing.T) {
 				Password:                   "new_password_for_this_user",
 				RecoverPasswordToken:       nil,
 				RecoverPasswordTokenExpiry: nil,
+				TokenVersion:               1,
 			},
 			wantErr: nil,
 		},
@@ -367,6 +385,7 @@ func TestRecoverPassword(t *testing.T) {
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		mockMailer,
 		dummyHasher,
@@ -402,7 +421,7 @@ func TestConnectStorageProvider(t *testing.T) {
 		wantErr            error
 	}
 
-	userRepo, userStorageCredRepo := newRepo()
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
 	// user1, _ := userRepo.FindByID(1)
 
 	tests := []test{
@@ -442,6 +461,7 @@ func TestConnectStorageProvider(t *testing.T) {
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		mockMailer,
 		dummyHasher,
@@ -483,6 +503,172 @@ func TestConnectStorageProvider(t *testing.T) {
 	}
 }
 
+func TestStorageProviderAuthorization(t *testing.T) {
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
+
+	userSvc := user.NewService(
+		userRepo,
+		userStorageCredRepo,
+		sessionRepo,
+		authenticator,
+		mockMailer,
+		dummyHasher,
+		strGen,
+		storageProviderPool,
+		htmlTemplates,
+		textTemplates,
+		user.Config{
+			StorageProviderAuthorizationSecret:      "state_secret",
+			StorageProviderAuthorizationCallbackURL: "http://localhost:8080/oauth/callback/",
+		},
+	)
+
+	_, err := userSvc.StartStorageProviderAuthorization(1, 1234)
+	assert.Equal(t, domain.ErrStorageProviderInvalid, err)
+
+	_, err = userSvc.StartStorageProviderAuthorization(123, 1)
+	assert.Equal(t, domain.ErrUserNotFound, err)
+
+	authorizeURL, err := userSvc.StartStorageProviderAuthorization(357, 1)
+	assert.Nil(t, err)
+
+	parsedURL, err := url.Parse(authorizeURL)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost:8080/oauth/callback/1", parsedURL.Query().Get("redirect_uri"))
+	state := parsedURL.Query().Get("state")
+
+	type test struct {
+		providerID uint
+		state      string
+		code       string
+		wantErr    error
+	}
+
+	tests := []test{
+		{providerID: 1, state: "", code: storageprovider.MockAuthorizationCode, wantErr: domain.ErrStorageProviderInvalidAuthorizationState},
+		{providerID: 1, state: state + "x", code: storageprovider.MockAuthorizationCode, wantErr: domain.ErrStorageProviderInvalidAuthorizationState},
+		{providerID: 1, state: "eA." + state[3:], code: storageprovider.MockAuthorizationCode, wantErr: domain.ErrStorageProviderInvalidAuthorizationState},
+		{providerID: 1234, state: state, code: storageprovider.MockAuthorizationCode, wantErr: domain.ErrStorageProviderInvalidAuthorizationState},
+		{providerID: 1, state: state, code: storageprovider.MockAuthorizationCode, wantErr: nil},
+	}
+
+	storageprovider.SetSharedAccountInfo(domain.StorageProviderAccountInfo{
+		Email: "user_357@drophere.link",
+		Photo: "https://my.photo/user_357.jpg",
+	})
+
+	for i, tc := range tests {
+		gotErr := userSvc.CompleteStorageProviderAuthorization(tc.providerID, tc.state, tc.code)
+		if gotErr != tc.wantErr {
+			t.Fatalf("test %d: expected: %v, got: %v", i, tc.wantErr, gotErr)
+		}
+	}
+
+	// the state is signed with another secret
+	otherUserSvc := user.NewService(
+		userRepo,
+		userStorageCredRepo,
+		sessionRepo,
+		authenticator,
+		mockMailer,
+		dummyHasher,
+		strGen,
+		storageProviderPool,
+		htmlTemplates,
+		textTemplates,
+		user.Config{StorageProviderAuthorizationSecret: "another_secret"},
+	)
+	err = otherUserSvc.CompleteStorageProviderAuthorization(1, state, storageprovider.MockAuthorizationCode)
+	assert.Equal(t, domain.ErrStorageProviderInvalidAuthorizationState, err)
+
+	// the state can only be used once
+	err = userSvc.CompleteStorageProviderAuthorization(1, state, storageprovider.MockAuthorizationCode)
+	assert.Equal(t, domain.ErrStorageProviderInvalidAuthorizationState, err)
+
+	// invalid code is rejected by the provider
+	authorizeURL, _ = userSvc.StartStorageProviderAuthorization(357, 1)
+	parsedURL, _ = url.Parse(authorizeURL)
+	err = userSvc.CompleteStorageProviderAuthorization(1, parsedURL.Query().Get("state"), "invalid_code")
+	assert.NotNil(t, err)
+	assert.NotEqual(t, domain.ErrStorageProviderInvalidAuthorizationState, err)
+
+	ucs, _ := userStorageCredRepo.Find(domain.UserStorageCredentialFilters{
+		UserIDs:     []uint{357},
+		ProviderIDs: []uint{1},
+	}, false)
+	if assert.Len(t, ucs, 1) {
+		assert.Equal(t, "mock_access_token", ucs[0].ProviderCredential)
+		assert.Equal(t, "mock_refresh_token", ucs[0].ProviderRefreshToken)
+		assert.NotNil(t, ucs[0].ProviderCredentialExpiry)
+		assert.Equal(t, "user_357@drophere.link", ucs[0].Email)
+	}
+}
+
+func TestRefreshStorageProviderCredential(t *testing.T) {
+	type test struct {
+		cred            domain.UserStorageCredential
+		wantAccessToken string
+		wantErr         error
+	}
+
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
+	expiredAt := time2ptr(time.Now().Add(-time.Hour))
+
+	tests := []test{
+		{
+			cred:    domain.UserStorageCredential{ID: 2000, UserID: 1, ProviderID: 1234, ProviderRefreshToken: "mock_refresh_token"},
+			wantErr: domain.ErrStorageProviderInvalid,
+		},
+		{
+			// no refresh token
+			cred:            domain.UserStorageCredential{ID: 2000, UserID: 1, ProviderID: 1, ProviderCredential: "user_1_mock_token", ProviderCredentialExpiry: expiredAt},
+			wantAccessToken: "user_1_mock_token",
+			wantErr:         domain.ErrStorageProviderCredentialExpired,
+		},
+		{
+			// refresh token is revoked
+			cred:            domain.UserStorageCredential{ID: 2000, UserID: 1, ProviderID: 1, ProviderCredential: "user_1_mock_token", ProviderRefreshToken: "revoked", ProviderCredentialExpiry: expiredAt},
+			wantAccessToken: "user_1_mock_token",
+			wantErr:         domain.ErrStorageProviderCredentialExpired,
+		},
+		{
+			cred:            domain.UserStorageCredential{ID: 2000, UserID: 1, ProviderID: 1, ProviderCredential: "user_1_mock_token", ProviderRefreshToken: "mock_refresh_token", ProviderCredentialExpiry: expiredAt},
+			wantAccessToken: "mock_refreshed_access_token",
+			wantErr:         nil,
+		},
+	}
+
+	userSvc := user.NewService(
+		userRepo,
+		userStorageCredRepo,
+		sessionRepo,
+		authenticator,
+		mockMailer,
+		dummyHasher,
+		strGen,
+		storageProviderPool,
+		htmlTemplates,
+		textTemplates,
+		user.Config{},
+	)
+
+	for i, tc := range tests {
+		gotCred, gotErr := userSvc.RefreshStorageProviderCredential(tc.cred)
+		if gotErr != tc.wantErr {
+			t.Fatalf("test %d: expected: %v, got: %v", i, tc.wantErr, gotErr)
+		}
+
+		if tc.wantAccessToken != "" {
+			assert.Equal(t, tc.wantAccessToken, gotCred.ProviderCredential)
+		}
+	}
+
+	// the refreshed token is persisted
+	savedCred, _ := userStorageCredRepo.FindByID(2000, false)
+	assert.Equal(t, "mock_refreshed_access_token", savedCred.ProviderCredential)
+	assert.False(t, savedCred.IsExpiredAt(time.Now()))
+}
+
 func TestDisconnectStorageProvider(t *testing.T) {
 	type test struct {
 		userID     uint
@@ -490,7 +676,7 @@ func TestDisconnectStorageProvider(t *testing.T) {
 		wantErr    error
 	}
 
-	userRepo, userStorageCredRepo := newRepo()
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
 	// user1, _ := userRepo.FindByID(1)
 
 	tests := []test{
@@ -520,6 +706,7 @@ func TestDisconnectStorageProvider(t *testing.T) {
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		mockMailer,
 		dummyHasher,
@@ -559,7 +746,7 @@ func TestListStorageProviders(t *testing.T) {
 		wantErr           error
 	}
 
-	userRepo, userStorageCredRepo := newRepo()
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
 	uscsUser1, _ := userStorageCredRepo.Find(domain.UserStorageCredentialFilters{
 		UserIDs: []uint{1},
 	}, false)
@@ -582,6 +769,7 @@ func TestListStorageProviders(t *testing.T) {
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		mockMailer,
 		dummyHasher,
@@ -600,3 +788,72 @@ func TestListStorageProviders(t *testing.T) {
 
 	}
 }
+
+func TestVerifyEmail(t *testing.T) {
+	userRepo, userStorageCredRepo, sessionRepo := newRepo()
+
+	userSvc := user.NewService(
+		userRepo,
+		userStorageCredRepo,
+		sessionRepo,
+		authenticator,
+		mockMailer,
+		dummyHasher,
+		strGen,
+		storageProviderPool,
+		htmlTemplates,
+		textTemplates,
+		user.Config{VerifyEmailWebURL: "http://localhost:3000/verify-email"},
+	)
+
+	stringgenerator.SetMockResult("email_verification_token")
+	defer stringgenerator.SetMockResult("this_is_not_a_random_string")
+
+	err := userSvc.RequestEmailVerification(123)
+	assert.Equal(t, domain.ErrUserNotFound, err)
+
+	err = userSvc.RequestEmailVerification(1)
+	assert.Nil(t, err)
+
+	u, _ := userRepo.FindByID(1)
+	if assert.NotNil(t, u.EmailVerificationTokenExpiry) {
+		assert.WithinDuration(t, time.Now().Add(24*time.Hour), *u.EmailVerificationTokenExpiry, time.Minute)
+	}
+
+	type test struct {
+		email   string
+		token   string
+		wantErr error
+	}
+
+	tests := []test{
+		{email: "", token: "email_verification_token", wantErr: domain.ErrUserNotFound},
+		{email: "user@drophere.link", token: "", wantErr: domain.ErrUserNotFound},
+		{email: "user@drophere.link", token: "another_token", wantErr: domain.ErrUserNotFound},
+		{email: "user@drophere.link", token: "email_verification_token", wantErr: nil},
+		{email: "user@drophere.link", token: "email_verification_token", wantErr: domain.ErrUserEmailAlreadyVerified},
+	}
+
+	for i, tc := range tests {
+		gotErr := userSvc.VerifyEmail(tc.email, tc.token)
+		if gotErr != tc.wantErr {
+			t.Fatalf("test %d: expected: %v, got: %v", i, tc.wantErr, gotErr)
+		}
+	}
+
+	u, _ = userRepo.FindByID(1)
+	assert.True(t, u.IsEmailVerified())
+	assert.Nil(t, u.EmailVerificationToken)
+
+	err = userSvc.RequestEmailVerification(1)
+	assert.Equal(t, domain.ErrUserEmailAlreadyVerified, err)
+
+	// the expired token can not be used
+	u, _ = userRepo.FindByID(357)
+	u.EmailVerificationToken = str2ptr("expired_token")
+	u.EmailVerificationTokenExpiry = time2ptr(time.Now().Add(-time.Minute))
+	userRepo.Update(u)
+
+	err = userSvc.VerifyEmail("user_357@drophere.link", "expired_token")
+	assert.Equal(t, domain.ErrUserEmailVerificationTokenExpired, err)
+}
@@ -1,6 +1,9 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	// ErrUserStorageCredentialNotFound error
@@ -10,13 +13,29 @@ var (
 // UserStorageCredential stores information about user's account on
 // a storage provider (e.g. Dropbox)
 type UserStorageCredential struct {
-	ID                 uint
-	UserID             uint
-	User               User
-	ProviderID         uint
-	ProviderCredential string
-	Email              string
-	Photo              string
+	ID                       uint
+	UserID                   uint
+	User                     User
+	ProviderID               uint
+	ProviderCredential       string
+	ProviderRefreshToken     string
+	ProviderCredentialExpiry *time.Time
+	Email                    string
+	Photo                    string
+}
+
+// StorageProviderCredential returns the data needed to access storage provider API
+func (c *UserStorageCredential) StorageProviderCredential() StorageProviderCredential {
+	return StorageProviderCredential{
+		UserAccessToken: c.ProviderCredential,
+		RefreshToken:    c.ProviderRefreshToken,
+		Expiry:          c.ProviderCredentialExpiry,
+	}
+}
+
+// IsExpiredAt checks if the access token is already expired at the given time
+func (c *UserStorageCredential) IsExpiredAt(t time.Time) bool {
+	return c.ProviderCredentialExpiry != nil && !t.Before(*c.ProviderCredentialExpiry)
 }
 
 // UserStorageCredentialFilters stores filters to be used by
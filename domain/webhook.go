@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrWebhookNotFound error
+	ErrWebhookNotFound = errors.New("Webhook not found")
+	// ErrWebhookInvalidURL error
+	ErrWebhookInvalidURL = errors.New("Webhook URL must be an absolute http or https URL")
+	// ErrWebhookInvalidEvent error
+	ErrWebhookInvalidEvent = errors.New("Invalid webhook event")
+	// ErrWebhookNoEvent error
+	ErrWebhookNoEvent = errors.New("Webhook must subscribe to at least one event")
+)
+
+const (
+	// WebhookEventUploadCompleted is sent when a file is stored on the storage provider
+	WebhookEventUploadCompleted = "upload.completed"
+	// WebhookEventUploadFailed is sent when a file fails to be stored on the storage provider
+	WebhookEventUploadFailed = "upload.failed"
+	// WebhookEventLinkCreated is sent when a link is created
+	WebhookEventLinkCreated = "link.created"
+	// WebhookEventLinkDeleted is sent when a link is deleted
+	WebhookEventLinkDeleted = "link.deleted"
+	// WebhookEventLinkExpired is sent when the deadline of a link passes
+	WebhookEventLinkExpired = "link.expired"
+)
+
+// WebhookEvents lists every event a webhook can subscribe to
+var WebhookEvents = []string{
+	WebhookEventUploadCompleted,
+	WebhookEventUploadFailed,
+	WebhookEventLinkCreated,
+	WebhookEventLinkDeleted,
+	WebhookEventLinkExpired,
+}
+
+const (
+	// WebhookDeliveryPending is a delivery waiting for its next attempt
+	WebhookDeliveryPending = "pending"
+	// WebhookDeliverySucceeded is a delivery accepted by the endpoint
+	WebhookDeliverySucceeded = "succeeded"
+	// WebhookDeliveryFailed is a delivery which is not retried anymore
+	WebhookDeliveryFailed = "failed"
+)
+
+// Webhook is an endpoint of a user which receives the events of the user's links
+type Webhook struct {
+	ID     uint
+	UserID uint
+	URL    string
+	// Secret signs the payloads with HMAC-SHA256
+	Secret string
+	// Events is comma-separated list of the subscribed events
+	Events    string
+	Active    bool
+	CreatedAt time.Time
+}
+
+// EventList returns the subscribed events
+func (w *Webhook) EventList() []string {
+	return splitList(w.Events)
+}
+
+// Subscribes checks whether the webhook subscribes to the event
+func (w *Webhook) Subscribes(event string) bool {
+	for _, e := range w.EventList() {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery is an event sent to a webhook, along with its delivery attempts
+type WebhookDelivery struct {
+	ID        uint
+	WebhookID uint
+	Event     string
+	// Payload is the JSON body sent to the webhook
+	Payload  string
+	Status   string
+	Attempts int
+	// ResponseStatus is the HTTP status code of the last attempt, 0 when there is no response
+	ResponseStatus int
+	// LastError describes why the last attempt failed
+	LastError     string
+	NextAttemptAt *time.Time
+	DeliveredAt   *time.Time
+	CreatedAt     time.Time
+}
+
+// WebhookSender sends the webhook payloads
+type WebhookSender interface {
+	Send(url string, headers map[string]string, body []byte) (statusCode int, err error)
+}
+
+// WebhookRepository abstraction
+type WebhookRepository interface {
+	Create(w *Webhook) (*Webhook, error)
+	FindByID(id uint) (*Webhook, error)
+	ListByUser(userID uint) ([]Webhook, error)
+	Update(w *Webhook) (*Webhook, error)
+	Delete(w *Webhook) error
+	CreateDelivery(d *WebhookDelivery) (*WebhookDelivery, error)
+	UpdateDelivery(d *WebhookDelivery) (*WebhookDelivery, error)
+	ListDueDeliveries(now time.Time, limit int) ([]WebhookDelivery, error)
+	ListDeliveriesByWebhook(webhookID uint, limit int) ([]WebhookDelivery, error)
+}
+
+// WebhookService abstraction
+type WebhookService interface {
+	CreateWebhook(user *User, url string, events []string) (*Webhook, error)
+	UpdateWebhook(id uint, url *string, events []string, active *bool) (*Webhook, error)
+	DeleteWebhook(id uint) error
+	FetchWebhook(id uint) (*Webhook, error)
+	ListWebhooks(userID uint) ([]Webhook, error)
+	ListDeliveries(webhookID uint, limit int) ([]WebhookDelivery, error)
+	DispatchLinkEvent(event string, l *Link) error
+	DispatchUploadEvent(event string, l *Link, s *Submission) error
+	DispatchExpiredLinks(from, to time.Time) error
+	DeliverDue(now time.Time) error
+}
@@ -0,0 +1,303 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+const (
+	// SignatureHeader holds "sha256=" followed by the hex-encoded HMAC-SHA256 of the body
+	SignatureHeader = "X-Drophere-Signature"
+	// EventHeader holds the event name
+	EventHeader = "X-Drophere-Event"
+	// DeliveryHeader holds the delivery ID, it is the same on every retry
+	DeliveryHeader = "X-Drophere-Delivery"
+)
+
+// payload is the JSON body sent to the webhooks
+type payload struct {
+	Event     string      `json:"event"`
+	CreatedAt time.Time   `json:"createdAt"`
+	Data      payloadData `json:"data"`
+}
+
+type payloadData struct {
+	Link       linkPayload        `json:"link"`
+	Submission *submissionPayload `json:"submission,omitempty"`
+}
+
+type linkPayload struct {
+	ID       uint       `json:"id"`
+	Title    string     `json:"title"`
+	Slug     string     `json:"slug"`
+	Deadline *time.Time `json:"deadline"`
+	OpensAt  *time.Time `json:"opensAt"`
+}
+
+type submissionPayload struct {
+	ID              uint              `json:"id"`
+	FileName        string            `json:"fileName"`
+	Size            int64             `json:"size"`
+	ContentType     string            `json:"contentType"`
+	UploaderName    string            `json:"uploaderName"`
+	UploaderEmail   string            `json:"uploaderEmail"`
+	Identifier      string            `json:"identifier"`
+	FormAnswers     map[string]string `json:"formAnswers"`
+	Status          string            `json:"status"`
+	Late            bool              `json:"late"`
+	LatenessSeconds int64             `json:"latenessSeconds"`
+	ReceiptID       string            `json:"receiptId"`
+	SHA256          string            `json:"sha256"`
+	CreatedAt       time.Time         `json:"createdAt"`
+}
+
+func newLinkPayload(l *domain.Link) linkPayload {
+	return linkPayload{
+		ID:       l.ID,
+		Title:    l.Title,
+		Slug:     l.Slug,
+		Deadline: l.Deadline,
+		OpensAt:  l.OpensAt,
+	}
+}
+
+// DispatchLinkEvent queues the link event for the owner's webhooks
+func (s *service) DispatchLinkEvent(event string, l *domain.Link) error {
+	return s.dispatch(l.UserID, event, payloadData{Link: newLinkPayload(l)})
+}
+
+// DispatchUploadEvent queues the upload event for the link owner's webhooks
+func (s *service) DispatchUploadEvent(event string, l *domain.Link, sub *domain.Submission) error {
+	answers := map[string]string(sub.FormAnswers)
+	if answers == nil {
+		answers = map[string]string{}
+	}
+
+	return s.dispatch(l.UserID, event, payloadData{
+		Link: newLinkPayload(l),
+		Submission: &submissionPayload{
+			ID:              sub.ID,
+			FileName:        sub.FileName,
+			Size:            sub.Size,
+			ContentType:     sub.ContentType,
+			UploaderName:    sub.UploaderName,
+			UploaderEmail:   sub.UploaderEmail,
+			Identifier:      sub.RosterIdentifier,
+			FormAnswers:     answers,
+			Status:          sub.Status,
+			Late:            sub.Late,
+			LatenessSeconds: sub.LatenessSeconds,
+			ReceiptID:       sub.ReceiptID,
+			SHA256:          sub.SHA256,
+			CreatedAt:       sub.CreatedAt,
+		},
+	})
+}
+
+// DispatchExpiredLinks queues the link.expired event of the links which stop accepting uploads
+// from the start of the range until before its end, i.e. after the grace period of their late policy.
+// A failing link does not stop the others, the errors are returned together
+func (s *service) DispatchExpiredLinks(from, to time.Time) error {
+	links, err := s.linkRepo.ListClosingBetween(from, to)
+	if err != nil {
+		return err
+	}
+
+	var errs errorList
+	for i := range links {
+		if links[i].Status(to) != domain.LinkStatusClosed {
+			continue
+		}
+
+		if err = s.DispatchLinkEvent(domain.WebhookEventLinkExpired, &links[i]); err != nil {
+			errs = append(errs, fmt.Errorf("link %d: %v", links[i].ID, err))
+		}
+	}
+
+	return errs.err()
+}
+
+// errorList combines the errors of the links which are skipped
+type errorList []error
+
+func (e errorList) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// err returns nil when there is no error
+func (e errorList) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
+
+// dispatch records a pending delivery for each active webhook of the user subscribing to the event,
+// the deliveries are sent by DeliverDue
+func (s *service) dispatch(userID uint, event string, data payloadData) error {
+	webhooks, err := s.webhookRepo.ListByUser(userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var body []byte
+	for _, w := range webhooks {
+		if !w.Active || !w.Subscribes(event) {
+			continue
+		}
+
+		// the payload is the same for every webhook
+		if body == nil {
+			body, err = json.Marshal(payload{Event: event, CreatedAt: now, Data: data})
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = s.webhookRepo.CreateDelivery(&domain.WebhookDelivery{
+			WebhookID:     w.ID,
+			Event:         event,
+			Payload:       string(body),
+			Status:        domain.WebhookDeliveryPending,
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// DeliverDue sends the pending deliveries whose next attempt is due. A delivery succeeds when the
+// endpoint responds with 2xx status, otherwise it is retried with exponential backoff
+// until the maximum attempts is reached. The deliveries are sent by a bounded number of workers
+// so a slow endpoint does not hold up the others
+func (s *service) DeliverDue(now time.Time) error {
+	deliveries, err := s.webhookRepo.ListDueDeliveries(now, dueDeliveryBatchSize)
+	if err != nil {
+		return err
+	}
+
+	webhooks := make(map[uint]*domain.Webhook)
+	due := make([]int, 0, len(deliveries))
+	for i := range deliveries {
+		d := &deliveries[i]
+
+		w, ok := webhooks[d.WebhookID]
+		if !ok {
+			w, err = s.webhookRepo.FindByID(d.WebhookID)
+			if err != nil && err != domain.ErrWebhookNotFound {
+				return err
+			}
+			webhooks[d.WebhookID] = w
+		}
+
+		if w == nil || !w.Active {
+			d.Status = domain.WebhookDeliveryFailed
+			d.LastError = "Webhook is deleted or disabled"
+			d.NextAttemptAt = nil
+			continue
+		}
+		due = append(due, i)
+	}
+
+	pending := make(chan int)
+	var wg sync.WaitGroup
+	for n := 0; n < s.config.Concurrency && n < len(due); n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range pending {
+				d := &deliveries[i]
+				s.attempt(webhooks[d.WebhookID], d, now)
+			}
+		}()
+	}
+	for _, i := range due {
+		pending <- i
+	}
+	close(pending)
+	wg.Wait()
+
+	// the deliveries which are already sent are still updated when one of the updates fails
+	var firstErr error
+	for i := range deliveries {
+		if _, err = s.webhookRepo.UpdateDelivery(&deliveries[i]); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return firstErr
+}
+
+// attempt sends the delivery once and schedules the next attempt when it fails
+func (s *service) attempt(w *domain.Webhook, d *domain.WebhookDelivery, now time.Time) {
+	body := []byte(d.Payload)
+	statusCode, err := s.sender.Send(w.URL, map[string]string{
+		"Content-Type":  "application/json",
+		EventHeader:     d.Event,
+		DeliveryHeader:  strconv.FormatUint(uint64(d.ID), 10),
+		SignatureHeader: "sha256=" + Sign(w.Secret, body),
+	}, body)
+
+	d.Attempts++
+	d.ResponseStatus = statusCode
+
+	if err == nil && statusCode >= 200 && statusCode < 300 {
+		d.Status = domain.WebhookDeliverySucceeded
+		d.LastError = ""
+		d.NextAttemptAt = nil
+		d.DeliveredAt = &now
+		return
+	}
+
+	if err != nil {
+		d.LastError = err.Error()
+	} else {
+		d.LastError = fmt.Sprintf("Unexpected response status %d", statusCode)
+	}
+
+	if d.Attempts >= s.config.MaxAttempts {
+		d.Status = domain.WebhookDeliveryFailed
+		d.NextAttemptAt = nil
+		return
+	}
+
+	nextAttemptAt := now.Add(s.backoff(d.Attempts))
+	d.NextAttemptAt = &nextAttemptAt
+}
+
+// backoff returns the delay after the attempts, it doubles on each retry up to the maximum
+func (s *service) backoff(attempts int) time.Duration {
+	backoff := s.config.MaxBackoff
+	// the shift is bounded to avoid overflow
+	if shift := attempts - 1; shift < 32 {
+		if doubled := s.config.InitialBackoff << uint(shift); doubled > 0 && doubled < backoff {
+			backoff = doubled
+		}
+	}
+	return backoff
+}
+
+// Sign returns the hex-encoded HMAC-SHA256 of the body using the webhook's secret
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,198 @@
+package webhook
+
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+const (
+	defaultMaxAttempts    = 8
+	defaultInitialBackoff = time.Minute
+	defaultMaxBackoff     = 24 * time.Hour
+	defaultConcurrency    = 10
+
+	// dueDeliveryBatchSize is the number of deliveries attempted on each DeliverDue call
+	dueDeliveryBatchSize = 100
+
+	defaultDeliveryLimit = 50
+	maxDeliveryLimit     = 200
+)
+
+// Config holds the delivery retry policy
+type Config struct {
+	// MaxAttempts is the number of attempts before a delivery is marked as failed
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry, it doubles on each retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the delay between the retries
+	MaxBackoff time.Duration
+	// Concurrency is the number of deliveries sent at the same time
+	Concurrency int
+}
+
+type service struct {
+	webhookRepo     domain.WebhookRepository
+	linkRepo        domain.LinkRepository
+	sender          domain.WebhookSender
+	stringGenerator domain.StringGenerator
+	config          Config
+}
+
+// NewService returns new service instance
+func NewService(
+	webhookRepo domain.WebhookRepository,
+	linkRepo domain.LinkRepository,
+	sender domain.WebhookSender,
+	stringGenerator domain.StringGenerator,
+	config Config,
+) domain.WebhookService {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.InitialBackoff <= 0 {
+		config.InitialBackoff = defaultInitialBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultMaxBackoff
+	}
+	if config.Concurrency < 1 {
+		config.Concurrency = defaultConcurrency
+	}
+
+	return &service{
+		webhookRepo:     webhookRepo,
+		linkRepo:        linkRepo,
+		sender:          sender,
+		stringGenerator: stringGenerator,
+		config:          config,
+	}
+}
+
+// CreateWebhook registers the endpoint with a generated secret
+func (s *service) CreateWebhook(user *domain.User, endpoint string, events []string) (*domain.Webhook, error) {
+	endpoint, err := normalizeURL(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	eventList, err := normalizeEvents(events)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.webhookRepo.Create(&domain.Webhook{
+		UserID:    user.ID,
+		URL:       endpoint,
+		Secret:    s.stringGenerator.Generate(),
+		Events:    eventList,
+		Active:    true,
+		CreatedAt: time.Now(),
+	})
+}
+
+// UpdateWebhook changes the given fields, nil means unchanged
+func (s *service) UpdateWebhook(id uint, endpoint *string, events []string, active *bool) (*domain.Webhook, error) {
+	w, err := s.webhookRepo.FindByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if endpoint != nil {
+		if w.URL, err = normalizeURL(*endpoint); err != nil {
+			return nil, err
+		}
+	}
+
+	if events != nil {
+		if w.Events, err = normalizeEvents(events); err != nil {
+			return nil, err
+		}
+	}
+
+	if active != nil {
+		w.Active = *active
+	}
+
+	return s.webhookRepo.Update(w)
+}
+
+// DeleteWebhook removes the webhook along with its deliveries
+func (s *service) DeleteWebhook(id uint) error {
+	w, err := s.webhookRepo.FindByID(id)
+	if err != nil {
+		return err
+	}
+
+	return s.webhookRepo.Delete(w)
+}
+
+// FetchWebhook returns the webhook
+func (s *service) FetchWebhook(id uint) (*domain.Webhook, error) {
+	return s.webhookRepo.FindByID(id)
+}
+
+// ListWebhooks returns the webhooks of the user
+func (s *service) ListWebhooks(userID uint) ([]domain.Webhook, error) {
+	return s.webhookRepo.ListByUser(userID)
+}
+
+// ListDeliveries returns the latest deliveries of the webhook, newest first
+func (s *service) ListDeliveries(webhookID uint, limit int) ([]domain.WebhookDelivery, error) {
+	if limit < 1 {
+		limit = defaultDeliveryLimit
+	}
+	if limit > maxDeliveryLimit {
+		limit = maxDeliveryLimit
+	}
+
+	return s.webhookRepo.ListDeliveriesByWebhook(webhookID, limit)
+}
+
+// normalizeURL checks that the endpoint is an absolute http or https URL
+func normalizeURL(endpoint string) (string, error) {
+	endpoint = strings.TrimSpace(endpoint)
+
+	u, err := url.Parse(endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return "", domain.ErrWebhookInvalidURL
+	}
+
+	return endpoint, nil
+}
+
+// normalizeEvents checks the events and returns them as comma-separated list without duplicates
+func normalizeEvents(events []string) (string, error) {
+	seen := make(map[string]bool, len(events))
+	eventList := make([]string, 0, len(events))
+	for _, event := range events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if seen[event] {
+			continue
+		}
+
+		if !isKnownEvent(event) {
+			return "", domain.ErrWebhookInvalidEvent
+		}
+
+		seen[event] = true
+		eventList = append(eventList, event)
+	}
+
+	if len(eventList) < 1 {
+		return "", domain.ErrWebhookNoEvent
+	}
+
+	return strings.Join(eventList, ","), nil
+}
+
+func isKnownEvent(event string) bool {
+	for _, e := range domain.WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,293 @@
+package webhook_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/domain/webhook"
+	"github.com/bccfilkom/drophere-go/infrastructure/database/inmemory"
+	"github.com/bccfilkom/drophere-go/infrastructure/stringgenerator"
+	"github.com/bccfilkom/drophere-go/infrastructure/webhooksender"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newService() (domain.WebhookService, domain.LinkRepository) {
+	memdb := inmemory.New()
+	linkRepo := inmemory.NewLinkRepository(memdb)
+
+	webhookSvc := webhook.NewService(
+		inmemory.NewWebhookRepository(memdb),
+		linkRepo,
+		webhooksender.NewMock(),
+		stringgenerator.NewMock(),
+		webhook.Config{MaxAttempts: 3, InitialBackoff: time.Minute},
+	)
+
+	return webhookSvc, linkRepo
+}
+
+func TestCreateWebhook(t *testing.T) {
+	type test struct {
+		url        string
+		events     []string
+		wantEvents string
+		wantErr    error
+	}
+
+	tests := []test{
+		{
+			url:        " https://lms.example.com/hooks/drophere ",
+			events:     []string{"upload.completed", "Link.Created ", "upload.completed"},
+			wantEvents: "upload.completed,link.created",
+		},
+		{url: "ftp://lms.example.com", events: []string{"upload.completed"}, wantErr: domain.ErrWebhookInvalidURL},
+		{url: "/hooks/drophere", events: []string{"upload.completed"}, wantErr: domain.ErrWebhookInvalidURL},
+		{url: "https://lms.example.com", events: []string{"upload.started"}, wantErr: domain.ErrWebhookInvalidEvent},
+		{url: "https://lms.example.com", events: []string{}, wantErr: domain.ErrWebhookNoEvent},
+	}
+
+	stringgenerator.SetMockResult("webhook_secret")
+	user := &domain.User{ID: 1}
+
+	for i, tc := range tests {
+		webhookSvc, _ := newService()
+
+		w, err := webhookSvc.CreateWebhook(user, tc.url, tc.events)
+		assert.Equal(t, tc.wantErr, err, "test %d", i)
+		if tc.wantErr != nil {
+			continue
+		}
+
+		assert.Equal(t, "https://lms.example.com/hooks/drophere", w.URL, "test %d", i)
+		assert.Equal(t, tc.wantEvents, w.Events, "test %d", i)
+		assert.Equal(t, "webhook_secret", w.Secret, "test %d", i)
+		assert.True(t, w.Active, "test %d", i)
+	}
+}
+
+func TestUpdateWebhook(t *testing.T) {
+	webhookSvc, _ := newService()
+
+	w, _ := webhookSvc.CreateWebhook(&domain.User{ID: 1}, "https://lms.example.com", []string{"upload.completed"})
+
+	active := false
+	updated, err := webhookSvc.UpdateWebhook(w.ID, nil, []string{"link.expired"}, &active)
+	assert.Nil(t, err)
+	assert.Equal(t, "https://lms.example.com", updated.URL)
+	assert.Equal(t, "link.expired", updated.Events)
+	assert.False(t, updated.Active)
+
+	_, err = webhookSvc.UpdateWebhook(w.ID, nil, []string{}, nil)
+	assert.Equal(t, domain.ErrWebhookNoEvent, err)
+
+	_, err = webhookSvc.UpdateWebhook(w.ID+1, nil, nil, nil)
+	assert.Equal(t, domain.ErrWebhookNotFound, err)
+
+	err = webhookSvc.DeleteWebhook(w.ID)
+	assert.Nil(t, err)
+
+	_, err = webhookSvc.FetchWebhook(w.ID)
+	assert.Equal(t, domain.ErrWebhookNotFound, err)
+}
+
+func TestDeliverDue(t *testing.T) {
+	webhooksender.ClearRequests()
+	webhooksender.SetMockResult(200, nil)
+	defer webhooksender.SetMockResult(200, nil)
+
+	webhookSvc, linkRepo := newService()
+
+	stringgenerator.SetMockResult("webhook_secret")
+	w, _ := webhookSvc.CreateWebhook(&domain.User{ID: 1}, "https://lms.example.com", []string{"upload.completed"})
+	// webhooks of other users and other events are not delivered
+	webhookSvc.CreateWebhook(&domain.User{ID: 357}, "https://other.example.com", []string{"upload.completed"})
+	webhookSvc.CreateWebhook(&domain.User{ID: 1}, "https://failed.example.com", []string{"upload.failed"})
+
+	l, _ := linkRepo.FindByID(1)
+	sub := &domain.Submission{
+		ID:        2,
+		LinkID:    1,
+		FileName:  "report.pdf",
+		Size:      1024,
+		Status:    domain.SubmissionStatusSucceeded,
+		ReceiptID: "receipt_2",
+		CreatedAt: time.Date(2019, time.July, 15, 10, 0, 0, 0, time.UTC),
+	}
+	err := webhookSvc.DispatchUploadEvent(domain.WebhookEventUploadCompleted, l, sub)
+	assert.Nil(t, err)
+
+	now := time.Now()
+	err = webhookSvc.DeliverDue(now)
+	assert.Nil(t, err)
+	if assert.Len(t, webhooksender.MockRequests, 1) {
+		req := webhooksender.MockRequests[0]
+		assert.Equal(t, "https://lms.example.com", req.URL)
+		assert.Equal(t, "upload.completed", req.Headers[webhook.EventHeader])
+		assert.Equal(t, "sha256="+webhook.Sign("webhook_secret", []byte(req.Body)), req.Headers[webhook.SignatureHeader])
+
+		var body struct {
+			Event string
+			Data  struct {
+				Link       struct{ Slug string }
+				Submission struct{ FileName, ReceiptID string }
+			}
+		}
+		assert.Nil(t, json.Unmarshal([]byte(req.Body), &body))
+		assert.Equal(t, "upload.completed", body.Event)
+		assert.Equal(t, "drop-here", body.Data.Link.Slug)
+		assert.Equal(t, "report.pdf", body.Data.Submission.FileName)
+		assert.Equal(t, "receipt_2", body.Data.Submission.ReceiptID)
+	}
+
+	deliveries, _ := webhookSvc.ListDeliveries(w.ID, 0)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, domain.WebhookDeliverySucceeded, deliveries[0].Status)
+		assert.Equal(t, 1, deliveries[0].Attempts)
+		assert.Equal(t, 200, deliveries[0].ResponseStatus)
+		assert.Nil(t, deliveries[0].NextAttemptAt)
+	}
+
+	// the succeeded delivery is not sent again
+	webhooksender.ClearRequests()
+	webhookSvc.DeliverDue(now.Add(time.Hour))
+	assert.Len(t, webhooksender.MockRequests, 0)
+}
+
+func TestDeliverDueRetry(t *testing.T) {
+	webhooksender.ClearRequests()
+	webhooksender.SetMockResult(0, errors.New("connection refused"))
+	defer webhooksender.SetMockResult(200, nil)
+
+	webhookSvc, linkRepo := newService()
+
+	w, _ := webhookSvc.CreateWebhook(&domain.User{ID: 1}, "https://lms.example.com", []string{"link.created"})
+	l, _ := linkRepo.FindByID(1)
+	webhookSvc.DispatchLinkEvent(domain.WebhookEventLinkCreated, l)
+
+	now := time.Now()
+	webhookSvc.DeliverDue(now)
+
+	deliveries, _ := webhookSvc.ListDeliveries(w.ID, 0)
+	assert.Equal(t, domain.WebhookDeliveryPending, deliveries[0].Status)
+	assert.Equal(t, "connection refused", deliveries[0].LastError)
+	assert.Equal(t, now.Add(time.Minute), *deliveries[0].NextAttemptAt)
+
+	// the retry is not due yet
+	webhookSvc.DeliverDue(now.Add(30 * time.Second))
+	assert.Len(t, webhooksender.MockRequests, 1)
+
+	// the backoff doubles on each retry
+	webhooksender.SetMockResult(500, nil)
+	now = now.Add(time.Minute)
+	webhookSvc.DeliverDue(now)
+
+	deliveries, _ = webhookSvc.ListDeliveries(w.ID, 0)
+	assert.Equal(t, domain.WebhookDeliveryPending, deliveries[0].Status)
+	assert.Equal(t, 500, deliveries[0].ResponseStatus)
+	assert.Equal(t, "Unexpected response status 500", deliveries[0].LastError)
+	assert.Equal(t, now.Add(2*time.Minute), *deliveries[0].NextAttemptAt)
+
+	// the delivery fails after the maximum attempts
+	now = now.Add(2 * time.Minute)
+	webhookSvc.DeliverDue(now)
+
+	deliveries, _ = webhookSvc.ListDeliveries(w.ID, 0)
+	assert.Equal(t, domain.WebhookDeliveryFailed, deliveries[0].Status)
+	assert.Equal(t, 3, deliveries[0].Attempts)
+	assert.Nil(t, deliveries[0].NextAttemptAt)
+	assert.Len(t, webhooksender.MockRequests, 3)
+}
+
+func TestDispatchExpiredLinks(t *testing.T) {
+	webhooksender.ClearRequests()
+	webhookSvc, linkRepo := newService()
+
+	w, _ := webhookSvc.CreateWebhook(&domain.User{ID: 1}, "https://lms.example.com", []string{"link.expired"})
+
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	l, _ := linkRepo.FindByID(1)
+	l.Deadline = &expired
+	linkRepo.Update(l)
+
+	// the link accepting every late upload never expires
+	upcoming := now.Add(time.Minute)
+	l, _ = linkRepo.FindByID(2)
+	l.Deadline = &upcoming
+	l.LatePolicy = domain.LatePolicyAccept
+	linkRepo.Update(l)
+
+	// the link with a grace period expires once the grace period passes
+	l, _ = linkRepo.FindByID(3)
+	l.UserID = 1
+	l.Deadline = &expired
+	l.LatePolicy = domain.LatePolicyGracePeriod
+	l.GracePeriodMinutes = 30
+	linkRepo.Update(l)
+
+	err := webhookSvc.DispatchExpiredLinks(now.Add(-time.Hour), now)
+	assert.Nil(t, err)
+
+	deliveries, _ := webhookSvc.ListDeliveries(w.ID, 0)
+	if assert.Len(t, deliveries, 1) {
+		assert.Equal(t, domain.WebhookEventLinkExpired, deliveries[0].Event)
+		assert.Contains(t, deliveries[0].Payload, `"slug":"drop-here"`)
+	}
+
+	err = webhookSvc.DispatchExpiredLinks(now, now.Add(time.Hour))
+	assert.Nil(t, err)
+
+	deliveries, _ = webhookSvc.ListDeliveries(w.ID, 0)
+	if assert.Len(t, deliveries, 2) {
+		assert.Contains(t, deliveries[0].Payload+deliveries[1].Payload, `"slug":"another-link"`)
+	}
+}
+
+// failingWebhookRepository fails listing the webhooks of a user
+type failingWebhookRepository struct {
+	domain.WebhookRepository
+	userID uint
+}
+
+func (repo *failingWebhookRepository) ListByUser(userID uint) ([]domain.Webhook, error) {
+	if userID == repo.userID {
+		return nil, errors.New("connection lost")
+	}
+	return repo.WebhookRepository.ListByUser(userID)
+}
+
+func TestDispatchExpiredLinksContinuesAfterFailure(t *testing.T) {
+	memdb := inmemory.New()
+	linkRepo := inmemory.NewLinkRepository(memdb)
+	webhookSvc := webhook.NewService(
+		&failingWebhookRepository{inmemory.NewWebhookRepository(memdb), 1},
+		linkRepo,
+		webhooksender.NewMock(),
+		stringgenerator.NewMock(),
+		webhook.Config{},
+	)
+
+	w, _ := webhookSvc.CreateWebhook(&domain.User{ID: 357}, "https://lms.example.com", []string{"link.expired"})
+
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	for _, id := range []uint{1, 3} {
+		l, _ := linkRepo.FindByID(id)
+		l.Deadline = &expired
+		linkRepo.Update(l)
+	}
+
+	err := webhookSvc.DispatchExpiredLinks(now.Add(-time.Hour), now)
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "connection lost")
+	}
+
+	deliveries, _ := webhookSvc.ListDeliveries(w.ID, 0)
+	if assert.Len(t, deliveries, 1) {
+		assert.Contains(t, deliveries[0].Payload, `"slug":"another-link"`)
+	}
+}
@@ -0,0 +1,17 @@
+CREATE TABLE `submissions` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `link_id` int(10) unsigned NOT NULL,
+  `file_name` varchar(255) CHARACTER SET utf8mb4 NOT NULL,
+  `stored_path` varchar(1024) CHARACTER SET utf8mb4 NOT NULL DEFAULT '',
+  `size` bigint NOT NULL DEFAULT 0,
+  `content_type` varchar(255) NOT NULL DEFAULT '',
+  `uploader_name` varchar(255) CHARACTER SET utf8mb4 NOT NULL DEFAULT '',
+  `uploader_email` varchar(255) NOT NULL DEFAULT '',
+  `uploader_ip` varchar(45) NOT NULL DEFAULT '',
+  `provider_id` int(10) unsigned NOT NULL,
+  `status` varchar(16) NOT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `submissions_link_id_links_id_foreign` (`link_id`),
+  CONSTRAINT `submissions_link_id_links_id_foreign` FOREIGN KEY (`link_id`) REFERENCES `links` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
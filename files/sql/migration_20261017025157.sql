@@ -0,0 +1,3 @@
+-- S3-compatible storage credentials are stored as JSON which may exceed 255 characters
+ALTER TABLE `user_storage_credentials`
+MODIFY `provider_credential` text NOT NULL;
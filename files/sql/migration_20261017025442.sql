@@ -0,0 +1,3 @@
+ALTER TABLE `user_storage_credentials`
+ADD `provider_refresh_token` text NULL,
+ADD `provider_credential_expiry` datetime NULL;
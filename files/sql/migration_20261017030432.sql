@@ -0,0 +1,4 @@
+ALTER TABLE `links`
+ADD `max_file_size` bigint NOT NULL DEFAULT 0,
+ADD `allowed_extensions` varchar(1024) NOT NULL DEFAULT '',
+ADD `allowed_mime_types` varchar(1024) NOT NULL DEFAULT '';
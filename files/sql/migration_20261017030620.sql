@@ -0,0 +1,5 @@
+ALTER TABLE `links`
+ADD `notification_mode` varchar(16) NOT NULL DEFAULT 'none';
+
+ALTER TABLE `submissions`
+ADD `notified_at` datetime NULL DEFAULT NULL;
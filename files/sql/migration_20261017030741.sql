@@ -0,0 +1,4 @@
+ALTER TABLE `submissions`
+ADD `receipt_id` varchar(64) NOT NULL DEFAULT '',
+ADD `sha256` char(64) NOT NULL DEFAULT '',
+ADD KEY `submissions_receipt_id` (`receipt_id`);
@@ -0,0 +1,5 @@
+ALTER TABLE `links`
+ADD `form_fields` text CHARACTER SET utf8mb4 NULL;
+
+ALTER TABLE `submissions`
+ADD `form_answers` text CHARACTER SET utf8mb4 NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE `links`
+ADD `file_name_template` varchar(255) CHARACTER SET utf8mb4 NOT NULL DEFAULT '';
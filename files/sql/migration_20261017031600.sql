@@ -0,0 +1,13 @@
+CREATE TABLE `roster_entries` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `link_id` int(10) unsigned NOT NULL,
+  `identifier` varchar(255) CHARACTER SET utf8mb4 NOT NULL,
+  `name` varchar(255) CHARACTER SET utf8mb4 NOT NULL DEFAULT '',
+  `email` varchar(255) NOT NULL DEFAULT '',
+  PRIMARY KEY (`id`),
+  KEY `roster_entries_link_id_links_id_foreign` (`link_id`),
+  CONSTRAINT `roster_entries_link_id_links_id_foreign` FOREIGN KEY (`link_id`) REFERENCES `links` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+ALTER TABLE `submissions`
+ADD `roster_identifier` varchar(255) CHARACTER SET utf8mb4 NOT NULL DEFAULT '';
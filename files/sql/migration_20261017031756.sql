@@ -0,0 +1,8 @@
+ALTER TABLE `links`
+ADD `late_policy` varchar(16) NOT NULL DEFAULT 'close',
+ADD `grace_period_minutes` int NOT NULL DEFAULT 0,
+ADD `late_subfolder` tinyint(1) NOT NULL DEFAULT 0;
+
+ALTER TABLE `submissions`
+ADD `late` tinyint(1) NOT NULL DEFAULT 0,
+ADD `lateness_seconds` bigint NOT NULL DEFAULT 0;
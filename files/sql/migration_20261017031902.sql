@@ -0,0 +1,2 @@
+ALTER TABLE `links`
+ADD `opens_at` datetime NULL DEFAULT NULL;
@@ -0,0 +1,21 @@
+CREATE TABLE `reminder_subscriptions` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `link_id` int(10) unsigned NOT NULL,
+  `email` varchar(255) NOT NULL,
+  `name` varchar(255) CHARACTER SET utf8mb4 NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `reminder_subscriptions_link_id_email_unique` (`link_id`, `email`),
+  CONSTRAINT `reminder_subscriptions_link_id_links_id_foreign` FOREIGN KEY (`link_id`) REFERENCES `links` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE `sent_reminders` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `link_id` int(10) unsigned NOT NULL,
+  `offset_minutes` int NOT NULL,
+  `email` varchar(255) NOT NULL,
+  `sent_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `sent_reminders_link_id_offset_minutes_email_unique` (`link_id`, `offset_minutes`, `email`),
+  CONSTRAINT `sent_reminders_link_id_links_id_foreign` FOREIGN KEY (`link_id`) REFERENCES `links` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
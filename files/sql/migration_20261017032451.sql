@@ -0,0 +1,29 @@
+CREATE TABLE `webhooks` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int(10) unsigned NOT NULL,
+  `url` varchar(2048) NOT NULL,
+  `secret` varchar(255) NOT NULL,
+  `events` varchar(255) NOT NULL,
+  `active` tinyint(1) NOT NULL DEFAULT 1,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `webhooks_user_id_users_id_foreign` (`user_id`),
+  CONSTRAINT `webhooks_user_id_users_id_foreign` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
+
+CREATE TABLE `webhook_deliveries` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `webhook_id` int(10) unsigned NOT NULL,
+  `event` varchar(64) NOT NULL,
+  `payload` mediumtext CHARACTER SET utf8mb4 NOT NULL,
+  `status` varchar(16) NOT NULL,
+  `attempts` int NOT NULL DEFAULT 0,
+  `response_status` int NOT NULL DEFAULT 0,
+  `last_error` text NOT NULL,
+  `next_attempt_at` datetime DEFAULT NULL,
+  `delivered_at` datetime DEFAULT NULL,
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `webhook_deliveries_status_next_attempt_at_index` (`status`, `next_attempt_at`),
+  CONSTRAINT `webhook_deliveries_webhook_id_webhooks_id_foreign` FOREIGN KEY (`webhook_id`) REFERENCES `webhooks` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,11 @@
+CREATE TABLE `analytics_events` (
+  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+  `link_id` int(10) unsigned NOT NULL,
+  `type` varchar(32) NOT NULL,
+  `bytes` bigint NOT NULL DEFAULT 0,
+  `failure_reason` varchar(255) CHARACTER SET utf8mb4 NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL,
+  PRIMARY KEY (`id`),
+  KEY `analytics_events_link_id_created_at_index` (`link_id`, `created_at`),
+  CONSTRAINT `analytics_events_link_id_links_id_foreign` FOREIGN KEY (`link_id`) REFERENCES `links` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
@@ -0,0 +1,17 @@
+CREATE TABLE `sessions` (
+  `id` int(10) unsigned NOT NULL AUTO_INCREMENT,
+  `user_id` int(10) unsigned NOT NULL,
+  `refresh_token_hash` char(64) NOT NULL,
+  `previous_refresh_token_hash` char(64) NOT NULL DEFAULT '',
+  `user_agent` varchar(512) CHARACTER SET utf8mb4 NOT NULL DEFAULT '',
+  `ip` varchar(45) NOT NULL DEFAULT '',
+  `created_at` datetime NOT NULL,
+  `last_used_at` datetime NOT NULL,
+  `expires_at` datetime NOT NULL,
+  `revoked_at` datetime DEFAULT NULL,
+  PRIMARY KEY (`id`),
+  UNIQUE KEY `sessions_refresh_token_hash_unique` (`refresh_token_hash`),
+  KEY `sessions_previous_refresh_token_hash_index` (`previous_refresh_token_hash`),
+  KEY `sessions_user_id_users_id_foreign` (`user_id`),
+  CONSTRAINT `sessions_user_id_users_id_foreign` FOREIGN KEY (`user_id`) REFERENCES `users` (`id`) ON DELETE CASCADE ON UPDATE CASCADE
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;
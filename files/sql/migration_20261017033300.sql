@@ -0,0 +1,2 @@
+ALTER TABLE `users`
+ADD `token_version` int(10) unsigned NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+ALTER TABLE `users`
+ADD `email_verified_at` datetime NULL,
+ADD `email_verification_token` varchar(255) NULL,
+ADD `email_verification_token_expiry` datetime NULL;
+
+-- the accounts registered before the verification are trusted
+UPDATE `users` SET `email_verified_at` = NOW();
@@ -0,0 +1,2 @@
+ALTER TABLE `users`
+ADD `storage_provider_authorization_nonce` varchar(255) NULL;
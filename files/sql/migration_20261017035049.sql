@@ -0,0 +1,5 @@
+-- the reminders are only sent to the confirmed subscriptions
+ALTER TABLE `reminder_subscriptions`
+ADD `confirmation_token` varchar(255) NULL,
+ADD `confirmed_at` datetime NULL,
+ADD UNIQUE KEY `reminder_subscriptions_confirmation_token_unique` (`confirmation_token`);
@@ -0,0 +1,3 @@
+-- the confirmation email is resent at most once per interval
+ALTER TABLE `reminder_subscriptions`
+ADD `confirmation_sent_at` datetime NULL;
@@ -0,0 +1,80 @@
+package attemptstore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// sweepInterval is how often the expired attempts are removed
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	count         int
+	lastAttemptAt time.Time
+	expiresAt     time.Time
+}
+
+type memoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryEntry
+	lastSweep time.Time
+}
+
+// NewMemory returns attempt store which keeps the attempts in the process memory,
+// the attempts are not shared between server instances
+func NewMemory() domain.PasswordAttemptStore {
+	return &memoryStore{
+		entries:   make(map[string]memoryEntry),
+		lastSweep: time.Now(),
+	}
+}
+
+// Increment adds delta to the attempts under the lock, the expired attempts count as zero
+func (s *memoryStore) Increment(key string, delta int, now time.Time, ttl time.Duration) (domain.PasswordAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.entries[key]
+	if !ok || !now.Before(entry.expiresAt) {
+		entry = memoryEntry{}
+	}
+
+	a := domain.PasswordAttempts{
+		Count:             entry.count + delta,
+		PreviousAttemptAt: entry.lastAttemptAt,
+	}
+
+	if a.Count <= 0 {
+		delete(s.entries, key)
+		a.Count = 0
+	} else {
+		entry.count = a.Count
+		if delta > 0 {
+			entry.lastAttemptAt = now
+			entry.expiresAt = now.Add(ttl)
+		}
+		s.entries[key] = entry
+	}
+
+	if now.Sub(s.lastSweep) >= sweepInterval {
+		for k, entry := range s.entries {
+			if !now.Before(entry.expiresAt) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+
+	return a, nil
+}
+
+// Delete removes the attempts
+func (s *memoryStore) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
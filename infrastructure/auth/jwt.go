@@ -15,8 +15,10 @@ import (
 var (
 	// A private key for context that only this package can access. This is important
 	// to prevent collisions between different context uses
-	userCtxKey      = &contextKey{"user"}
-	errInvalidToken = errors.New("jwt: invalid token")
+	userCtxKey        = &contextKey{"user"}
+	sessionCtxKey     = &contextKey{"session"}
+	accessTokenCtxKey = &contextKey{"accessToken"}
+	errInvalidToken   = errors.New("jwt: invalid token")
 )
 
 type contextKey struct {
@@ -25,31 +27,45 @@ type contextKey struct {
 
 // JWTAuthenticator struct
 type JWTAuthenticator struct {
-	key      []byte
-	duration time.Duration
-	algo     string
-	userRepo domain.UserRepository
+	keys           *KeySet
+	duration       time.Duration
+	userRepo       domain.UserRepository
+	sessionRepo    domain.SessionRepository
+	accessTokenSvc domain.AccessTokenService
 }
 
 // NewJWT func
-func NewJWT(secret string, duration time.Duration, algo string, userRepo domain.UserRepository) *JWTAuthenticator {
+func NewJWT(
+	keys *KeySet,
+	duration time.Duration,
+	userRepo domain.UserRepository,
+	sessionRepo domain.SessionRepository,
+	accessTokenSvc domain.AccessTokenService,
+) *JWTAuthenticator {
 	return &JWTAuthenticator{
-		key:      []byte(secret),
-		duration: duration,
-		algo:     algo,
-		userRepo: userRepo,
+		keys:           keys,
+		duration:       duration,
+		userRepo:       userRepo,
+		sessionRepo:    sessionRepo,
+		accessTokenSvc: accessTokenSvc,
 	}
 }
 
 // Authenticate func
-func (j *JWTAuthenticator) Authenticate(u *domain.User) (*domain.UserCredentials, error) {
+func (j *JWTAuthenticator) Authenticate(u *domain.User, sessionID uint) (*domain.UserCredentials, error) {
 	expiry := time.Now().Add(j.duration)
-	token := jwt.NewWithClaims(jwt.GetSigningMethod(j.algo), jwt.MapClaims{
+	key := j.keys.active
+	token := jwt.NewWithClaims(key.method, jwt.MapClaims{
 		"user_id": u.ID,
+		"sid":     sessionID,
+		"ver":     u.TokenVersion,
 		"exp":     expiry.Unix(),
 	})
+	if key.ID != "" {
+		token.Header["kid"] = key.ID
+	}
 
-	tokenS, err := token.SignedString(j.key)
+	tokenS, err := token.SignedString(key.signKey)
 	if err != nil {
 		return nil, err
 	}
@@ -59,31 +75,62 @@ func (j *JWTAuthenticator) Authenticate(u *domain.User) (*domain.UserCredentials
 	}, nil
 }
 
-func (j *JWTAuthenticator) validateAndGetUserID(token string) (uint, error) {
+// JWKS returns the public keys verifying the tokens
+func (j *JWTAuthenticator) JWKS() JWKS {
+	return j.keys.JWKS()
+}
+
+// tokenClaims holds the claims of a valid token
+type tokenClaims struct {
+	userID       uint
+	sessionID    uint
+	tokenVersion uint
+}
+
+// validateAndGetUserID returns the user ID along with the other claims carried by the token
+func (j *JWTAuthenticator) validateAndGetUserID(token string) (*tokenClaims, error) {
 	payloadI, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
-		if jwt.GetSigningMethod(j.algo) != token.Method {
+		// the tokens signed with the retired keys are still valid until they expire
+		kid, _ := token.Header["kid"].(string)
+		key := j.keys.find(kid)
+		if key == nil || key.method != token.Method {
 			return nil, errInvalidToken
 		}
 
-		return j.key, nil
+		return key.verifyKey, nil
 	})
 
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	if !payloadI.Valid {
-		return 0, errInvalidToken
+		return nil, errInvalidToken
 	}
 
 	claims := payloadI.Claims.(jwt.MapClaims)
 
 	userID, ok := claims["user_id"].(float64)
 	if !ok {
-		return 0, errInvalidToken
+		return nil, errInvalidToken
+	}
+
+	sessionID, ok := claims["sid"].(float64)
+	if !ok {
+		return nil, errInvalidToken
 	}
 
-	return uint(userID), nil
+	// the tokens minted before the token version was introduced are rejected as well
+	tokenVersion, ok := claims["ver"].(float64)
+	if !ok {
+		return nil, errInvalidToken
+	}
+
+	return &tokenClaims{
+		userID:       uint(userID),
+		sessionID:    uint(sessionID),
+		tokenVersion: uint(tokenVersion),
+	}, nil
 }
 
 func writeGqlError(w http.ResponseWriter, msg string) {
@@ -122,22 +169,24 @@ func (j *JWTAuthenticator) Middleware() func(http.Handler) http.Handler {
 				return
 			}
 
-			userID, err := j.validateAndGetUserID(authToken)
-			if err != nil {
+			// personal access tokens are accepted alongside the JWTs
+			var ctx context.Context
+			var err error
+			if strings.HasPrefix(authToken, domain.AccessTokenPrefix) {
+				ctx, err = j.authenticateAccessToken(r.Context(), authToken)
+			} else {
+				ctx, err = j.authenticateJWT(r.Context(), authToken)
+			}
+
+			if err == errInvalidToken {
 				writeGqlError(w, "Invalid or expired token")
 				return
 			}
-
-			// get the user from the database
-			user, err := j.userRepo.FindByID(userID)
 			if err != nil {
 				writeGqlError(w, "Server Error")
 				return
 			}
 
-			// put it in context
-			ctx := context.WithValue(r.Context(), userCtxKey, user)
-
 			// and call the next with our new context
 			r = r.WithContext(ctx)
 			next.ServeHTTP(w, r)
@@ -145,8 +194,76 @@ func (j *JWTAuthenticator) Middleware() func(http.Handler) http.Handler {
 	}
 }
 
+// authenticateJWT puts the user and the session of the token in the context
+func (j *JWTAuthenticator) authenticateJWT(ctx context.Context, token string) (context.Context, error) {
+	claims, err := j.validateAndGetUserID(token)
+	if err != nil {
+		return nil, errInvalidToken
+	}
+
+	// reject the tokens of the sessions which have been logged out
+	session, err := j.sessionRepo.FindByID(claims.sessionID)
+	if err == domain.ErrSessionNotFound {
+		return nil, errInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if session.UserID != claims.userID || !session.IsActiveAt(time.Now()) {
+		return nil, errInvalidToken
+	}
+
+	// get the user from the database
+	user, err := j.userRepo.FindByID(claims.userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// the password has been changed after the token was minted
+	if user.TokenVersion != claims.tokenVersion {
+		return nil, errInvalidToken
+	}
+
+	// put it in context
+	ctx = context.WithValue(ctx, userCtxKey, user)
+	return context.WithValue(ctx, sessionCtxKey, session.ID), nil
+}
+
+// authenticateAccessToken puts the user and the personal access token in the context
+func (j *JWTAuthenticator) authenticateAccessToken(ctx context.Context, token string) (context.Context, error) {
+	accessToken, err := j.accessTokenSvc.Authenticate(token)
+	if err == domain.ErrAccessTokenInvalid {
+		return nil, errInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := j.userRepo.FindByID(accessToken.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, userCtxKey, user)
+	return context.WithValue(ctx, accessTokenCtxKey, accessToken), nil
+}
+
 // GetAuthenticatedUser finds the user from the context. REQUIRES Middleware to have run.
 func (j *JWTAuthenticator) GetAuthenticatedUser(ctx context.Context) *domain.User {
 	raw, _ := ctx.Value(userCtxKey).(*domain.User)
 	return raw
 }
+
+// GetAuthenticatedSessionID finds the session ID of the access token from the context. REQUIRES Middleware to have run.
+func (j *JWTAuthenticator) GetAuthenticatedSessionID(ctx context.Context) uint {
+	raw, _ := ctx.Value(sessionCtxKey).(uint)
+	return raw
+}
+
+// GetAuthenticatedAccessToken finds the personal access token from the context, it is nil when
+// the user signs in with the password. REQUIRES Middleware to have run.
+func (j *JWTAuthenticator) GetAuthenticatedAccessToken(ctx context.Context) *domain.AccessToken {
+	raw, _ := ctx.Value(accessTokenCtxKey).(*domain.AccessToken)
+	return raw
+}
@@ -15,7 +15,7 @@ func NewJWTMock() domain.Authenticator {
 }
 
 // Authenticate mock
-func (j *jwtAuthenticatorMock) Authenticate(u *domain.User) (*domain.UserCredentials, error) {
+func (j *jwtAuthenticatorMock) Authenticate(u *domain.User, sessionID uint) (*domain.UserCredentials, error) {
 	t := time.Now().Add(time.Hour)
 	return &domain.UserCredentials{
 		Token: "user_token_"+strconv.Itoa(int(u.ID)),
@@ -0,0 +1,188 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"math/big"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+var (
+	// ErrUnsupportedAlgorithm error
+	ErrUnsupportedAlgorithm = errors.New("jwt: unsupported signing algorithm")
+	// ErrInvalidKey error
+	ErrInvalidKey = errors.New("jwt: invalid key for the signing algorithm")
+	// ErrNoSigningKey error
+	ErrNoSigningKey = errors.New("jwt: the active key has no private key")
+	// ErrDuplicateKeyID error
+	ErrDuplicateKeyID = errors.New("jwt: duplicate key ID")
+)
+
+// Key is a signing or verification key identified by the "kid" header of the tokens
+type Key struct {
+	ID        string
+	method    jwt.SigningMethod
+	signKey   interface{}
+	verifyKey interface{}
+}
+
+// NewHMACKey returns a shared secret key for HS256, HS384 or HS512
+func NewHMACKey(id, algo, secret string) (*Key, error) {
+	method, ok := jwt.GetSigningMethod(algo).(*jwt.SigningMethodHMAC)
+	if !ok {
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	if secret == "" {
+		return nil, ErrInvalidKey
+	}
+
+	return &Key{
+		ID:        id,
+		method:    method,
+		signKey:   []byte(secret),
+		verifyKey: []byte(secret),
+	}, nil
+}
+
+// ParsePEMKey parses the PEM encoded RSA key for RS256, RS384 or RS512, or the ECDSA key for
+// ES256, ES384 or ES512. A private key signs and verifies the tokens, while a public key
+// only verifies them, which is enough for the retired keys
+func ParsePEMKey(id, algo string, pemBytes []byte) (*Key, error) {
+	key := &Key{ID: id}
+
+	switch method := jwt.GetSigningMethod(algo).(type) {
+	case *jwt.SigningMethodRSA:
+		key.method = method
+
+		if privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(pemBytes); err == nil {
+			key.signKey, key.verifyKey = privateKey, &privateKey.PublicKey
+		} else if publicKey, err := jwt.ParseRSAPublicKeyFromPEM(pemBytes); err == nil {
+			key.verifyKey = publicKey
+		} else {
+			return nil, ErrInvalidKey
+		}
+
+	case *jwt.SigningMethodECDSA:
+		key.method = method
+
+		var publicKey *ecdsa.PublicKey
+		if privateKey, err := jwt.ParseECPrivateKeyFromPEM(pemBytes); err == nil {
+			key.signKey, publicKey = privateKey, &privateKey.PublicKey
+		} else if publicKey, err = jwt.ParseECPublicKeyFromPEM(pemBytes); err != nil {
+			return nil, ErrInvalidKey
+		}
+
+		// e.g. ES256 requires the P-256 curve
+		if publicKey.Curve.Params().BitSize != method.CurveBits {
+			return nil, ErrInvalidKey
+		}
+		key.verifyKey = publicKey
+
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+
+	return key, nil
+}
+
+// KeySet holds the active key signing the new tokens along with the retired keys,
+// which still verify the tokens signed before the rotation
+type KeySet struct {
+	active *Key
+	keys   []*Key
+}
+
+// NewKeySet returns new key set, the active key must have a private key
+func NewKeySet(active *Key, retired ...*Key) (*KeySet, error) {
+	if active == nil || active.signKey == nil {
+		return nil, ErrNoSigningKey
+	}
+
+	keys := append([]*Key{active}, retired...)
+	seen := make(map[string]bool, len(keys))
+	for _, k := range keys {
+		if seen[k.ID] {
+			return nil, ErrDuplicateKeyID
+		}
+		seen[k.ID] = true
+	}
+
+	return &KeySet{
+		active: active,
+		keys:   keys,
+	}, nil
+}
+
+// find returns the key of the ID, or nil when it is unknown
+func (ks *KeySet) find(id string) *Key {
+	for _, k := range ks.keys {
+		if k.ID == id {
+			return k
+		}
+	}
+	return nil
+}
+
+// JWK is a public key in JSON Web Key format (RFC 7517)
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the public keys of the set, the shared secret keys are never published
+func (ks *KeySet) JWKS() JWKS {
+	jwks := JWKS{Keys: []JWK{}}
+	for _, k := range ks.keys {
+		jwk := JWK{
+			Kid: k.ID,
+			Use: "sig",
+			Alg: k.method.Alg(),
+		}
+
+		switch publicKey := k.verifyKey.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = encodeBigInt(publicKey.N, 0)
+			jwk.E = encodeBigInt(big.NewInt(int64(publicKey.E)), 0)
+
+		case *ecdsa.PublicKey:
+			size := (publicKey.Curve.Params().BitSize + 7) / 8
+			jwk.Kty = "EC"
+			jwk.Crv = publicKey.Curve.Params().Name
+			jwk.X = encodeBigInt(publicKey.X, size)
+			jwk.Y = encodeBigInt(publicKey.Y, size)
+
+		default:
+			continue
+		}
+
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// encodeBigInt returns the base64url encoding of the big-endian bytes, left-padded to the size
+func encodeBigInt(n *big.Int, size int) string {
+	b := n.Bytes()
+	if len(b) < size {
+		b = append(make([]byte, size-len(b)), b...)
+	}
+	return base64.RawURLEncoding.EncodeToString(b)
+}
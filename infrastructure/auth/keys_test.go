@@ -0,0 +1,135 @@
+package auth_test
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/infrastructure/auth"
+	"github.com/bccfilkom/drophere-go/infrastructure/database/inmemory"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func generatePEMKeys(t *testing.T) (rsaPrivate, rsaPublic, ecPrivate []byte) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaPublicDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecPrivateDER, err := x509.MarshalECPrivateKey(ecKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rsaPrivate = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)})
+	rsaPublic = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: rsaPublicDER})
+	ecPrivate = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: ecPrivateDER})
+	return
+}
+
+func TestParsePEMKey(t *testing.T) {
+	rsaPrivate, rsaPublic, ecPrivate := generatePEMKeys(t)
+
+	type test struct {
+		algo    string
+		pem     []byte
+		wantErr error
+	}
+
+	tests := []test{
+		{algo: "RS256", pem: rsaPrivate},
+		{algo: "RS256", pem: rsaPublic},
+		{algo: "ES256", pem: ecPrivate},
+		{algo: "ES384", pem: ecPrivate, wantErr: auth.ErrInvalidKey},
+		{algo: "ES256", pem: rsaPrivate, wantErr: auth.ErrInvalidKey},
+		{algo: "RS256", pem: []byte("not a key"), wantErr: auth.ErrInvalidKey},
+		{algo: "HS256", pem: rsaPrivate, wantErr: auth.ErrUnsupportedAlgorithm},
+		{algo: "none", pem: rsaPrivate, wantErr: auth.ErrUnsupportedAlgorithm},
+	}
+
+	for i, tc := range tests {
+		_, err := auth.ParsePEMKey("key", tc.algo, tc.pem)
+		assert.Equal(t, tc.wantErr, err, "test %d", i)
+	}
+
+	publicKey, _ := auth.ParsePEMKey("retired", "RS256", rsaPublic)
+	_, err := auth.NewKeySet(publicKey)
+	assert.Equal(t, auth.ErrNoSigningKey, err)
+
+	privateKey, _ := auth.ParsePEMKey("retired", "RS256", rsaPrivate)
+	_, err = auth.NewKeySet(privateKey, publicKey)
+	assert.Equal(t, auth.ErrDuplicateKeyID, err)
+}
+
+func TestKeyRotation(t *testing.T) {
+	rsaPrivate, rsaPublic, ecPrivate := generatePEMKeys(t)
+
+	memdb := inmemory.New()
+	userRepo := inmemory.NewUserRepository(memdb)
+	sessionRepo := inmemory.NewSessionRepository(memdb)
+
+	u, _ := userRepo.FindByID(1)
+	session, _ := sessionRepo.Create(&domain.Session{UserID: u.ID, ExpiresAt: time.Now().Add(time.Hour)})
+
+	oldKey, _ := auth.ParsePEMKey("2026-04", "RS256", rsaPrivate)
+	oldKeys, _ := auth.NewKeySet(oldKey)
+	creds, err := auth.NewJWT(oldKeys, time.Hour, userRepo, sessionRepo, nil).Authenticate(u, session.ID)
+	assert.Nil(t, err)
+
+	newKey, _ := auth.ParsePEMKey("2026-10", "ES256", ecPrivate)
+	retiredKey, _ := auth.ParsePEMKey("2026-04", "RS256", rsaPublic)
+	hmacKey, _ := auth.NewHMACKey("legacy", "HS256", "secret")
+	rotatedKeys, _ := auth.NewKeySet(newKey, retiredKey, hmacKey)
+	droppedKeys, _ := auth.NewKeySet(newKey)
+
+	authenticate := func(keys *auth.KeySet, token string) *domain.User {
+		authenticator := auth.NewJWT(keys, time.Hour, userRepo, sessionRepo, nil)
+
+		var user *domain.User
+		handler := authenticator.Middleware()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user = authenticator.GetAuthenticatedUser(r.Context())
+		}))
+
+		r := httptest.NewRequest(http.MethodPost, "/query", nil)
+		r.Header.Set("Authorization", "Bearer "+token)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+		return user
+	}
+
+	// the tokens of the retired key are still accepted
+	assert.NotNil(t, authenticate(rotatedKeys, creds.Token))
+	assert.Nil(t, authenticate(droppedKeys, creds.Token))
+
+	creds, err = auth.NewJWT(rotatedKeys, time.Hour, userRepo, sessionRepo, nil).Authenticate(u, session.ID)
+	assert.Nil(t, err)
+	assert.NotNil(t, authenticate(droppedKeys, creds.Token))
+	assert.Nil(t, authenticate(oldKeys, creds.Token))
+
+	// the shared secret is never published
+	jwks := rotatedKeys.JWKS()
+	if assert.Len(t, jwks.Keys, 2) {
+		assert.Equal(t, auth.JWK{Kty: "EC", Kid: "2026-10", Use: "sig", Alg: "ES256", Crv: "P-256", X: jwks.Keys[0].X, Y: jwks.Keys[0].Y}, jwks.Keys[0])
+		assert.Len(t, jwks.Keys[0].X, 43)
+		assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+		assert.Equal(t, "2026-04", jwks.Keys[1].Kid)
+		assert.Equal(t, "RS256", jwks.Keys[1].Alg)
+		assert.Equal(t, "AQAB", jwks.Keys[1].E)
+	}
+}
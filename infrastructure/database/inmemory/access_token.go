@@ -0,0 +1,67 @@
+package inmemory
+
+import (
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+type accessTokenRepository struct {
+	db *DB
+}
+
+// NewAccessTokenRepository func
+func NewAccessTokenRepository(db *DB) domain.AccessTokenRepository {
+	return &accessTokenRepository{db}
+}
+
+// Create implementation
+func (repo *accessTokenRepository) Create(t *domain.AccessToken) (*domain.AccessToken, error) {
+	repo.db.lastAccessTokenID++
+	t.ID = repo.db.lastAccessTokenID
+	repo.db.accessTokens = append(repo.db.accessTokens, *t)
+	return t, nil
+}
+
+// FindByID implementation
+func (repo *accessTokenRepository) FindByID(id uint) (*domain.AccessToken, error) {
+	for i := range repo.db.accessTokens {
+		if repo.db.accessTokens[i].ID == id {
+			t := repo.db.accessTokens[i]
+			return &t, nil
+		}
+	}
+	return nil, domain.ErrAccessTokenNotFound
+}
+
+// ListByUser implementation
+func (repo *accessTokenRepository) ListByUser(userID uint) ([]domain.AccessToken, error) {
+	tokens := make([]domain.AccessToken, 0)
+	for _, t := range repo.db.accessTokens {
+		if t.UserID == userID {
+			tokens = append(tokens, t)
+		}
+	}
+
+	return tokens, nil
+}
+
+// Update implementation
+func (repo *accessTokenRepository) Update(t *domain.AccessToken) (*domain.AccessToken, error) {
+	for i := range repo.db.accessTokens {
+		if repo.db.accessTokens[i].ID == t.ID {
+			repo.db.accessTokens[i] = *t
+			return t, nil
+		}
+	}
+	return nil, domain.ErrAccessTokenNotFound
+}
+
+// Delete implementation
+func (repo *accessTokenRepository) Delete(t *domain.AccessToken) error {
+	for i := range repo.db.accessTokens {
+		if repo.db.accessTokens[i].ID == t.ID {
+			repo.db.accessTokens = append(repo.db.accessTokens[:i], repo.db.accessTokens[i+1:]...)
+			break
+		}
+	}
+	return nil
+}
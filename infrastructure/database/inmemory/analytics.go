@@ -0,0 +1,90 @@
+package inmemory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+type analyticsRepository struct {
+	db *DB
+}
+
+// NewAnalyticsRepository func
+func NewAnalyticsRepository(db *DB) domain.AnalyticsRepository {
+	return &analyticsRepository{db}
+}
+
+// Create implementation
+func (repo *analyticsRepository) Create(e *domain.AnalyticsEvent) (*domain.AnalyticsEvent, error) {
+	e.ID = uint(len(repo.db.analyticsEvents) + 1)
+	repo.db.analyticsEvents = append(repo.db.analyticsEvents, *e)
+	return e, nil
+}
+
+// listByLinkBetween returns the events of the link from the start of the range until before its end
+func (repo *analyticsRepository) listByLinkBetween(linkID uint, from, to time.Time) []domain.AnalyticsEvent {
+	events := make([]domain.AnalyticsEvent, 0)
+	for _, e := range repo.db.analyticsEvents {
+		if e.LinkID == linkID && !e.CreatedAt.Before(from) && e.CreatedAt.Before(to) {
+			events = append(events, e)
+		}
+	}
+
+	return events
+}
+
+// CountByLinkBetween implementation
+func (repo *analyticsRepository) CountByLinkBetween(linkID uint, from, to time.Time, bucketSize string) ([]domain.AnalyticsEventCount, error) {
+	events := repo.listByLinkBetween(linkID, from, to)
+
+	counts := make([]domain.AnalyticsEventCount, 0)
+	indexes := make(map[domain.AnalyticsEventCount]int)
+	for _, e := range events {
+		start := e.CreatedAt.UTC().Truncate(time.Hour)
+		if bucketSize == domain.LinkStatsBucketDay {
+			start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+		}
+
+		key := domain.AnalyticsEventCount{BucketStart: start, Type: e.Type}
+		i, ok := indexes[key]
+		if !ok {
+			i = len(counts)
+			indexes[key] = i
+			counts = append(counts, key)
+		}
+		counts[i].Count++
+		counts[i].Bytes += e.Bytes
+	}
+
+	return counts, nil
+}
+
+// CountFailureReasonsByLinkBetween implementation
+func (repo *analyticsRepository) CountFailureReasonsByLinkBetween(linkID uint, from, to time.Time, limit int) ([]domain.FailureReasonCount, error) {
+	events := repo.listByLinkBetween(linkID, from, to)
+
+	counts := make(map[string]int)
+	for _, e := range events {
+		if e.Type == domain.AnalyticsEventUploadFailed {
+			counts[e.FailureReason]++
+		}
+	}
+
+	reasons := make([]domain.FailureReasonCount, 0, len(counts))
+	for reason, n := range counts {
+		reasons = append(reasons, domain.FailureReasonCount{Reason: reason, Count: n})
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		if reasons[i].Count != reasons[j].Count {
+			return reasons[i].Count > reasons[j].Count
+		}
+		return reasons[i].Reason < reasons[j].Reason
+	})
+	if len(reasons) > limit {
+		reasons = reasons[:limit]
+	}
+
+	return reasons, nil
+}
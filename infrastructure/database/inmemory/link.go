@@ -1,6 +1,10 @@
 package inmemory
 
-import "github.com/bccfilkom/drophere-go/domain"
+import (
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
 
 type linkRepository struct {
 	db *DB
@@ -53,6 +57,42 @@ func (repo *linkRepository) FindBySlug(slug string) (*domain.Link, error) {
 	return nil, domain.ErrLinkNotFound
 }
 
+// ListByDeadlineBetween implementation
+func (repo *linkRepository) ListByDeadlineBetween(from, to time.Time) ([]domain.Link, error) {
+	links := make([]domain.Link, 0, len(repo.db.links))
+	for _, link := range repo.db.links {
+		if link.Deadline != nil && link.Deadline.After(from) && !link.Deadline.After(to) {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// ListClosingBetween implementation
+func (repo *linkRepository) ListClosingBetween(from, to time.Time) ([]domain.Link, error) {
+	links := make([]domain.Link, 0, len(repo.db.links))
+	for _, link := range repo.db.links {
+		if closesAt := link.ClosesAt(); closesAt != nil && !closesAt.Before(from) && closesAt.Before(to) {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
+// ListByNotificationMode implementation
+func (repo *linkRepository) ListByNotificationMode(mode string) ([]domain.Link, error) {
+	links := make([]domain.Link, 0, len(repo.db.links))
+	for _, link := range repo.db.links {
+		if link.NotificationMode == mode {
+			links = append(links, link)
+		}
+	}
+
+	return links, nil
+}
+
 // ListByUser implementation
 func (repo *linkRepository) ListByUser(userID uint) ([]domain.Link, error) {
 	links := make([]domain.Link, 0, len(repo.db.links))
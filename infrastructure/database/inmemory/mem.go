@@ -1,6 +1,7 @@
 package inmemory
 
 import (
+	"sync"
 	"time"
 
 	"github.com/bccfilkom/drophere-go/domain"
@@ -11,6 +12,26 @@ type DB struct {
 	users            []domain.User
 	links            []domain.Link
 	userStorageCreds []domain.UserStorageCredential
+	submissions      []domain.Submission
+	rosterEntries    []domain.RosterEntry
+	lastRosterID     uint
+	reminderSubs     []domain.ReminderSubscription
+	sentReminders    []domain.SentReminder
+
+	webhooks              []domain.Webhook
+	lastWebhookID         uint
+	webhookDeliveries     []domain.WebhookDelivery
+	lastWebhookDeliveryID uint
+
+	analyticsEvents []domain.AnalyticsEvent
+
+	// sessionsMu guards the sessions, which are refreshed concurrently
+	sessionsMu    sync.Mutex
+	sessions      []domain.Session
+	lastSessionID uint
+
+	accessTokens      []domain.AccessToken
+	lastAccessTokenID uint
 }
 
 // New func
@@ -66,6 +87,37 @@ func (db *DB) populate() {
 			Photo:              "http://my.photo/user1.jpg",
 		},
 	}
+
+	db.submissions = []domain.Submission{
+		{
+			ID:          1,
+			LinkID:      1,
+			FileName:    "report.pdf",
+			StoredPath:  "/drophere/drop-here/report.pdf",
+			Size:        1024,
+			ContentType: "application/pdf",
+			UploaderIP:  "127.0.0.1",
+			ProviderID:  1,
+			Status:      domain.SubmissionStatusSucceeded,
+			CreatedAt:   time.Date(2019, time.July, 14, 10, 0, 0, 0, time.UTC),
+			ReceiptID:   "receipt_1",
+			SHA256:      "b4f56b3d5ac4d2bbbd32ff7e2e2a02d4b8ab4dfa4b7e3d7b53d1e96e8bb7d4b3",
+		},
+		{
+			ID:            2,
+			LinkID:        1,
+			FileName:      "report (revised).pdf",
+			StoredPath:    "/drophere/drop-here/report (revised).pdf",
+			Size:          2048,
+			ContentType:   "application/pdf",
+			UploaderName:  "User 357",
+			UploaderEmail: "user_357@drophere.link",
+			UploaderIP:    "127.0.0.1",
+			ProviderID:    1,
+			Status:        domain.SubmissionStatusSucceeded,
+			CreatedAt:     time.Date(2019, time.July, 15, 10, 0, 0, 0, time.UTC),
+		},
+	}
 }
 
 // FindUserByEmail func
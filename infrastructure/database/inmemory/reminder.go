@@ -0,0 +1,72 @@
+package inmemory
+
+import "github.com/bccfilkom/drophere-go/domain"
+
+type reminderRepository struct {
+	db *DB
+}
+
+// NewReminderRepository func
+func NewReminderRepository(db *DB) domain.ReminderRepository {
+	return &reminderRepository{db}
+}
+
+// CreateSubscription implementation
+func (repo *reminderRepository) CreateSubscription(s *domain.ReminderSubscription) (*domain.ReminderSubscription, error) {
+	s.ID = uint(len(repo.db.reminderSubs) + 1)
+	repo.db.reminderSubs = append(repo.db.reminderSubs, *s)
+	return s, nil
+}
+
+// ListSubscriptionsByLink implementation
+func (repo *reminderRepository) ListSubscriptionsByLink(linkID uint) ([]domain.ReminderSubscription, error) {
+	subscriptions := make([]domain.ReminderSubscription, 0, len(repo.db.reminderSubs))
+	for _, s := range repo.db.reminderSubs {
+		if s.LinkID == linkID {
+			subscriptions = append(subscriptions, s)
+		}
+	}
+
+	return subscriptions, nil
+}
+
+// FindSubscriptionByConfirmationToken implementation
+func (repo *reminderRepository) FindSubscriptionByConfirmationToken(token string) (*domain.ReminderSubscription, error) {
+	for _, s := range repo.db.reminderSubs {
+		if s.ConfirmationToken != nil && *s.ConfirmationToken == token {
+			return &s, nil
+		}
+	}
+
+	return nil, domain.ErrReminderSubscriptionNotFound
+}
+
+// UpdateSubscription implementation
+func (repo *reminderRepository) UpdateSubscription(s *domain.ReminderSubscription) (*domain.ReminderSubscription, error) {
+	for i := range repo.db.reminderSubs {
+		if repo.db.reminderSubs[i].ID == s.ID {
+			repo.db.reminderSubs[i] = *s
+			break
+		}
+	}
+	return s, nil
+}
+
+// CreateSentReminder implementation
+func (repo *reminderRepository) CreateSentReminder(r *domain.SentReminder) (*domain.SentReminder, error) {
+	r.ID = uint(len(repo.db.sentReminders) + 1)
+	repo.db.sentReminders = append(repo.db.sentReminders, *r)
+	return r, nil
+}
+
+// ListSentRemindersByLink implementation
+func (repo *reminderRepository) ListSentRemindersByLink(linkID uint) ([]domain.SentReminder, error) {
+	reminders := make([]domain.SentReminder, 0, len(repo.db.sentReminders))
+	for _, r := range repo.db.sentReminders {
+		if r.LinkID == linkID {
+			reminders = append(reminders, r)
+		}
+	}
+
+	return reminders, nil
+}
@@ -0,0 +1,44 @@
+package inmemory
+
+import "github.com/bccfilkom/drophere-go/domain"
+
+type rosterRepository struct {
+	db *DB
+}
+
+// NewRosterRepository func
+func NewRosterRepository(db *DB) domain.RosterRepository {
+	return &rosterRepository{db}
+}
+
+// ListByLink implementation
+func (repo *rosterRepository) ListByLink(linkID uint) ([]domain.RosterEntry, error) {
+	entries := make([]domain.RosterEntry, 0, len(repo.db.rosterEntries))
+	for _, entry := range repo.db.rosterEntries {
+		if entry.LinkID == linkID {
+			entries = append(entries, entry)
+		}
+	}
+
+	return entries, nil
+}
+
+// ReplaceByLink implementation
+func (repo *rosterRepository) ReplaceByLink(linkID uint, entries []domain.RosterEntry) ([]domain.RosterEntry, error) {
+	kept := make([]domain.RosterEntry, 0, len(repo.db.rosterEntries)+len(entries))
+	for _, entry := range repo.db.rosterEntries {
+		if entry.LinkID != linkID {
+			kept = append(kept, entry)
+		}
+	}
+
+	for i := range entries {
+		repo.db.lastRosterID++
+		entries[i].ID = repo.db.lastRosterID
+		entries[i].LinkID = linkID
+		kept = append(kept, entries[i])
+	}
+	repo.db.rosterEntries = kept
+
+	return entries, nil
+}
@@ -0,0 +1,119 @@
+package inmemory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+type sessionRepository struct {
+	db *DB
+}
+
+// NewSessionRepository func
+func NewSessionRepository(db *DB) domain.SessionRepository {
+	return &sessionRepository{db}
+}
+
+// Create implementation
+func (repo *sessionRepository) Create(s *domain.Session) (*domain.Session, error) {
+	repo.db.sessionsMu.Lock()
+	defer repo.db.sessionsMu.Unlock()
+
+	repo.db.lastSessionID++
+	s.ID = repo.db.lastSessionID
+	repo.db.sessions = append(repo.db.sessions, *s)
+	return s, nil
+}
+
+// FindByID implementation
+func (repo *sessionRepository) FindByID(id uint) (*domain.Session, error) {
+	repo.db.sessionsMu.Lock()
+	defer repo.db.sessionsMu.Unlock()
+
+	for i := range repo.db.sessions {
+		if repo.db.sessions[i].ID == id {
+			s := repo.db.sessions[i]
+			return &s, nil
+		}
+	}
+	return nil, domain.ErrSessionNotFound
+}
+
+// FindByRefreshTokenHash implementation
+func (repo *sessionRepository) FindByRefreshTokenHash(hash string) (*domain.Session, error) {
+	repo.db.sessionsMu.Lock()
+	defer repo.db.sessionsMu.Unlock()
+
+	for i := range repo.db.sessions {
+		if repo.db.sessions[i].RefreshTokenHash == hash || repo.db.sessions[i].PreviousRefreshTokenHash == hash {
+			s := repo.db.sessions[i]
+			return &s, nil
+		}
+	}
+	return nil, domain.ErrSessionNotFound
+}
+
+// Update implementation
+func (repo *sessionRepository) Update(s *domain.Session) (*domain.Session, error) {
+	repo.db.sessionsMu.Lock()
+	defer repo.db.sessionsMu.Unlock()
+
+	for i := range repo.db.sessions {
+		if repo.db.sessions[i].ID == s.ID {
+			repo.db.sessions[i] = *s
+			return s, nil
+		}
+	}
+	return nil, domain.ErrSessionNotFound
+}
+
+// Rotate implementation
+func (repo *sessionRepository) Rotate(s *domain.Session, previousHash string) (bool, error) {
+	repo.db.sessionsMu.Lock()
+	defer repo.db.sessionsMu.Unlock()
+
+	for i := range repo.db.sessions {
+		if repo.db.sessions[i].ID == s.ID {
+			if repo.db.sessions[i].RevokedAt != nil || repo.db.sessions[i].RefreshTokenHash != previousHash {
+				return false, nil
+			}
+			repo.db.sessions[i] = *s
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ListActiveByUser implementation
+func (repo *sessionRepository) ListActiveByUser(userID uint, at time.Time) ([]domain.Session, error) {
+	repo.db.sessionsMu.Lock()
+	defer repo.db.sessionsMu.Unlock()
+
+	sessions := make([]domain.Session, 0)
+	for _, s := range repo.db.sessions {
+		if s.UserID == userID && s.IsActiveAt(at) {
+			sessions = append(sessions, s)
+		}
+	}
+
+	sort.SliceStable(sessions, func(i, j int) bool {
+		return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+	})
+
+	return sessions, nil
+}
+
+// RevokeAllByUser implementation
+func (repo *sessionRepository) RevokeAllByUser(userID uint, at time.Time) error {
+	repo.db.sessionsMu.Lock()
+	defer repo.db.sessionsMu.Unlock()
+
+	for i := range repo.db.sessions {
+		if repo.db.sessions[i].UserID == userID && repo.db.sessions[i].RevokedAt == nil {
+			repo.db.sessions[i].RevokedAt = &at
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package inmemory
+
+import "github.com/bccfilkom/drophere-go/domain"
+
+type submissionRepository struct {
+	db *DB
+}
+
+// NewSubmissionRepository func
+func NewSubmissionRepository(db *DB) domain.SubmissionRepository {
+	return &submissionRepository{db}
+}
+
+// Create implementation
+func (repo *submissionRepository) Create(s *domain.Submission) (*domain.Submission, error) {
+	s.ID = uint(len(repo.db.submissions) + 1)
+	repo.db.submissions = append(repo.db.submissions, *s)
+	return s, nil
+}
+
+// FindByID implementation
+func (repo *submissionRepository) FindByID(id uint) (*domain.Submission, error) {
+	for i := range repo.db.submissions {
+		if repo.db.submissions[i].ID == id {
+			return &repo.db.submissions[i], nil
+		}
+	}
+
+	return nil, domain.ErrSubmissionNotFound
+}
+
+// FindByReceiptID implementation
+func (repo *submissionRepository) FindByReceiptID(receiptID string) (*domain.Submission, error) {
+	for i := range repo.db.submissions {
+		if repo.db.submissions[i].ReceiptID == receiptID {
+			return &repo.db.submissions[i], nil
+		}
+	}
+
+	return nil, domain.ErrSubmissionNotFound
+}
+
+// ListByLink implementation, the newest submission comes first
+func (repo *submissionRepository) ListByLink(linkID uint) ([]domain.Submission, error) {
+	submissions := make([]domain.Submission, 0, len(repo.db.submissions))
+	for i := len(repo.db.submissions) - 1; i >= 0; i-- {
+		if repo.db.submissions[i].LinkID == linkID {
+			submissions = append(submissions, repo.db.submissions[i])
+		}
+	}
+
+	return submissions, nil
+}
+
+// ListUnnotifiedByLink implementation, the oldest submission comes first
+func (repo *submissionRepository) ListUnnotifiedByLink(linkID uint) ([]domain.Submission, error) {
+	submissions := make([]domain.Submission, 0, len(repo.db.submissions))
+	for _, s := range repo.db.submissions {
+		if s.LinkID == linkID && s.NotifiedAt == nil {
+			submissions = append(submissions, s)
+		}
+	}
+
+	return submissions, nil
+}
+
+// Update implementation
+func (repo *submissionRepository) Update(s *domain.Submission) (*domain.Submission, error) {
+	for i := range repo.db.submissions {
+		if repo.db.submissions[i].ID == s.ID {
+			repo.db.submissions[i] = *s
+			return s, nil
+		}
+	}
+
+	return nil, domain.ErrSubmissionNotFound
+}
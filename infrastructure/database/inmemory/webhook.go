@@ -0,0 +1,128 @@
+package inmemory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+type webhookRepository struct {
+	db *DB
+}
+
+// NewWebhookRepository func
+func NewWebhookRepository(db *DB) domain.WebhookRepository {
+	return &webhookRepository{db}
+}
+
+// Create implementation
+func (repo *webhookRepository) Create(w *domain.Webhook) (*domain.Webhook, error) {
+	repo.db.lastWebhookID++
+	w.ID = repo.db.lastWebhookID
+	repo.db.webhooks = append(repo.db.webhooks, *w)
+	return w, nil
+}
+
+// FindByID implementation
+func (repo *webhookRepository) FindByID(id uint) (*domain.Webhook, error) {
+	for i := range repo.db.webhooks {
+		if repo.db.webhooks[i].ID == id {
+			return &repo.db.webhooks[i], nil
+		}
+	}
+	return nil, domain.ErrWebhookNotFound
+}
+
+// ListByUser implementation
+func (repo *webhookRepository) ListByUser(userID uint) ([]domain.Webhook, error) {
+	webhooks := make([]domain.Webhook, 0, len(repo.db.webhooks))
+	for _, w := range repo.db.webhooks {
+		if w.UserID == userID {
+			webhooks = append(webhooks, w)
+		}
+	}
+
+	return webhooks, nil
+}
+
+// Update implementation
+func (repo *webhookRepository) Update(w *domain.Webhook) (*domain.Webhook, error) {
+	for i := range repo.db.webhooks {
+		if repo.db.webhooks[i].ID == w.ID {
+			repo.db.webhooks[i] = *w
+			return w, nil
+		}
+	}
+	return nil, domain.ErrWebhookNotFound
+}
+
+// Delete implementation
+func (repo *webhookRepository) Delete(w *domain.Webhook) error {
+	for i := range repo.db.webhooks {
+		if repo.db.webhooks[i].ID == w.ID {
+			repo.db.webhooks = append(repo.db.webhooks[:i], repo.db.webhooks[i+1:]...)
+			break
+		}
+	}
+
+	deliveries := make([]domain.WebhookDelivery, 0, len(repo.db.webhookDeliveries))
+	for _, d := range repo.db.webhookDeliveries {
+		if d.WebhookID != w.ID {
+			deliveries = append(deliveries, d)
+		}
+	}
+	repo.db.webhookDeliveries = deliveries
+
+	return nil
+}
+
+// CreateDelivery implementation
+func (repo *webhookRepository) CreateDelivery(d *domain.WebhookDelivery) (*domain.WebhookDelivery, error) {
+	repo.db.lastWebhookDeliveryID++
+	d.ID = repo.db.lastWebhookDeliveryID
+	repo.db.webhookDeliveries = append(repo.db.webhookDeliveries, *d)
+	return d, nil
+}
+
+// UpdateDelivery implementation
+func (repo *webhookRepository) UpdateDelivery(d *domain.WebhookDelivery) (*domain.WebhookDelivery, error) {
+	for i := range repo.db.webhookDeliveries {
+		if repo.db.webhookDeliveries[i].ID == d.ID {
+			repo.db.webhookDeliveries[i] = *d
+			break
+		}
+	}
+	return d, nil
+}
+
+// ListDueDeliveries implementation
+func (repo *webhookRepository) ListDueDeliveries(now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for _, d := range repo.db.webhookDeliveries {
+		if d.Status == domain.WebhookDeliveryPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	sort.SliceStable(deliveries, func(i, j int) bool {
+		return deliveries[i].NextAttemptAt.Before(*deliveries[j].NextAttemptAt)
+	})
+	if len(deliveries) > limit {
+		deliveries = deliveries[:limit]
+	}
+
+	return deliveries, nil
+}
+
+// ListDeliveriesByWebhook implementation
+func (repo *webhookRepository) ListDeliveriesByWebhook(webhookID uint, limit int) ([]domain.WebhookDelivery, error) {
+	deliveries := make([]domain.WebhookDelivery, 0)
+	for i := len(repo.db.webhookDeliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		if repo.db.webhookDeliveries[i].WebhookID == webhookID {
+			deliveries = append(deliveries, repo.db.webhookDeliveries[i])
+		}
+	}
+
+	return deliveries, nil
+}
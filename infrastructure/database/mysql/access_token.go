@@ -0,0 +1,62 @@
+package mysql
+
+import (
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/jinzhu/gorm"
+)
+
+type accessTokenRepository struct {
+	db *gorm.DB
+}
+
+// NewAccessTokenRepository func
+func NewAccessTokenRepository(db *gorm.DB) domain.AccessTokenRepository {
+	return &accessTokenRepository{db}
+}
+
+// Create implementation
+func (repo *accessTokenRepository) Create(t *domain.AccessToken) (*domain.AccessToken, error) {
+	if err := repo.db.Create(t).Error; err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// FindByID implementation
+func (repo *accessTokenRepository) FindByID(id uint) (*domain.AccessToken, error) {
+	t := domain.AccessToken{}
+	if q := repo.db.Find(&t, id); q.RecordNotFound() {
+		return nil, domain.ErrAccessTokenNotFound
+	} else if q.Error != nil {
+		return nil, q.Error
+	}
+
+	return &t, nil
+}
+
+// ListByUser implementation
+func (repo *accessTokenRepository) ListByUser(userID uint) ([]domain.AccessToken, error) {
+	var tokens []domain.AccessToken
+	if err := repo.db.
+		Where("`user_id` = ? ", userID).
+		Order("`id` ASC").
+		Find(&tokens).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+// Update implementation
+func (repo *accessTokenRepository) Update(t *domain.AccessToken) (*domain.AccessToken, error) {
+	if err := repo.db.Save(t).Error; err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Delete implementation
+func (repo *accessTokenRepository) Delete(t *domain.AccessToken) error {
+	return repo.db.Delete(t).Error
+}
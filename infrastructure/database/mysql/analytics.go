@@ -0,0 +1,67 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/jinzhu/gorm"
+)
+
+type analyticsRepository struct {
+	db *gorm.DB
+}
+
+// NewAnalyticsRepository func
+func NewAnalyticsRepository(db *gorm.DB) domain.AnalyticsRepository {
+	return &analyticsRepository{db}
+}
+
+// Create implementation
+func (repo *analyticsRepository) Create(e *domain.AnalyticsEvent) (*domain.AnalyticsEvent, error) {
+	if err := repo.db.Create(e).Error; err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// CountByLinkBetween implementation
+func (repo *analyticsRepository) CountByLinkBetween(linkID uint, from, to time.Time, bucketSize string) ([]domain.AnalyticsEventCount, error) {
+	bucketStart := "TIMESTAMP(DATE(`created_at`), MAKETIME(HOUR(`created_at`), 0, 0))"
+	if bucketSize == domain.LinkStatsBucketDay {
+		bucketStart = "TIMESTAMP(DATE(`created_at`))"
+	}
+
+	var counts []domain.AnalyticsEventCount
+	if err := repo.db.
+		Model(&domain.AnalyticsEvent{}).
+		Select(bucketStart+" AS `bucket_start`, `type`, COUNT(*) AS `count`, COALESCE(SUM(`bytes`), 0) AS `bytes`").
+		Where("`link_id` = ? AND `created_at` >= ? AND `created_at` < ? ", linkID, from, to).
+		Group("`bucket_start`, `type`").
+		Scan(&counts).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// CountFailureReasonsByLinkBetween implementation
+func (repo *analyticsRepository) CountFailureReasonsByLinkBetween(linkID uint, from, to time.Time, limit int) ([]domain.FailureReasonCount, error) {
+	var reasons []domain.FailureReasonCount
+	if err := repo.db.
+		Model(&domain.AnalyticsEvent{}).
+		Select("`failure_reason` AS `reason`, COUNT(*) AS `count`").
+		Where(
+			"`link_id` = ? AND `type` = ? AND `created_at` >= ? AND `created_at` < ? ",
+			linkID, domain.AnalyticsEventUploadFailed, from, to,
+		).
+		Group("`failure_reason`").
+		Order("`count` DESC, `reason` ASC").
+		Limit(limit).
+		Scan(&reasons).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return reasons, nil
+}
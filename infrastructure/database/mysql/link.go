@@ -1,6 +1,8 @@
 package mysql
 
 import (
+	"time"
+
 	"github.com/bccfilkom/drophere-go/domain"
 	"github.com/jinzhu/gorm"
 )
@@ -58,6 +60,53 @@ func (repo *linkRepository) FindBySlug(slug string) (*domain.Link, error) {
 	return &l, nil
 }
 
+// ListByDeadlineBetween implementation
+func (repo *linkRepository) ListByDeadlineBetween(from, to time.Time) ([]domain.Link, error) {
+	var links []domain.Link
+	if err := repo.db.
+		Where("`deadline` > ? AND `deadline` <= ? ", from, to).
+		Preload("User").
+		Find(&links).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// ListClosingBetween implementation
+func (repo *linkRepository) ListClosingBetween(from, to time.Time) ([]domain.Link, error) {
+	closesAt := "CASE WHEN `late_policy` = ? THEN DATE_ADD(`deadline`, INTERVAL `grace_period_minutes` MINUTE) ELSE `deadline` END"
+
+	var links []domain.Link
+	if err := repo.db.
+		Where(
+			"`deadline` IS NOT NULL AND `late_policy` <> ? AND "+closesAt+" >= ? AND "+closesAt+" < ? ",
+			domain.LatePolicyAccept, domain.LatePolicyGracePeriod, from, domain.LatePolicyGracePeriod, to,
+		).
+		Preload("User").
+		Find(&links).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// ListByNotificationMode implementation
+func (repo *linkRepository) ListByNotificationMode(mode string) ([]domain.Link, error) {
+	var links []domain.Link
+	if err := repo.db.
+		Where("`notification_mode` = ? ", mode).
+		Preload("User").
+		Find(&links).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
 // ListByUser implementation
 func (repo *linkRepository) ListByUser(userID uint) ([]domain.Link, error) {
 	var links []domain.Link
@@ -0,0 +1,77 @@
+package mysql
+
+import (
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/jinzhu/gorm"
+)
+
+type reminderRepository struct {
+	db *gorm.DB
+}
+
+// NewReminderRepository func
+func NewReminderRepository(db *gorm.DB) domain.ReminderRepository {
+	return &reminderRepository{db}
+}
+
+// CreateSubscription implementation
+func (repo *reminderRepository) CreateSubscription(s *domain.ReminderSubscription) (*domain.ReminderSubscription, error) {
+	if err := repo.db.Create(s).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// ListSubscriptionsByLink implementation
+func (repo *reminderRepository) ListSubscriptionsByLink(linkID uint) ([]domain.ReminderSubscription, error) {
+	var subscriptions []domain.ReminderSubscription
+	if err := repo.db.
+		Where("`link_id` = ? ", linkID).
+		Find(&subscriptions).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return subscriptions, nil
+}
+
+// FindSubscriptionByConfirmationToken implementation
+func (repo *reminderRepository) FindSubscriptionByConfirmationToken(token string) (*domain.ReminderSubscription, error) {
+	s := domain.ReminderSubscription{}
+	if q := repo.db.Where("`confirmation_token` = ? ", token).First(&s); q.RecordNotFound() {
+		return nil, domain.ErrReminderSubscriptionNotFound
+	} else if q.Error != nil {
+		return nil, q.Error
+	}
+
+	return &s, nil
+}
+
+// UpdateSubscription implementation
+func (repo *reminderRepository) UpdateSubscription(s *domain.ReminderSubscription) (*domain.ReminderSubscription, error) {
+	if err := repo.db.Save(s).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// CreateSentReminder implementation
+func (repo *reminderRepository) CreateSentReminder(r *domain.SentReminder) (*domain.SentReminder, error) {
+	if err := repo.db.Create(r).Error; err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// ListSentRemindersByLink implementation
+func (repo *reminderRepository) ListSentRemindersByLink(linkID uint) ([]domain.SentReminder, error) {
+	var reminders []domain.SentReminder
+	if err := repo.db.
+		Where("`link_id` = ? ", linkID).
+		Find(&reminders).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return reminders, nil
+}
@@ -0,0 +1,59 @@
+package mysql
+
+import (
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/jinzhu/gorm"
+)
+
+type rosterRepository struct {
+	db *gorm.DB
+}
+
+// NewRosterRepository func
+func NewRosterRepository(db *gorm.DB) domain.RosterRepository {
+	return &rosterRepository{db}
+}
+
+// ListByLink implementation
+func (repo *rosterRepository) ListByLink(linkID uint) ([]domain.RosterEntry, error) {
+	var entries []domain.RosterEntry
+	if err := repo.db.
+		Where("`link_id` = ? ", linkID).
+		Order("`id` ASC").
+		Find(&entries).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// ReplaceByLink implementation
+func (repo *rosterRepository) ReplaceByLink(linkID uint, entries []domain.RosterEntry) ([]domain.RosterEntry, error) {
+	tx := repo.db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	if err := tx.
+		Where("`link_id` = ? ", linkID).
+		Delete(domain.RosterEntry{}).
+		Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	for i := range entries {
+		entries[i].LinkID = linkID
+		if err := tx.Create(&entries[i]).Error; err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
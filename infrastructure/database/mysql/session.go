@@ -0,0 +1,102 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/jinzhu/gorm"
+)
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+// NewSessionRepository func
+func NewSessionRepository(db *gorm.DB) domain.SessionRepository {
+	return &sessionRepository{db}
+}
+
+// Create implementation
+func (repo *sessionRepository) Create(s *domain.Session) (*domain.Session, error) {
+	if err := repo.db.Create(s).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// FindByID implementation
+func (repo *sessionRepository) FindByID(id uint) (*domain.Session, error) {
+	s := domain.Session{}
+	if q := repo.db.Find(&s, id); q.RecordNotFound() {
+		return nil, domain.ErrSessionNotFound
+	} else if q.Error != nil {
+		return nil, q.Error
+	}
+
+	return &s, nil
+}
+
+// FindByRefreshTokenHash implementation
+func (repo *sessionRepository) FindByRefreshTokenHash(hash string) (*domain.Session, error) {
+	s := domain.Session{}
+	if q := repo.db.
+		Where("`refresh_token_hash` = ? OR `previous_refresh_token_hash` = ? ", hash, hash).
+		First(&s); q.RecordNotFound() {
+		return nil, domain.ErrSessionNotFound
+	} else if q.Error != nil {
+		return nil, q.Error
+	}
+
+	return &s, nil
+}
+
+// Update implementation
+func (repo *sessionRepository) Update(s *domain.Session) (*domain.Session, error) {
+	if err := repo.db.Save(s).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Rotate implementation
+func (repo *sessionRepository) Rotate(s *domain.Session, previousHash string) (bool, error) {
+	q := repo.db.
+		Model(&domain.Session{}).
+		Where("`id` = ? AND `refresh_token_hash` = ? AND `revoked_at` IS NULL ", s.ID, previousHash).
+		Updates(map[string]interface{}{
+			"refresh_token_hash":          s.RefreshTokenHash,
+			"previous_refresh_token_hash": s.PreviousRefreshTokenHash,
+			"user_agent":                  s.UserAgent,
+			"ip":                          s.IP,
+			"last_used_at":                s.LastUsedAt,
+			"expires_at":                  s.ExpiresAt,
+		})
+	if q.Error != nil {
+		return false, q.Error
+	}
+
+	return q.RowsAffected == 1, nil
+}
+
+// ListActiveByUser implementation
+func (repo *sessionRepository) ListActiveByUser(userID uint, at time.Time) ([]domain.Session, error) {
+	var sessions []domain.Session
+	if err := repo.db.
+		Where("`user_id` = ? AND `revoked_at` IS NULL AND `expires_at` > ? ", userID, at).
+		Order("`last_used_at` DESC").
+		Find(&sessions).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
+
+// RevokeAllByUser implementation
+func (repo *sessionRepository) RevokeAllByUser(userID uint, at time.Time) error {
+	return repo.db.
+		Model(&domain.Session{}).
+		Where("`user_id` = ? AND `revoked_at` IS NULL ", userID).
+		Update("revoked_at", at).
+		Error
+}
@@ -0,0 +1,85 @@
+package mysql
+
+import (
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/jinzhu/gorm"
+)
+
+type submissionRepository struct {
+	db *gorm.DB
+}
+
+// NewSubmissionRepository func
+func NewSubmissionRepository(db *gorm.DB) domain.SubmissionRepository {
+	return &submissionRepository{db}
+}
+
+// Create implementation
+func (repo *submissionRepository) Create(s *domain.Submission) (*domain.Submission, error) {
+	if err := repo.db.Create(s).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// FindByID implementation
+func (repo *submissionRepository) FindByID(id uint) (*domain.Submission, error) {
+	s := domain.Submission{}
+	if q := repo.db.Find(&s, id); q.RecordNotFound() {
+		return nil, domain.ErrSubmissionNotFound
+	} else if q.Error != nil {
+		return nil, q.Error
+	}
+
+	return &s, nil
+}
+
+// FindByReceiptID implementation
+func (repo *submissionRepository) FindByReceiptID(receiptID string) (*domain.Submission, error) {
+	s := domain.Submission{}
+	if q := repo.db.
+		Where("`receipt_id` = ? ", receiptID).
+		Find(&s); q.RecordNotFound() {
+		return nil, domain.ErrSubmissionNotFound
+	} else if q.Error != nil {
+		return nil, q.Error
+	}
+
+	return &s, nil
+}
+
+// ListByLink implementation
+func (repo *submissionRepository) ListByLink(linkID uint) ([]domain.Submission, error) {
+	var submissions []domain.Submission
+	if err := repo.db.
+		Where("`link_id` = ? ", linkID).
+		Order("`created_at` DESC").
+		Find(&submissions).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return submissions, nil
+}
+
+// ListUnnotifiedByLink implementation
+func (repo *submissionRepository) ListUnnotifiedByLink(linkID uint) ([]domain.Submission, error) {
+	var submissions []domain.Submission
+	if err := repo.db.
+		Where("`link_id` = ? AND `notified_at` IS NULL", linkID).
+		Order("`created_at` ASC").
+		Find(&submissions).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return submissions, nil
+}
+
+// Update implementation
+func (repo *submissionRepository) Update(s *domain.Submission) (*domain.Submission, error) {
+	if err := repo.db.Save(s).Error; err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/jinzhu/gorm"
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+// NewWebhookRepository func
+func NewWebhookRepository(db *gorm.DB) domain.WebhookRepository {
+	return &webhookRepository{db}
+}
+
+// Create implementation
+func (repo *webhookRepository) Create(w *domain.Webhook) (*domain.Webhook, error) {
+	if err := repo.db.Create(w).Error; err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// FindByID implementation
+func (repo *webhookRepository) FindByID(id uint) (*domain.Webhook, error) {
+	w := domain.Webhook{}
+	if q := repo.db.Find(&w, id); q.RecordNotFound() {
+		return nil, domain.ErrWebhookNotFound
+	} else if q.Error != nil {
+		return nil, q.Error
+	}
+
+	return &w, nil
+}
+
+// ListByUser implementation
+func (repo *webhookRepository) ListByUser(userID uint) ([]domain.Webhook, error) {
+	var webhooks []domain.Webhook
+	if err := repo.db.
+		Where("`user_id` = ? ", userID).
+		Order("`id` ASC").
+		Find(&webhooks).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return webhooks, nil
+}
+
+// Update implementation
+func (repo *webhookRepository) Update(w *domain.Webhook) (*domain.Webhook, error) {
+	if err := repo.db.Save(w).Error; err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Delete implementation, the deliveries are removed by the foreign key
+func (repo *webhookRepository) Delete(w *domain.Webhook) error {
+	return repo.db.Delete(w).Error
+}
+
+// CreateDelivery implementation
+func (repo *webhookRepository) CreateDelivery(d *domain.WebhookDelivery) (*domain.WebhookDelivery, error) {
+	if err := repo.db.Create(d).Error; err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// UpdateDelivery implementation
+func (repo *webhookRepository) UpdateDelivery(d *domain.WebhookDelivery) (*domain.WebhookDelivery, error) {
+	if err := repo.db.Save(d).Error; err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// ListDueDeliveries implementation
+func (repo *webhookRepository) ListDueDeliveries(now time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	if err := repo.db.
+		Where("`status` = ? AND `next_attempt_at` <= ? ", domain.WebhookDeliveryPending, now).
+		Order("`next_attempt_at` ASC").
+		Limit(limit).
+		Find(&deliveries).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+// ListDeliveriesByWebhook implementation
+func (repo *webhookRepository) ListDeliveriesByWebhook(webhookID uint, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	if err := repo.db.
+		Where("`webhook_id` = ? ", webhookID).
+		Order("`id` DESC").
+		Limit(limit).
+		Find(&deliveries).
+		Error; err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
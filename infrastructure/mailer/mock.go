@@ -14,6 +14,9 @@ type MockMessage struct {
 // MockMessages is an in-memory storage for testing purpose
 var MockMessages []MockMessage
 
+// mockErrors maps the recipient addresses to the error Send returns
+var mockErrors = map[string]error{}
+
 func init() {
 	MockMessages = make([]MockMessage, 0)
 }
@@ -23,6 +26,15 @@ func ClearMessages() {
 	MockMessages = make([]MockMessage, 0)
 }
 
+// SetMockError makes Send fail for the recipient address, nil error lets it succeed again
+func SetMockError(address string, err error) {
+	if err == nil {
+		delete(mockErrors, address)
+		return
+	}
+	mockErrors[address] = err
+}
+
 type mockMailer struct{}
 
 // NewMockMailer returns new mockMailer instance
@@ -32,6 +44,9 @@ func NewMockMailer() domain.Mailer {
 
 // Send sends the email to mockMailer server
 func (m *mockMailer) Send(from, to domain.MailAddress, subject, messagePlain, messageHTML string) error {
+	if err := mockErrors[to.Address]; err != nil {
+		return err
+	}
 	MockMessages = append(MockMessages, MockMessage{from.Address, to.Address, subject, messagePlain, messageHTML})
 	return nil
 }
@@ -0,0 +1,62 @@
+package netguard
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// ErrDisallowedAddress is returned when the host resolves to an internal address
+var ErrDisallowedAddress = errors.New("the address is not allowed")
+
+// privateNetworks are the private IPv4 ranges (RFC 1918), the carrier-grade NAT range (RFC 6598),
+// the "this network" range (RFC 1122) and the IPv6 unique local addresses (RFC 4193)
+var privateNetworks = []*net.IPNet{
+	{IP: net.IPv4(0, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(10, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)},
+	{IP: net.IPv4(172, 16, 0, 0), Mask: net.CIDRMask(12, 32)},
+	{IP: net.IPv4(192, 168, 0, 0), Mask: net.CIDRMask(16, 32)},
+	{IP: net.IP{0xfc, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}, Mask: net.CIDRMask(7, 128)},
+}
+
+// NewTransport returns HTTP transport which can not reach the server itself or the internal network.
+// The addresses are checked when connecting, after the host name is resolved and on every redirect
+func NewTransport(timeout time.Duration) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: timeout,
+		Control: CheckAddress,
+	}
+
+	// the requests do not go through the environment proxy, which would be the only address checked
+	return &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: timeout,
+		MaxIdleConns:        100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
+
+// CheckAddress rejects the loopback, private, link-local and unspecified addresses,
+// it is meant for net.Dialer.Control
+func CheckAddress(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsUnspecified() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+		return ErrDisallowedAddress
+	}
+
+	for _, ipNet := range privateNetworks {
+		if ipNet.Contains(ip) {
+			return ErrDisallowedAddress
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,486 @@
+package storageprovider
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+const (
+	googleDriveProviderID uint = 87654321
+
+	// DefaultGoogleDriveAPIBaseURL is the base URL of the Google Drive API
+	DefaultGoogleDriveAPIBaseURL = "https://www.googleapis.com"
+	// DefaultGoogleDriveUploadChunkSize is the size of each request body when uploading a file
+	DefaultGoogleDriveUploadChunkSize int64 = 8 << 20
+
+	// googleDriveUploadChunkAlignment is required by Google Drive for every chunk but the last one
+	googleDriveUploadChunkAlignment int64 = 256 << 10
+	// googleDriveUploadTimeout is the time limit of each upload request, not the whole file
+	googleDriveUploadTimeout = 2 * time.Minute
+	// googleDriveMaxCachedCredentials bounds the folder cache, it is emptied once it is full
+	googleDriveMaxCachedCredentials = 1000
+
+	googleDriveFolderMimeType = "application/vnd.google-apps.folder"
+	// Google Drive accepts any name, the slash is replaced so the stored path stays unambiguous
+	googleDriveInvalidFileNameChars = "/"
+	googleDriveMaxFileNameLength    = 255
+
+	googleDriveAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleDriveTokenURL = "https://oauth2.googleapis.com/token"
+	googleDriveScope    = "https://www.googleapis.com/auth/drive.file email profile"
+)
+
+type googleDrive struct {
+	remoteDirectory string
+	config          GoogleDriveConfig
+	oauth2Config    OAuth2Config
+
+	// folderIDs caches the folder IDs of each access token, keyed by the parent ID and the folder name
+	folderIDsMu sync.Mutex
+	folderIDs   map[string]map[string]string
+}
+
+// GoogleDriveConfig holds the Google Drive API settings, zero values are replaced with the defaults
+type GoogleDriveConfig struct {
+	APIBaseURL string
+	// UploadChunkSize is rounded down to a multiple of 256 KiB
+	UploadChunkSize int64
+}
+
+type googleDriveFile struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+type googleDriveErrorJSON struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewGoogleDriveStorageProvider returns new StorageProviderService
+func NewGoogleDriveStorageProvider(remoteDirectory string, config GoogleDriveConfig, oauth2Config OAuth2Config) domain.StorageProviderService {
+	if config.APIBaseURL == "" {
+		config.APIBaseURL = DefaultGoogleDriveAPIBaseURL
+	}
+	config.UploadChunkSize -= config.UploadChunkSize % googleDriveUploadChunkAlignment
+	if config.UploadChunkSize <= 0 {
+		config.UploadChunkSize = DefaultGoogleDriveUploadChunkSize
+	}
+	config.APIBaseURL = strings.TrimRight(config.APIBaseURL, "/")
+
+	return &googleDrive{
+		remoteDirectory: remoteDirectory,
+		config:          config,
+		oauth2Config:    oauth2Config.withDefaults(googleDriveAuthURL, googleDriveTokenURL),
+		folderIDs:       map[string]map[string]string{},
+	}
+}
+
+// ID returns provider ID
+func (g *googleDrive) ID() uint {
+	return googleDriveProviderID
+}
+
+// AuthorizationURL returns the URL of Google consent page
+func (g *googleDrive) AuthorizationURL(state, redirectURL string) (string, error) {
+	return g.oauth2Config.authCodeURL(state, redirectURL, url.Values{
+		"scope": {googleDriveScope},
+		// ask for refresh token
+		"access_type": {"offline"},
+		"prompt":      {"consent"},
+	})
+}
+
+// ExchangeCode trades the authorization code for Google access token
+func (g *googleDrive) ExchangeCode(code, redirectURL string) (domain.StorageProviderCredential, error) {
+	return g.oauth2Config.exchangeCode(code, redirectURL)
+}
+
+// RefreshCredential requests new Google access token using the refresh token
+func (g *googleDrive) RefreshCredential(cred domain.StorageProviderCredential) (domain.StorageProviderCredential, error) {
+	return g.oauth2Config.refreshCredential(cred)
+}
+
+// AccountInfo fetches Google Drive account information
+func (g *googleDrive) AccountInfo(cred domain.StorageProviderCredential) (domain.StorageProviderAccountInfo, error) {
+	var (
+		accountInfo domain.StorageProviderAccountInfo
+		respBody    struct {
+			User struct {
+				EmailAddress string `json:"emailAddress"`
+				PhotoLink    string `json:"photoLink"`
+			} `json:"user"`
+		}
+	)
+
+	req, err := http.NewRequest(
+		http.MethodGet,
+		g.config.APIBaseURL+"/drive/v3/about?fields=user",
+		nil,
+	)
+	if err != nil {
+		return accountInfo, err
+	}
+
+	err = g.do(cred.UserAccessToken, req, 5*time.Second, &respBody)
+	if err != nil {
+		return accountInfo, err
+	}
+
+	accountInfo.Email = respBody.User.EmailAddress
+	accountInfo.Photo = respBody.User.PhotoLink
+
+	return accountInfo, nil
+}
+
+// Upload sends the file to Google Drive, inside the slug folder under the remote directory
+func (g *googleDrive) Upload(cred domain.StorageProviderCredential, file io.Reader, fileName, slug string) (string, error) {
+	rootFolderID, err := g.findOrCreateFolder(cred.UserAccessToken, g.remoteDirectory, "root")
+	if err != nil {
+		return "", err
+	}
+
+	// each sub folder of the slug is a separate Drive folder
+	slugFolderID := rootFolderID
+	for _, folder := range strings.Split(strings.Trim(slug, "/"), "/") {
+		slugFolderID, err = g.findOrCreateFolder(cred.UserAccessToken, folder, slugFolderID)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	metadata, err := json.Marshal(map[string]interface{}{
+		"name":    sanitizeFileName(fileName, googleDriveInvalidFileNameChars, googleDriveMaxFileNameLength),
+		"parents": []string{slugFolderID},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	// read ahead the first chunk to find out whether the file fits in a single request
+	chunk := make([]byte, g.config.UploadChunkSize)
+	n, err := io.ReadFull(file, chunk)
+	var uploadedFile googleDriveFile
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		uploadedFile, err = g.uploadSingle(cred.UserAccessToken, metadata, chunk[:n])
+	} else if err == nil {
+		uploadedFile, err = g.uploadResumable(cred.UserAccessToken, metadata, chunk, file)
+	}
+	if err != nil {
+		// the cached folders might have been deleted meanwhile
+		g.forgetFolders(cred.UserAccessToken)
+		return "", err
+	}
+
+	return fmt.Sprintf("/%s/%s/%s", g.remoteDirectory, slug, uploadedFile.Name), nil
+}
+
+// uploadSingle sends the metadata along with the whole content in a multipart request
+func (g *googleDrive) uploadSingle(accessToken string, metadata, content []byte) (googleDriveFile, error) {
+	var (
+		uploadedFile googleDriveFile
+		body         bytes.Buffer
+	)
+
+	mw := multipart.NewWriter(&body)
+	if err := writeGoogleDriveMultipartBody(mw, metadata, content); err != nil {
+		return uploadedFile, err
+	}
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		g.config.APIBaseURL+"/upload/drive/v3/files?uploadType=multipart&fields=id,name",
+		&body,
+	)
+	if err != nil {
+		return uploadedFile, err
+	}
+	req.Header.Set("Content-Type", "multipart/related; boundary="+mw.Boundary())
+
+	err = g.do(accessToken, req, googleDriveUploadTimeout, &uploadedFile)
+	return uploadedFile, err
+}
+
+// uploadResumable starts a resumable upload session and sends the file chunk by chunk,
+// the next chunk is read ahead so the last chunk can tell the total size
+func (g *googleDrive) uploadResumable(accessToken string, metadata, firstChunk []byte, file io.Reader) (googleDriveFile, error) {
+	var uploadedFile googleDriveFile
+
+	req, err := http.NewRequest(
+		http.MethodPost,
+		g.config.APIBaseURL+"/upload/drive/v3/files?uploadType=resumable&fields=id,name",
+		bytes.NewReader(metadata),
+	)
+	if err != nil {
+		return uploadedFile, err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=UTF-8")
+
+	res, err := g.send(accessToken, req, 5*time.Second)
+	if err != nil {
+		return uploadedFile, err
+	}
+	res.Body.Close()
+
+	sessionURL := res.Header.Get("Location")
+	if sessionURL == "" {
+		return uploadedFile, errors.New("Google Drive error: no upload session URL")
+	}
+
+	var (
+		chunk  = firstChunk
+		next   = make([]byte, len(firstChunk))
+		offset int64
+	)
+	for {
+		n, err := io.ReadFull(file, next)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return uploadedFile, err
+		}
+
+		total := "*"
+		if n == 0 {
+			total = strconv.FormatInt(offset+int64(len(chunk)), 10)
+		}
+
+		done, err := g.uploadChunk(accessToken, sessionURL, chunk, offset, total, &uploadedFile)
+		if err != nil {
+			return uploadedFile, err
+		}
+		if done {
+			return uploadedFile, nil
+		}
+		if n == 0 {
+			return uploadedFile, errors.New("Google Drive error: the upload session is not finished")
+		}
+
+		offset += int64(len(chunk))
+		chunk, next = next[:n], chunk[:cap(chunk)]
+	}
+}
+
+// uploadChunk sends the chunk to the upload session, it reports whether the upload is finished
+// and the uploaded file is decoded to v
+func (g *googleDrive) uploadChunk(accessToken, sessionURL string, chunk []byte, offset int64, total string, v interface{}) (bool, error) {
+	req, err := http.NewRequest(http.MethodPut, sessionURL, bytes.NewReader(chunk))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%s", offset, offset+int64(len(chunk))-1, total))
+
+	res, err := g.send(accessToken, req, googleDriveUploadTimeout)
+	if err != nil {
+		return false, err
+	}
+	defer res.Body.Close()
+
+	// Google Drive responds 308 until the last chunk is received
+	if res.StatusCode == http.StatusPermanentRedirect {
+		return false, nil
+	}
+
+	return true, json.NewDecoder(res.Body).Decode(v)
+}
+
+func writeGoogleDriveMultipartBody(mw *multipart.Writer, metadata, content []byte) error {
+	metadataPart, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"application/json; charset=UTF-8"},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = metadataPart.Write(metadata); err != nil {
+		return err
+	}
+
+	mediaPart, err := mw.CreatePart(textproto.MIMEHeader{
+		"Content-Type": {"application/octet-stream"},
+	})
+	if err != nil {
+		return err
+	}
+
+	if _, err = mediaPart.Write(content); err != nil {
+		return err
+	}
+
+	return mw.Close()
+}
+
+// cachedFolderID returns the cached ID of the folder inside the parent folder, if any
+func (g *googleDrive) cachedFolderID(accessToken, name, parentID string) (string, bool) {
+	g.folderIDsMu.Lock()
+	defer g.folderIDsMu.Unlock()
+
+	id, ok := g.folderIDs[accessToken][parentID+"/"+name]
+	return id, ok
+}
+
+// cacheFolderID keeps the ID of the folder inside the parent folder for the next uploads
+func (g *googleDrive) cacheFolderID(accessToken, name, parentID, id string) {
+	g.folderIDsMu.Lock()
+	defer g.folderIDsMu.Unlock()
+
+	folders, ok := g.folderIDs[accessToken]
+	if !ok {
+		if len(g.folderIDs) >= googleDriveMaxCachedCredentials {
+			g.folderIDs = map[string]map[string]string{}
+		}
+		folders = map[string]string{}
+		g.folderIDs[accessToken] = folders
+	}
+	folders[parentID+"/"+name] = id
+}
+
+// forgetFolders drops the cached folder IDs of the access token
+func (g *googleDrive) forgetFolders(accessToken string) {
+	g.folderIDsMu.Lock()
+	defer g.folderIDsMu.Unlock()
+
+	delete(g.folderIDs, accessToken)
+}
+
+// findOrCreateFolder returns the ID of a folder with the given name inside the parent folder,
+// the folder is created when it does not exist yet. The IDs are cached for the access token
+func (g *googleDrive) findOrCreateFolder(accessToken, name, parentID string) (string, error) {
+	if id, ok := g.cachedFolderID(accessToken, name, parentID); ok {
+		return id, nil
+	}
+
+	id, err := g.findOrCreateFolderUncached(accessToken, name, parentID)
+	if err != nil {
+		return "", err
+	}
+
+	g.cacheFolderID(accessToken, name, parentID, id)
+	return id, nil
+}
+
+func (g *googleDrive) findOrCreateFolderUncached(accessToken, name, parentID string) (string, error) {
+	query := fmt.Sprintf(
+		"name = '%s' and mimeType = '%s' and '%s' in parents and trashed = false",
+		escapeGoogleDriveQuery(name),
+		googleDriveFolderMimeType,
+		escapeGoogleDriveQuery(parentID),
+	)
+
+	req, err := http.NewRequest(
+		http.MethodGet,
+		g.config.APIBaseURL+"/drive/v3/files?spaces=drive&fields=files(id,name)&q="+url.QueryEscape(query),
+		nil,
+	)
+	if err != nil {
+		return "", err
+	}
+
+	var fileList struct {
+		Files []googleDriveFile `json:"files"`
+	}
+	err = g.do(accessToken, req, 5*time.Second, &fileList)
+	if err != nil {
+		return "", err
+	}
+
+	if len(fileList.Files) > 0 {
+		return fileList.Files[0].ID, nil
+	}
+
+	body, err := json.Marshal(map[string]interface{}{
+		"name":     name,
+		"mimeType": googleDriveFolderMimeType,
+		"parents":  []string{parentID},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	req, err = http.NewRequest(
+		http.MethodPost,
+		g.config.APIBaseURL+"/drive/v3/files?fields=id,name",
+		bytes.NewReader(body),
+	)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	var folder googleDriveFile
+	err = g.do(accessToken, req, 5*time.Second, &folder)
+	if err != nil {
+		return "", err
+	}
+
+	return folder.ID, nil
+}
+
+// do sends the request to Google Drive API and decodes the JSON response to v
+func (g *googleDrive) do(accessToken string, req *http.Request, timeout time.Duration, v interface{}) error {
+	res, err := g.send(accessToken, req, timeout)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
+
+// send sends the request to Google Drive API, the error responses are mapped to errors.
+// The 308 response of the upload sessions is returned as is
+func (g *googleDrive) send(accessToken string, req *http.Request, timeout time.Duration) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	client := http.Client{
+		Timeout: timeout,
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if (res.StatusCode < 200 || res.StatusCode > 299) && res.StatusCode != http.StatusPermanentRedirect {
+		defer res.Body.Close()
+		return nil, g.mapToRegularError(res.Body, res.StatusCode)
+	}
+
+	return res, nil
+}
+
+func (g *googleDrive) mapToRegularError(responseReader io.Reader, httpStatusCode int) error {
+	byteResponse, err := io.ReadAll(responseReader)
+	if err != nil {
+		return err
+	}
+
+	// Google responds 401 only for invalid or expired access token
+	if httpStatusCode == http.StatusUnauthorized {
+		return domain.ErrStorageProviderCredentialExpired
+	}
+
+	var errorJSON googleDriveErrorJSON
+	if json.Unmarshal(byteResponse, &errorJSON) == nil && errorJSON.Error.Message != "" {
+		return errors.New("Google Drive error: " + errorJSON.Error.Message)
+	}
+
+	return fmt.Errorf("Unknown Google Drive error (HTTP %d).\n%s", httpStatusCode, string(byteResponse))
+}
+
+func escapeGoogleDriveQuery(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s)
+}
@@ -0,0 +1,223 @@
+package storageprovider_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/infrastructure/storageprovider"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDriveFile struct {
+	id, name, parent, mimeType, content string
+}
+
+// newFakeDrive returns a minimal Google Drive API stand-in, it counts the folder lookups
+func newFakeDrive(t *testing.T, files *[]fakeDriveFile, folderLookups *int) *httptest.Server {
+	sessions := map[string]*fakeDriveFile{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer drive_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error": {"code": 401, "message": "Invalid Credentials"}}`))
+			return
+		}
+
+		newID := func() string {
+			return "file" + string(rune('a'+len(*files)))
+		}
+
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/about":
+			w.Write([]byte(`{"user": {"emailAddress": "user@drophere.link", "photoLink": "http://my.photo/user1.jpg"}}`))
+
+		case r.Method == http.MethodGet && r.URL.Path == "/drive/v3/files":
+			if folderLookups != nil {
+				*folderLookups++
+			}
+			q := r.URL.Query().Get("q")
+			found := []map[string]string{}
+			for _, f := range *files {
+				if strings.Contains(q, "name = '"+f.name+"'") && strings.Contains(q, "'"+f.parent+"' in parents") {
+					found = append(found, map[string]string{"id": f.id, "name": f.name})
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"files": found})
+
+		case r.Method == http.MethodPost && r.URL.Path == "/drive/v3/files":
+			var body struct {
+				Name     string   `json:"name"`
+				MimeType string   `json:"mimeType"`
+				Parents  []string `json:"parents"`
+			}
+			json.NewDecoder(r.Body).Decode(&body)
+			f := fakeDriveFile{id: newID(), name: body.Name, parent: body.Parents[0], mimeType: body.MimeType}
+			*files = append(*files, f)
+			json.NewEncoder(w).Encode(map[string]string{"id": f.id, "name": f.name})
+
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/drive/v3/files" && r.URL.Query().Get("uploadType") == "resumable":
+			var metadata struct {
+				Name    string   `json:"name"`
+				Parents []string `json:"parents"`
+			}
+			json.NewDecoder(r.Body).Decode(&metadata)
+
+			sessionID := "session" + strconv.Itoa(len(sessions))
+			sessions[sessionID] = &fakeDriveFile{name: metadata.Name, parent: metadata.Parents[0]}
+			w.Header().Set("Location", "http://"+r.Host+"/upload/sessions/"+sessionID)
+
+		case r.Method == http.MethodPut && strings.HasPrefix(r.URL.Path, "/upload/sessions/"):
+			f := sessions[strings.TrimPrefix(r.URL.Path, "/upload/sessions/")]
+			content, _ := io.ReadAll(r.Body)
+
+			// e.g. "bytes 0-262143/*"
+			var start, end int
+			var total string
+			_, err := fmt.Sscanf(r.Header.Get("Content-Range"), "bytes %d-%d/%s", &start, &end, &total)
+			if !assert.Nil(t, err) || !assert.Equal(t, len(f.content), start) || !assert.Equal(t, len(content), end-start+1) {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			f.content += string(content)
+
+			if total == "*" {
+				w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", end))
+				w.WriteHeader(http.StatusPermanentRedirect)
+				return
+			}
+
+			assert.Equal(t, strconv.Itoa(len(f.content)), total)
+			f.id = newID()
+			*files = append(*files, *f)
+			json.NewEncoder(w).Encode(map[string]string{"id": f.id, "name": f.name})
+
+		case r.Method == http.MethodPost && r.URL.Path == "/upload/drive/v3/files":
+			_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			if !assert.Nil(t, err) {
+				return
+			}
+			mr := multipart.NewReader(r.Body, params["boundary"])
+
+			var metadata struct {
+				Name    string   `json:"name"`
+				Parents []string `json:"parents"`
+			}
+			part, _ := mr.NextPart()
+			json.NewDecoder(part).Decode(&metadata)
+			part, _ = mr.NextPart()
+			content, _ := io.ReadAll(part)
+
+			f := fakeDriveFile{id: newID(), name: metadata.Name, parent: metadata.Parents[0], content: string(content)}
+			*files = append(*files, f)
+			json.NewEncoder(w).Encode(map[string]string{"id": f.id, "name": f.name})
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestGoogleDriveAccountInfo(t *testing.T) {
+	files := []fakeDriveFile{}
+	server := newFakeDrive(t, &files, nil)
+	defer server.Close()
+
+	drive := storageprovider.NewGoogleDriveStorageProvider("drophere", storageprovider.GoogleDriveConfig{APIBaseURL: server.URL}, storageprovider.OAuth2Config{})
+
+	accountInfo, err := drive.AccountInfo(domain.StorageProviderCredential{UserAccessToken: "drive_token"})
+	assert.Nil(t, err)
+	assert.Equal(t, domain.StorageProviderAccountInfo{
+		Email: "user@drophere.link",
+		Photo: "http://my.photo/user1.jpg",
+	}, accountInfo)
+
+	_, err = drive.AccountInfo(domain.StorageProviderCredential{UserAccessToken: "invalid_token"})
+	assert.Equal(t, domain.ErrStorageProviderCredentialExpired, err)
+}
+
+func TestGoogleDriveUpload(t *testing.T) {
+	files := []fakeDriveFile{
+		{id: "existing_root", name: "drophere", parent: "root", mimeType: "application/vnd.google-apps.folder"},
+	}
+	folderLookups := 0
+	server := newFakeDrive(t, &files, &folderLookups)
+	defer server.Close()
+
+	drive := storageprovider.NewGoogleDriveStorageProvider("drophere", storageprovider.GoogleDriveConfig{APIBaseURL: server.URL}, storageprovider.OAuth2Config{})
+	cred := domain.StorageProviderCredential{UserAccessToken: "drive_token"}
+
+	storedPath, err := drive.Upload(cred, strings.NewReader("first file"), "report.pdf", "drop-here")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/report.pdf", storedPath)
+
+	storedPath, err = drive.Upload(cred, strings.NewReader("second file"), "cv.pdf", "drop-here")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/cv.pdf", storedPath)
+
+	// the folder IDs are cached after the first upload
+	assert.Equal(t, 2, folderLookups)
+
+	// the root folder is reused and the slug folder is only created once
+	if assert.Len(t, files, 4) {
+		slugFolder := files[1]
+		assert.Equal(t, "drop-here", slugFolder.name)
+		assert.Equal(t, "existing_root", slugFolder.parent)
+
+		assert.Equal(t, fakeDriveFile{id: files[2].id, name: "report.pdf", parent: slugFolder.id, content: "first file"}, files[2])
+		assert.Equal(t, fakeDriveFile{id: files[3].id, name: "cv.pdf", parent: slugFolder.id, content: "second file"}, files[3])
+	}
+
+	// the sub folder is created inside the slug folder
+	storedPath, err = drive.Upload(cred, strings.NewReader("late file"), "essay.pdf", "drop-here/late")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/late/essay.pdf", storedPath)
+
+	if assert.Len(t, files, 6) {
+		lateFolder := files[4]
+		assert.Equal(t, "late", lateFolder.name)
+		assert.Equal(t, files[1].id, lateFolder.parent)
+		assert.Equal(t, lateFolder.id, files[5].parent)
+	}
+
+	_, err = drive.Upload(domain.StorageProviderCredential{UserAccessToken: "invalid_token"}, strings.NewReader(""), "cv.pdf", "drop-here")
+	assert.Equal(t, domain.ErrStorageProviderCredentialExpired, err)
+}
+
+func TestGoogleDriveResumableUpload(t *testing.T) {
+	files := []fakeDriveFile{
+		{id: "existing_root", name: "drophere", parent: "root", mimeType: "application/vnd.google-apps.folder"},
+		{id: "existing_slug", name: "drop-here", parent: "existing_root", mimeType: "application/vnd.google-apps.folder"},
+	}
+	server := newFakeDrive(t, &files, nil)
+	defer server.Close()
+
+	// the chunk size is rounded down to 256 KiB
+	drive := storageprovider.NewGoogleDriveStorageProvider("drophere", storageprovider.GoogleDriveConfig{APIBaseURL: server.URL, UploadChunkSize: 300 << 10}, storageprovider.OAuth2Config{})
+	cred := domain.StorageProviderCredential{UserAccessToken: "drive_token"}
+
+	large := strings.Repeat("0123456789abcdef", 40<<10)
+	storedPath, err := drive.Upload(cred, strings.NewReader(large), "video.mp4", "drop-here")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/video.mp4", storedPath)
+
+	// the file size is a multiple of the chunk size
+	exact := strings.Repeat("x", 512<<10)
+	storedPath, err = drive.Upload(cred, strings.NewReader(exact), "data.bin", "drop-here")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/data.bin", storedPath)
+
+	if assert.Len(t, files, 4) {
+		assert.Equal(t, fakeDriveFile{id: files[2].id, name: "video.mp4", parent: "existing_slug", content: large}, files[2])
+		assert.Equal(t, fakeDriveFile{id: files[3].id, name: "data.bin", parent: "existing_slug", content: exact}, files[3])
+	}
+}
@@ -1,14 +1,17 @@
 package storageprovider
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
+	"unicode/utf16"
 
 	"github.com/bccfilkom/drophere-go/domain"
 )
@@ -17,10 +20,55 @@ var (
 	errNotEnoughScope = errors.New("Not enough scope given from the Dropbox access token. Please grant the required scope 'files.content.write' and reset the access token.")
 )
 
-const dropboxProviderID uint = 12345678
+const (
+	dropboxProviderID uint = 12345678
+
+	// DefaultDropboxAPIBaseURL is the base URL of the Dropbox RPC endpoints
+	DefaultDropboxAPIBaseURL = "https://api.dropboxapi.com"
+	// DefaultDropboxContentBaseURL is the base URL of the Dropbox content upload endpoints
+	DefaultDropboxContentBaseURL = "https://content.dropboxapi.com"
+	// DefaultDropboxUploadChunkSize is the size of each request body when uploading a file
+	DefaultDropboxUploadChunkSize int64 = 8 << 20
+
+	// dropboxMaxUploadChunkSize is the request body limit of Dropbox upload endpoints
+	dropboxMaxUploadChunkSize int64 = 150 << 20
+	// dropboxUploadTimeout is the time limit of each upload request, not the whole file
+	dropboxUploadTimeout = 2 * time.Minute
+	// dropboxInvalidFileNameChars are rejected or made incompatible by Dropbox
+	dropboxInvalidFileNameChars = `/\<>:"|?*`
+	dropboxMaxFileNameLength    = 255
+
+	dropboxAuthURL  = "https://www.dropbox.com/oauth2/authorize"
+	dropboxTokenURL = "https://api.dropboxapi.com/oauth2/token"
+)
 
 type dropbox struct {
 	remoteDirectory string
+	config          DropboxConfig
+	oauth2Config    OAuth2Config
+}
+
+// DropboxConfig holds the Dropbox API settings, zero values are replaced with the defaults
+type DropboxConfig struct {
+	APIBaseURL      string
+	ContentBaseURL  string
+	UploadChunkSize int64
+}
+
+type dropboxCommitInfo struct {
+	Path       string `json:"path"`
+	Mode       string `json:"mode"`
+	Autorename bool   `json:"autorename"`
+	Mute       bool   `json:"mute"`
+}
+
+type dropboxUploadSessionCursor struct {
+	SessionID string `json:"session_id"`
+	Offset    int64  `json:"offset"`
+}
+
+type dropboxFileMetadata struct {
+	PathDisplay string `json:"path_display"`
 }
 
 type dropboxError struct {
@@ -36,9 +84,23 @@ type dropboxErrorJson struct {
 }
 
 // NewDropboxStorageProvider returns new StorageProviderService
-func NewDropboxStorageProvider(remoteDirectory string) domain.StorageProviderService {
+func NewDropboxStorageProvider(remoteDirectory string, config DropboxConfig, oauth2Config OAuth2Config) domain.StorageProviderService {
+	if config.APIBaseURL == "" {
+		config.APIBaseURL = DefaultDropboxAPIBaseURL
+	}
+	if config.ContentBaseURL == "" {
+		config.ContentBaseURL = DefaultDropboxContentBaseURL
+	}
+	if config.UploadChunkSize <= 0 || config.UploadChunkSize > dropboxMaxUploadChunkSize {
+		config.UploadChunkSize = DefaultDropboxUploadChunkSize
+	}
+	config.APIBaseURL = strings.TrimRight(config.APIBaseURL, "/")
+	config.ContentBaseURL = strings.TrimRight(config.ContentBaseURL, "/")
+
 	return &dropbox{
 		remoteDirectory: remoteDirectory,
+		config:          config,
+		oauth2Config:    oauth2Config.withDefaults(dropboxAuthURL, dropboxTokenURL),
 	}
 }
 
@@ -47,13 +109,31 @@ func (d *dropbox) ID() uint {
 	return dropboxProviderID
 }
 
+// AuthorizationURL returns the URL of Dropbox consent page
+func (d *dropbox) AuthorizationURL(state, redirectURL string) (string, error) {
+	return d.oauth2Config.authCodeURL(state, redirectURL, url.Values{
+		// ask for refresh token
+		"token_access_type": {"offline"},
+	})
+}
+
+// ExchangeCode trades the authorization code for Dropbox access token
+func (d *dropbox) ExchangeCode(code, redirectURL string) (domain.StorageProviderCredential, error) {
+	return d.oauth2Config.exchangeCode(code, redirectURL)
+}
+
+// RefreshCredential requests new Dropbox access token using the refresh token
+func (d *dropbox) RefreshCredential(cred domain.StorageProviderCredential) (domain.StorageProviderCredential, error) {
+	return d.oauth2Config.refreshCredential(cred)
+}
+
 // AccountInfo fetches Dropbox account information
 func (d *dropbox) AccountInfo(cred domain.StorageProviderCredential) (domain.StorageProviderAccountInfo, error) {
 	var accountInfo domain.StorageProviderAccountInfo
 
 	req, err := http.NewRequest(
 		http.MethodPost,
-		"https://api.dropboxapi.com/2/users/get_current_account",
+		d.config.APIBaseURL+"/2/users/get_current_account",
 		nil,
 	)
 	if err != nil {
@@ -94,16 +174,113 @@ func (d *dropbox) AccountInfo(cred domain.StorageProviderCredential) (domain.Sto
 	return accountInfo, nil
 }
 
-// Upload sends the file to Dropbox server
-func (d *dropbox) Upload(cred domain.StorageProviderCredential, file io.Reader, fileName, slug string) error {
+// Upload sends the file to Dropbox server. Small files are sent in a single request,
+// larger files are streamed chunk by chunk through an upload session
+func (d *dropbox) Upload(cred domain.StorageProviderCredential, file io.Reader, fileName, slug string) (string, error) {
+	fileName = sanitizeFileName(fileName, dropboxInvalidFileNameChars, dropboxMaxFileNameLength)
+
+	commitInfo := dropboxCommitInfo{
+		Path:       fmt.Sprintf("/%s/%s/%s", d.remoteDirectory, slug, fileName),
+		Mode:       "add",
+		Autorename: true,
+		Mute:       false,
+	}
+
+	// read ahead the first chunk to find out whether the file fits in a single request
+	chunk := make([]byte, d.config.UploadChunkSize)
+	n, err := io.ReadFull(file, chunk)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return d.uploadSingle(cred.UserAccessToken, chunk[:n], commitInfo)
+	} else if err != nil {
+		return "", err
+	}
+
+	return d.uploadSession(cred.UserAccessToken, chunk, file, commitInfo)
+}
+
+// uploadSingle uploads the whole file content using /2/files/upload
+func (d *dropbox) uploadSingle(accessToken string, content []byte, commitInfo dropboxCommitInfo) (string, error) {
+	var fileMetadata dropboxFileMetadata
+	err := d.doContent(accessToken, "/2/files/upload", commitInfo, content, &fileMetadata)
+	if err != nil {
+		return "", err
+	}
+
+	// Dropbox may rename the file, so we use the path from the response
+	return fileMetadata.PathDisplay, nil
+}
+
+// uploadSession starts an upload session with the first chunk, then appends the rest of
+// the file chunk by chunk, and commits the file with the last chunk
+func (d *dropbox) uploadSession(accessToken string, firstChunk []byte, file io.Reader, commitInfo dropboxCommitInfo) (string, error) {
+	var session struct {
+		SessionID string `json:"session_id"`
+	}
+	err := d.doContent(accessToken, "/2/files/upload_session/start", map[string]interface{}{"close": false}, firstChunk, &session)
+	if err != nil {
+		return "", err
+	}
+
+	cursor := dropboxUploadSessionCursor{
+		SessionID: session.SessionID,
+		Offset:    int64(len(firstChunk)),
+	}
+
+	// the chunk buffer is reused for the rest of the file
+	chunk := firstChunk
+	for {
+		n, err := io.ReadFull(file, chunk)
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			var fileMetadata dropboxFileMetadata
+			err = d.doContent(accessToken, "/2/files/upload_session/finish", map[string]interface{}{
+				"cursor": cursor,
+				"commit": commitInfo,
+			}, chunk[:n], &fileMetadata)
+			if err != nil {
+				return "", err
+			}
+
+			return fileMetadata.PathDisplay, nil
+		} else if err != nil {
+			return "", err
+		}
+
+		err = d.doContent(accessToken, "/2/files/upload_session/append_v2", map[string]interface{}{
+			"cursor": cursor,
+			"close":  false,
+		}, chunk, nil)
+		if err != nil {
+			return "", err
+		}
+
+		cursor.Offset += int64(n)
+	}
+}
+
+// doContent sends the content to Dropbox content endpoint with the JSON encoded arguments,
+// then decodes the JSON response to v if it is not nil
+func (d *dropbox) doContent(accessToken, endpoint string, arg interface{}, content []byte, v interface{}) error {
+	apiArg, err := dropboxAPIArg(arg)
+	if err != nil {
+		return err
+	}
 
-	req, err := d.prepareRequest(cred.UserAccessToken, file, fileName, slug)
+	req, err := http.NewRequest(
+		http.MethodPost,
+		d.config.ContentBaseURL+endpoint,
+		bytes.NewReader(content),
+	)
 	if err != nil {
 		return err
 	}
 
+	// prepare header
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Dropbox-API-Arg", apiArg)
+
 	client := http.Client{
-		Timeout: 10 * time.Second,
+		Timeout: dropboxUploadTimeout,
 	}
 
 	// do the request
@@ -111,46 +288,45 @@ func (d *dropbox) Upload(cred domain.StorageProviderCredential, file io.Reader,
 	if err != nil {
 		return err
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
 		dropboxError, err := d.mapToDropboxError(res.Body, res.StatusCode)
-		defer res.Body.Close()
 		if err != nil {
 			return err
 		}
 
-		regularError := d.mapToRegularError(dropboxError)
+		return d.mapToRegularError(dropboxError)
+	}
 
-		return regularError
+	if v == nil {
+		return nil
 	}
 
-	return nil
+	return json.NewDecoder(res.Body).Decode(v)
 }
 
-func (d *dropbox) prepareRequest(accessToken string, file io.Reader, fileName, slug string) (*http.Request, error) {
-
-	req, err := http.NewRequest(
-		http.MethodPost,
-		"https://content.dropboxapi.com/2/files/upload",
-		file,
-	)
+// dropboxAPIArg encodes the Dropbox-API-Arg header, characters outside ASCII
+// have to be escaped because HTTP headers can not carry them
+func dropboxAPIArg(arg interface{}) (string, error) {
+	b, err := json.Marshal(arg)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	// construct Dropbox API arguments
-	dropboxAPIArg := fmt.Sprintf(
-		`{"path": "/%s/%s/%s","mode": "add","autorename": true,"mute": false}`,
-		d.remoteDirectory,
-		slug,
-		fileName,
-	)
+	var sb strings.Builder
+	for _, r := range string(b) {
+		if r < 0x80 {
+			sb.WriteRune(r)
+			continue
+		}
 
-	// prepare header
-	req.Header.Set("Authorization", "Bearer "+accessToken)
-	req.Header.Set("Content-Type", "application/octet-stream")
-	req.Header.Set("Dropbox-API-Arg", dropboxAPIArg)
-	return req, nil
+		for _, c := range utf16.Encode([]rune{r}) {
+			fmt.Fprintf(&sb, `\u%04x`, c)
+		}
+	}
+
+	return sb.String(), nil
 }
 
 func (d *dropbox) mapToDropboxError(responseReader io.Reader, httpStatusCode int) (dropboxError, error) {
@@ -174,9 +350,15 @@ func (d *dropbox) mapToRegularError(dropboxError dropboxError) error {
 			return errNotEnoughScope
 		}
 	} else if dropboxError.HttpCode == http.StatusUnauthorized {
-		if dropboxError.Json.ErrorStructured[".tag"].(string) == "missing_scope" && dropboxError.Json.ErrorStructured["required_scope"].(string) == "files.content.write" {
+		tag, _ := dropboxError.Json.ErrorStructured[".tag"].(string)
+		requiredScope, _ := dropboxError.Json.ErrorStructured["required_scope"].(string)
+		if tag == "missing_scope" && requiredScope == "files.content.write" {
 			return errNotEnoughScope
 		}
+
+		if tag == "expired_access_token" {
+			return domain.ErrStorageProviderCredentialExpired
+		}
 	}
 
 	return errors.New("Unknown dropbox error.\n" + dropboxError.Message)
@@ -0,0 +1,208 @@
+package storageprovider_test
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/infrastructure/storageprovider"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeDropboxCommit struct {
+	Path string `json:"path"`
+}
+
+type fakeDropboxCursor struct {
+	SessionID string `json:"session_id"`
+	Offset    int    `json:"offset"`
+}
+
+type fakeDropbox struct {
+	files    map[string]string
+	sessions map[string]string
+	requests []string
+}
+
+// newFakeDropbox returns a minimal Dropbox API stand-in
+func newFakeDropbox(t *testing.T, fake *fakeDropbox) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fake.requests = append(fake.requests, r.URL.Path)
+
+		if r.Header.Get("Authorization") != "Bearer dropbox_token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error_summary": "expired_access_token/", "error": {".tag": "expired_access_token"}}`))
+			return
+		}
+
+		var arg struct {
+			fakeDropboxCommit
+			Cursor fakeDropboxCursor `json:"cursor"`
+			Commit fakeDropboxCommit `json:"commit"`
+		}
+		if r.URL.Path != "/2/users/get_current_account" {
+			if !assert.Nil(t, json.Unmarshal([]byte(r.Header.Get("Dropbox-API-Arg")), &arg)) {
+				return
+			}
+		}
+		content, _ := io.ReadAll(r.Body)
+
+		checkOffset := func() bool {
+			if len(fake.sessions[arg.Cursor.SessionID]) != arg.Cursor.Offset {
+				w.WriteHeader(http.StatusConflict)
+				w.Write([]byte(`{"error_summary": "incorrect_offset/"}`))
+				return false
+			}
+			return true
+		}
+
+		switch r.URL.Path {
+		case "/2/users/get_current_account":
+			w.Write([]byte(`{"email": "user@drophere.link", "profile_photo_url": "http://my.photo/user1.jpg"}`))
+
+		case "/2/files/upload":
+			fake.files[arg.Path] = string(content)
+			json.NewEncoder(w).Encode(map[string]string{"path_display": arg.Path})
+
+		case "/2/files/upload_session/start":
+			sessionID := "session" + string(rune('a'+len(fake.sessions)))
+			fake.sessions[sessionID] = string(content)
+			json.NewEncoder(w).Encode(map[string]string{"session_id": sessionID})
+
+		case "/2/files/upload_session/append_v2":
+			if checkOffset() {
+				fake.sessions[arg.Cursor.SessionID] += string(content)
+				w.Write([]byte("null"))
+			}
+
+		case "/2/files/upload_session/finish":
+			if checkOffset() {
+				fake.files[arg.Commit.Path] = fake.sessions[arg.Cursor.SessionID] + string(content)
+				json.NewEncoder(w).Encode(map[string]string{"path_display": arg.Commit.Path})
+			}
+
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func TestDropboxAccountInfo(t *testing.T) {
+	fake := &fakeDropbox{files: map[string]string{}, sessions: map[string]string{}}
+	server := newFakeDropbox(t, fake)
+	defer server.Close()
+
+	dropbox := storageprovider.NewDropboxStorageProvider(
+		"drophere",
+		storageprovider.DropboxConfig{APIBaseURL: server.URL, ContentBaseURL: server.URL},
+		storageprovider.OAuth2Config{},
+	)
+
+	accountInfo, err := dropbox.AccountInfo(domain.StorageProviderCredential{UserAccessToken: "dropbox_token"})
+	assert.Nil(t, err)
+	assert.Equal(t, domain.StorageProviderAccountInfo{
+		Email: "user@drophere.link",
+		Photo: "http://my.photo/user1.jpg",
+	}, accountInfo)
+}
+
+func TestDropboxUpload(t *testing.T) {
+	type test struct {
+		fileName     string
+		content      string
+		accessToken  string
+		wantRequests []string
+		wantFileName string
+		wantErr      error
+	}
+
+	tests := []test{
+		{
+			fileName:     "small.txt",
+			content:      "tiny",
+			accessToken:  "dropbox_token",
+			wantRequests: []string{"/2/files/upload"},
+		},
+		{
+			fileName:    "large.txt",
+			content:     "0123456789abcdefghij!",
+			accessToken: "dropbox_token",
+			wantRequests: []string{
+				"/2/files/upload_session/start",
+				"/2/files/upload_session/append_v2",
+				"/2/files/upload_session/finish",
+			},
+		},
+		{
+			// the last chunk is empty when the file size is a multiple of the chunk size
+			fileName:    "exact.txt",
+			content:     "0123456789abcdefghij",
+			accessToken: "dropbox_token",
+			wantRequests: []string{
+				"/2/files/upload_session/start",
+				"/2/files/upload_session/append_v2",
+				"/2/files/upload_session/finish",
+			},
+		},
+		{
+			fileName:     "résumé.pdf",
+			content:      "cv",
+			accessToken:  "dropbox_token",
+			wantRequests: []string{"/2/files/upload"},
+		},
+		{
+			// Dropbox rejects these characters in a file name
+			fileName:     `A: "report"?.pdf. `,
+			content:      "cv",
+			accessToken:  "dropbox_token",
+			wantRequests: []string{"/2/files/upload"},
+			wantFileName: "A_ _report__.pdf",
+		},
+		{
+			fileName:     "large.txt",
+			content:      "0123456789abcdefghij!",
+			accessToken:  "expired_token",
+			wantRequests: []string{"/2/files/upload_session/start"},
+			wantErr:      domain.ErrStorageProviderCredentialExpired,
+		},
+	}
+
+	for i, tc := range tests {
+		fake := &fakeDropbox{files: map[string]string{}, sessions: map[string]string{}}
+		server := newFakeDropbox(t, fake)
+
+		dropbox := storageprovider.NewDropboxStorageProvider(
+			"drophere",
+			storageprovider.DropboxConfig{APIBaseURL: server.URL, ContentBaseURL: server.URL, UploadChunkSize: 10},
+			storageprovider.OAuth2Config{},
+		)
+
+		storedPath, err := dropbox.Upload(
+			domain.StorageProviderCredential{UserAccessToken: tc.accessToken},
+			strings.NewReader(tc.content),
+			tc.fileName,
+			"drop-here",
+		)
+		server.Close()
+
+		assert.Equal(t, tc.wantRequests, fake.requests, "test %d", i)
+		assert.Equal(t, tc.wantErr, err, "test %d", i)
+		if tc.wantErr != nil {
+			continue
+		}
+
+		wantFileName := tc.fileName
+		if tc.wantFileName != "" {
+			wantFileName = tc.wantFileName
+		}
+
+		wantPath := "/drophere/drop-here/" + wantFileName
+		assert.Equal(t, wantPath, storedPath, "test %d", i)
+		assert.Equal(t, tc.content, fake.files[wantPath], "test %d", i)
+	}
+}
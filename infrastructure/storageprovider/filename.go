@@ -0,0 +1,53 @@
+package storageprovider
+
+import (
+	"path"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// fallbackFileName is used when nothing is left of the name after sanitizing
+const fallbackFileName = "file"
+
+// sanitizeFileName replaces the characters the storage provider does not accept with
+// underscore, drops control characters, trims the surrounding spaces and the trailing
+// dots, then shortens the name to maxLength bytes keeping the extension
+func sanitizeFileName(name, invalidChars string, maxLength int) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		if strings.ContainsRune(invalidChars, r) {
+			return '_'
+		}
+		return r
+	}, name)
+
+	name = strings.TrimRight(strings.TrimSpace(name), ". ")
+	if name == "" {
+		return fallbackFileName
+	}
+
+	if len(name) > maxLength {
+		ext := path.Ext(name)
+		if len(ext) >= maxLength/2 {
+			ext = ""
+		}
+		name = truncateUTF8(strings.TrimSuffix(name, ext), maxLength-len(ext)) + ext
+	}
+
+	return name
+}
+
+// truncateUTF8 shortens s to at most n bytes without splitting a character
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,195 @@
+package storageprovider
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+var (
+	errLocalInvalidFileName = errors.New("Invalid file name")
+	errLocalTooManyFiles    = errors.New("Too many files with the same name")
+)
+
+const (
+	localProviderID uint = 44332211
+
+	localMaxRenameAttempts = 1000
+	// localInvalidFileNameChars also covers Windows, so the files can be copied anywhere
+	localInvalidFileNameChars = `<>:"|?*`
+	// localMaxFileNameLength leaves room for the rename suffix within the common 255 bytes limit
+	localMaxFileNameLength = 240
+)
+
+type localFileSystem struct {
+	baseDirectory   string
+	remoteDirectory string
+}
+
+// NewLocalStorageProvider returns new StorageProviderService which stores the files
+// on the server's file system under <base directory>/<remote directory>/<slug>/<file name>
+func NewLocalStorageProvider(baseDirectory, remoteDirectory string) domain.StorageProviderService {
+	return &localFileSystem{
+		baseDirectory:   baseDirectory,
+		remoteDirectory: remoteDirectory,
+	}
+}
+
+// ID returns provider ID
+func (l *localFileSystem) ID() uint {
+	return localProviderID
+}
+
+// AccountInfo checks whether the storage directory is usable,
+// there is no account on local file system so the credential is ignored
+func (l *localFileSystem) AccountInfo(cred domain.StorageProviderCredential) (domain.StorageProviderAccountInfo, error) {
+	var accountInfo domain.StorageProviderAccountInfo
+
+	dir := filepath.Join(l.baseDirectory, l.remoteDirectory)
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return accountInfo, err
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "localhost"
+	}
+	accountInfo.Email = "drophere@" + hostname
+
+	return accountInfo, nil
+}
+
+// Upload writes the file to the server's file system. The file is written to a temporary file
+// first and then linked to its final name, so a partially written file is never visible
+func (l *localFileSystem) Upload(cred domain.StorageProviderCredential, file io.Reader, fileName, slug string) (string, error) {
+	fileName, err := sanitizeLocalFileName(fileName)
+	if err != nil {
+		return "", err
+	}
+	fileName = sanitizeFileName(fileName, localInvalidFileNameChars, localMaxFileNameLength)
+
+	slug, err = sanitizeLocalFolder(slug)
+	if err != nil {
+		return "", err
+	}
+
+	dir := filepath.Join(l.baseDirectory, l.remoteDirectory, filepath.FromSlash(slug))
+	if err = os.MkdirAll(dir, 0750); err != nil {
+		return "", err
+	}
+
+	tmpFile, err := ioutil.TempFile(dir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(tmpFile.Name())
+
+	_, err = io.Copy(tmpFile, file)
+	if err == nil {
+		err = tmpFile.Sync()
+	}
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return "", err
+	}
+
+	storedName, err := linkToAvailableName(tmpFile.Name(), dir, fileName)
+	if err != nil {
+		return "", err
+	}
+
+	return "/" + path.Join(l.remoteDirectory, slug, storedName), nil
+}
+
+// Download opens the file stored on the server's file system
+func (l *localFileSystem) Download(cred domain.StorageProviderCredential, storedPath string) (io.ReadCloser, error) {
+	base, err := filepath.Abs(filepath.Join(l.baseDirectory, l.remoteDirectory))
+	if err != nil {
+		return nil, err
+	}
+
+	fullPath, err := filepath.Abs(filepath.Join(l.baseDirectory, filepath.FromSlash(path.Clean("/"+storedPath))))
+	if err != nil {
+		return nil, err
+	}
+
+	// never serve files outside of the storage directory
+	if !strings.HasPrefix(fullPath, base+string(filepath.Separator)) {
+		return nil, domain.ErrStorageProviderFileNotFound
+	}
+
+	f, err := os.Open(fullPath)
+	if os.IsNotExist(err) {
+		return nil, domain.ErrStorageProviderFileNotFound
+	}
+
+	return f, err
+}
+
+// linkToAvailableName hard-links the source file into dir using fileName, appending " (n)"
+// to the name on collision. Linking fails when the target exists, so concurrent uploads
+// never overwrite each other
+func linkToAvailableName(source, dir, fileName string) (string, error) {
+	ext := filepath.Ext(fileName)
+	base := strings.TrimSuffix(fileName, ext)
+
+	for i := 0; i < localMaxRenameAttempts; i++ {
+		name := fileName
+		if i > 0 {
+			name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+		}
+
+		err := os.Link(source, filepath.Join(dir, name))
+		if err == nil {
+			return name, nil
+		}
+
+		if !os.IsExist(err) {
+			return "", err
+		}
+	}
+
+	return "", errLocalTooManyFiles
+}
+
+// sanitizeLocalFolder drops the empty, "." and ".." segments of the slash-separated folder
+// to prevent path traversal
+func sanitizeLocalFolder(folder string) (string, error) {
+	folder = strings.Replace(folder, "\\", "/", -1)
+
+	segments := make([]string, 0)
+	for _, segment := range strings.Split(folder, "/") {
+		segment = strings.TrimSpace(segment)
+		if segment != "" && segment != "." && segment != ".." {
+			segments = append(segments, segment)
+		}
+	}
+
+	if len(segments) < 1 {
+		return "", errLocalInvalidFileName
+	}
+
+	return strings.Join(segments, "/"), nil
+}
+
+// sanitizeLocalFileName strips any directory component from the name
+// to prevent path traversal
+func sanitizeLocalFileName(name string) (string, error) {
+	name = strings.Replace(name, "\\", "/", -1)
+	name = path.Base(strings.TrimSpace(name))
+
+	if name == "" || name == "." || name == ".." || name == "/" || strings.HasPrefix(name, ".upload-") {
+		return "", errLocalInvalidFileName
+	}
+
+	return name, nil
+}
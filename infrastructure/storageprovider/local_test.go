@@ -0,0 +1,95 @@
+package storageprovider_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/infrastructure/storageprovider"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalUpload(t *testing.T) {
+	type test struct {
+		fileName       string
+		slug           string
+		content        string
+		wantStoredPath string
+		wantErr        bool
+	}
+
+	baseDir, err := ioutil.TempDir("", "drophere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+
+	tests := []test{
+		{fileName: "report.pdf", slug: "drop-here", content: "first", wantStoredPath: "/drophere/drop-here/report.pdf"},
+		{fileName: "report.pdf", slug: "drop-here", content: "second", wantStoredPath: "/drophere/drop-here/report (1).pdf"},
+		{fileName: "../../etc/passwd", slug: "drop-here", content: "third", wantStoredPath: "/drophere/drop-here/passwd"},
+		{fileName: `C:\Users\me\cv.pdf`, slug: "drop-here", content: "fourth", wantStoredPath: "/drophere/drop-here/cv.pdf"},
+		{fileName: "cv.pdf", slug: "../escape", content: "fifth", wantStoredPath: "/drophere/escape/cv.pdf"},
+		{fileName: "report<1>?.pdf. ", slug: "drop-here", content: "sixth", wantStoredPath: "/drophere/drop-here/report_1__.pdf"},
+		{fileName: strings.Repeat("a", 300) + ".pdf", slug: "drop-here", content: "seventh", wantStoredPath: "/drophere/drop-here/" + strings.Repeat("a", 236) + ".pdf"},
+		{fileName: "cv.pdf", slug: "drop-here/late", content: "eighth", wantStoredPath: "/drophere/drop-here/late/cv.pdf"},
+		{fileName: "cv.pdf", slug: "../..", wantErr: true},
+		{fileName: "..", slug: "drop-here", wantErr: true},
+	}
+
+	local := storageprovider.NewLocalStorageProvider(baseDir, "drophere")
+
+	for _, tc := range tests {
+		gotStoredPath, gotErr := local.Upload(domain.StorageProviderCredential{}, strings.NewReader(tc.content), tc.fileName, tc.slug)
+		if tc.wantErr {
+			assert.NotNil(t, gotErr)
+			continue
+		}
+
+		assert.Nil(t, gotErr)
+		assert.Equal(t, tc.wantStoredPath, gotStoredPath)
+
+		content, err := ioutil.ReadFile(filepath.Join(baseDir, filepath.FromSlash(gotStoredPath)))
+		assert.Nil(t, err)
+		assert.Equal(t, tc.content, string(content))
+	}
+
+	// no temporary file is left behind
+	entries, _ := ioutil.ReadDir(filepath.Join(baseDir, "drophere", "drop-here"))
+	for _, entry := range entries {
+		assert.False(t, strings.HasPrefix(entry.Name(), ".upload-"))
+	}
+}
+
+func TestLocalDownload(t *testing.T) {
+	baseDir, err := ioutil.TempDir("", "drophere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(baseDir)
+
+	ioutil.WriteFile(filepath.Join(baseDir, "secret.txt"), []byte("secret"), 0600)
+
+	local := storageprovider.NewLocalStorageProvider(baseDir, "drophere")
+	storedPath, err := local.Upload(domain.StorageProviderCredential{}, strings.NewReader("my report"), "report.pdf", "drop-here")
+	assert.Nil(t, err)
+
+	downloader := local.(domain.StorageProviderDownloader)
+
+	file, err := downloader.Download(domain.StorageProviderCredential{}, storedPath)
+	if assert.Nil(t, err) {
+		content, _ := ioutil.ReadAll(file)
+		file.Close()
+		assert.Equal(t, "my report", string(content))
+	}
+
+	_, err = downloader.Download(domain.StorageProviderCredential{}, "/drophere/drop-here/unknown.pdf")
+	assert.Equal(t, domain.ErrStorageProviderFileNotFound, err)
+
+	_, err = downloader.Download(domain.StorageProviderCredential{}, "/drophere/../secret.txt")
+	assert.Equal(t, domain.ErrStorageProviderFileNotFound, err)
+}
@@ -1,13 +1,19 @@
 package storageprovider
 
 import (
+	"errors"
 	"io"
+	"net/url"
+	"time"
 
 	"github.com/bccfilkom/drophere-go/domain"
 )
 
 var sharedAccountInfo domain.StorageProviderAccountInfo
 
+// MockAuthorizationCode is the only authorization code accepted by mock
+const MockAuthorizationCode = "mock_authorization_code"
+
 type mock struct{}
 
 // SetSharedAccountInfo set the sharedAccountInfo object
@@ -25,12 +31,48 @@ func (m *mock) ID() uint {
 	return 1
 }
 
+// AuthorizationURL mock
+func (m *mock) AuthorizationURL(state, redirectURL string) (string, error) {
+	return "http://mock.provider/authorize?" + url.Values{
+		"state":        {state},
+		"redirect_uri": {redirectURL},
+	}.Encode(), nil
+}
+
+// ExchangeCode mock
+func (m *mock) ExchangeCode(code, redirectURL string) (domain.StorageProviderCredential, error) {
+	if code != MockAuthorizationCode {
+		return domain.StorageProviderCredential{}, errors.New("OAuth2 error: invalid code")
+	}
+
+	expiry := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return domain.StorageProviderCredential{
+		UserAccessToken: "mock_access_token",
+		RefreshToken:    "mock_refresh_token",
+		Expiry:          &expiry,
+	}, nil
+}
+
+// RefreshCredential mock
+func (m *mock) RefreshCredential(cred domain.StorageProviderCredential) (domain.StorageProviderCredential, error) {
+	if cred.RefreshToken != "mock_refresh_token" {
+		return cred, domain.ErrStorageProviderCredentialExpired
+	}
+
+	expiry := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	return domain.StorageProviderCredential{
+		UserAccessToken: "mock_refreshed_access_token",
+		RefreshToken:    cred.RefreshToken,
+		Expiry:          &expiry,
+	}, nil
+}
+
 // AccountInfo mock
 func (m *mock) AccountInfo(cred domain.StorageProviderCredential) (domain.StorageProviderAccountInfo, error) {
 	return sharedAccountInfo, nil
 }
 
 // Upload mock
-func (m *mock) Upload(cred domain.StorageProviderCredential, file io.Reader, fileName, slug string) error {
-	return nil
-}
\ No newline at end of file
+func (m *mock) Upload(cred domain.StorageProviderCredential, file io.Reader, fileName, slug string) (string, error) {
+	return "/" + slug + "/" + sanitizeFileName(fileName, `/\`, 255), nil
+}
@@ -0,0 +1,142 @@
+package storageprovider
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+var (
+	errOAuth2NotConfigured = errors.New("OAuth2 is not configured for this Storage Provider")
+	errOAuth2TokenRequest  = errors.New("The Storage Provider rejected the authorization")
+)
+
+// OAuth2Config stores the OAuth2 client registered on the storage provider.
+// AuthURL and TokenURL can be left empty to use the provider's default endpoints
+type OAuth2Config struct {
+	ClientID     string
+	ClientSecret string
+	AuthURL      string
+	TokenURL     string
+}
+
+type oauth2TokenJSON struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// withDefaults fills the empty endpoints with the given defaults
+func (c OAuth2Config) withDefaults(authURL, tokenURL string) OAuth2Config {
+	if c.AuthURL == "" {
+		c.AuthURL = authURL
+	}
+	if c.TokenURL == "" {
+		c.TokenURL = tokenURL
+	}
+	return c
+}
+
+// authCodeURL builds the URL of the provider's consent page
+func (c OAuth2Config) authCodeURL(state, redirectURL string, extraParams url.Values) (string, error) {
+	if c.ClientID == "" {
+		return "", errOAuth2NotConfigured
+	}
+
+	params := url.Values{}
+	for k, v := range extraParams {
+		params[k] = v
+	}
+	params.Set("client_id", c.ClientID)
+	params.Set("response_type", "code")
+	params.Set("redirect_uri", redirectURL)
+	params.Set("state", state)
+
+	sep := "?"
+	if strings.Contains(c.AuthURL, "?") {
+		sep = "&"
+	}
+
+	return c.AuthURL + sep + params.Encode(), nil
+}
+
+// exchangeCode trades the authorization code for the access and refresh token
+func (c OAuth2Config) exchangeCode(code, redirectURL string) (domain.StorageProviderCredential, error) {
+	return c.requestToken(url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {redirectURL},
+	})
+}
+
+// refreshCredential requests new access token using the refresh token,
+// the refresh token is kept when the provider does not rotate it
+func (c OAuth2Config) refreshCredential(cred domain.StorageProviderCredential) (domain.StorageProviderCredential, error) {
+	if cred.RefreshToken == "" {
+		return cred, domain.ErrStorageProviderCredentialExpired
+	}
+
+	newCred, err := c.requestToken(url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {cred.RefreshToken},
+	})
+	if err != nil {
+		return cred, err
+	}
+
+	if newCred.RefreshToken == "" {
+		newCred.RefreshToken = cred.RefreshToken
+	}
+
+	return newCred, nil
+}
+
+func (c OAuth2Config) requestToken(params url.Values) (domain.StorageProviderCredential, error) {
+	var cred domain.StorageProviderCredential
+
+	if c.ClientID == "" {
+		return cred, errOAuth2NotConfigured
+	}
+
+	params.Set("client_id", c.ClientID)
+	params.Set("client_secret", c.ClientSecret)
+
+	client := http.Client{
+		Timeout: 5 * time.Second,
+	}
+
+	now := time.Now()
+	res, err := client.PostForm(c.TokenURL, params)
+	if err != nil {
+		return cred, err
+	}
+	defer res.Body.Close()
+
+	byteResponse, err := io.ReadAll(res.Body)
+	if err != nil {
+		return cred, err
+	}
+
+	// the response may contain the tokens or the client details, so it is only logged
+	var token oauth2TokenJSON
+	if err = json.Unmarshal(byteResponse, &token); err != nil || res.StatusCode != http.StatusOK || token.AccessToken == "" {
+		log.Printf("oauth2 token request: status %d: %s", res.StatusCode, byteResponse)
+		return cred, errOAuth2TokenRequest
+	}
+
+	cred.UserAccessToken = token.AccessToken
+	cred.RefreshToken = token.RefreshToken
+	if token.ExpiresIn > 0 {
+		expiry := now.Add(time.Duration(token.ExpiresIn) * time.Second)
+		cred.Expiry = &expiry
+	}
+
+	return cred, nil
+}
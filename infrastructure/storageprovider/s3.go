@@ -0,0 +1,443 @@
+package storageprovider
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/infrastructure/netguard"
+)
+
+var (
+	errS3InvalidCredential = errors.New("Invalid S3 credential. Please provide the endpoint, bucket, access key ID and secret access key")
+	errS3BucketNotFound    = errors.New("S3 bucket is not found")
+	errS3AccessDenied      = errors.New("Access to the S3 bucket is denied")
+	errS3TooManyCollisions = errors.New("Too many files with the same name in the S3 bucket")
+	errS3Unknown           = errors.New("Unknown S3 error")
+)
+
+const (
+	s3ProviderID uint = 11223344
+
+	// DefaultS3UploadPartSize is the size of each part when uploading a file
+	DefaultS3UploadPartSize int64 = 8 << 20
+
+	// s3MaxUploadPartSize is the part size limit of S3 multipart upload
+	s3MaxUploadPartSize int64 = 5 << 30
+	// s3UploadTimeout is the time limit of each upload request, not the whole file
+	s3UploadTimeout = 2 * time.Minute
+	// s3DialTimeout is the time limit of connecting to the endpoint
+	s3DialTimeout = 30 * time.Second
+
+	s3DefaultRegion     = "us-east-1"
+	s3UnsignedPayload   = "UNSIGNED-PAYLOAD"
+	s3MaxRenameAttempts = 100
+	// s3InvalidFileNameChars are the characters AWS recommends to avoid in object keys
+	s3InvalidFileNameChars = "/\\{}^%`[]\"<>~#|"
+	// s3MaxFileNameLength leaves room for the remote directory, slug and rename suffix in the 1024 bytes key
+	s3MaxFileNameLength = 255
+)
+
+type s3 struct {
+	remoteDirectory string
+	config          S3Config
+	// guardedTransport is used for the endpoints which are not in AllowedHosts
+	guardedTransport http.RoundTripper
+}
+
+// S3Config holds the S3 upload settings, zero values are replaced with the defaults
+type S3Config struct {
+	// UploadPartSize is the size of each part of the multipart upload, S3 requires at least 5 MB
+	UploadPartSize int64
+	// AllowedHosts are the hosts (e.g. the internal MinIO) which may resolve to an internal address,
+	// the endpoints given by the users can not reach the server itself or the internal network otherwise
+	AllowedHosts []string
+}
+
+// s3Credential is stored as JSON in UserStorageCredential.ProviderCredential
+type s3Credential struct {
+	Endpoint        string `json:"endpoint"`
+	Region          string `json:"region"`
+	Bucket          string `json:"bucket"`
+	AccessKeyID     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+}
+
+type s3ErrorXML struct {
+	Code    string `xml:"Code"`
+	Message string `xml:"Message"`
+}
+
+type s3InitiateMultipartUploadXML struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type s3CompleteMultipartUploadXML struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// NewS3StorageProvider returns new StorageProviderService for S3-compatible object storages (e.g. MinIO).
+// The bucket is addressed using path-style URL, i.e. <endpoint>/<bucket>/<key>
+func NewS3StorageProvider(remoteDirectory string, config S3Config) domain.StorageProviderService {
+	if config.UploadPartSize <= 0 || config.UploadPartSize > s3MaxUploadPartSize {
+		config.UploadPartSize = DefaultS3UploadPartSize
+	}
+
+	return &s3{
+		remoteDirectory:  remoteDirectory,
+		config:           config,
+		guardedTransport: netguard.NewTransport(s3DialTimeout),
+	}
+}
+
+// ID returns provider ID
+func (s *s3) ID() uint {
+	return s3ProviderID
+}
+
+// AccountInfo checks whether the bucket is accessible with the given credential
+func (s *s3) AccountInfo(cred domain.StorageProviderCredential) (domain.StorageProviderAccountInfo, error) {
+	var accountInfo domain.StorageProviderAccountInfo
+
+	s3Cred, err := parseS3Credential(cred.UserAccessToken)
+	if err != nil {
+		return accountInfo, err
+	}
+
+	res, err := s.do(s3Cred, http.MethodHead, "", nil, nil, 0, 5*time.Second)
+	if err != nil {
+		return accountInfo, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return accountInfo, s.mapToRegularError(res)
+	}
+
+	endpointURL, _ := url.Parse(s3Cred.Endpoint)
+	accountInfo.Email = s3Cred.Bucket + "@" + endpointURL.Host
+
+	return accountInfo, nil
+}
+
+// Upload puts the file to <remote directory>/<slug>/<file name> in the bucket.
+// Like Dropbox's autorename, the file is renamed when the key already exists
+func (s *s3) Upload(cred domain.StorageProviderCredential, file io.Reader, fileName, slug string) (string, error) {
+	s3Cred, err := parseS3Credential(cred.UserAccessToken)
+	if err != nil {
+		return "", err
+	}
+
+	fileName = sanitizeFileName(fileName, s3InvalidFileNameChars, s3MaxFileNameLength)
+
+	key, err := s.availableKey(s3Cred, path.Join(s.remoteDirectory, slug), fileName)
+	if err != nil {
+		return "", err
+	}
+
+	// read ahead the first part to find out whether the file fits in a single request
+	part := make([]byte, s.config.UploadPartSize)
+	n, err := io.ReadFull(file, part)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
+		return s.uploadSingle(s3Cred, key, part[:n])
+	} else if err != nil {
+		return "", err
+	}
+
+	return s.uploadMultipart(s3Cred, key, part, file)
+}
+
+// uploadSingle puts the whole file content in a single request
+func (s *s3) uploadSingle(cred s3Credential, key string, content []byte) (string, error) {
+	res, err := s.do(cred, http.MethodPut, key, nil, bytes.NewReader(content), int64(len(content)), s3UploadTimeout)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return "", s.mapToRegularError(res)
+	}
+
+	return "/" + key, nil
+}
+
+// uploadMultipart starts a multipart upload, uploads the file part by part and completes
+// the upload. The upload is aborted on failure so the uploaded parts are not kept
+func (s *s3) uploadMultipart(cred s3Credential, key string, firstPart []byte, file io.Reader) (string, error) {
+	var initiate s3InitiateMultipartUploadXML
+	if err := s.doXML(cred, http.MethodPost, key, url.Values{"uploads": {""}}, nil, &initiate); err != nil {
+		return "", err
+	}
+
+	if err := s.uploadParts(cred, key, initiate.UploadID, firstPart, file); err != nil {
+		if res, abortErr := s.do(cred, http.MethodDelete, key, url.Values{"uploadId": {initiate.UploadID}}, nil, 0, 5*time.Second); abortErr == nil {
+			res.Body.Close()
+		}
+		return "", err
+	}
+
+	return "/" + key, nil
+}
+
+// uploadParts uploads the first part and the rest of the file, then completes the upload
+func (s *s3) uploadParts(cred s3Credential, key, uploadID string, firstPart []byte, file io.Reader) error {
+	complete := s3CompleteMultipartUploadXML{}
+
+	// the part buffer is reused for the rest of the file
+	part, n := firstPart, len(firstPart)
+	for partNumber := 1; n > 0; partNumber++ {
+		res, err := s.do(cred, http.MethodPut, key, url.Values{
+			"partNumber": {strconv.Itoa(partNumber)},
+			"uploadId":   {uploadID},
+		}, bytes.NewReader(part[:n]), int64(n), s3UploadTimeout)
+		if err != nil {
+			return err
+		}
+		res.Body.Close()
+
+		if res.StatusCode != http.StatusOK {
+			return s.mapToRegularError(res)
+		}
+
+		complete.Parts = append(complete.Parts, s3CompletedPart{
+			PartNumber: partNumber,
+			ETag:       res.Header.Get("ETag"),
+		})
+
+		n, err = io.ReadFull(file, part)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+	}
+
+	body, err := xml.Marshal(complete)
+	if err != nil {
+		return err
+	}
+
+	return s.doXML(cred, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, body, nil)
+}
+
+// doXML sends the XML body and decodes the XML response to v if it is not nil
+func (s *s3) doXML(cred s3Credential, method, key string, query url.Values, body []byte, v interface{}) error {
+	res, err := s.do(cred, method, key, query, bytes.NewReader(body), int64(len(body)), s3UploadTimeout)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return s.mapToRegularError(res)
+	}
+
+	if v == nil {
+		return nil
+	}
+
+	return xml.NewDecoder(res.Body).Decode(v)
+}
+
+// availableKey returns an object key which does not exist yet in the bucket,
+// appending " (n)" to the file name on collision
+func (s *s3) availableKey(cred s3Credential, dir, fileName string) (string, error) {
+	ext := path.Ext(fileName)
+	base := strings.TrimSuffix(fileName, ext)
+
+	for i := 0; i < s3MaxRenameAttempts; i++ {
+		name := fileName
+		if i > 0 {
+			name = fmt.Sprintf("%s (%d)%s", base, i, ext)
+		}
+		key := path.Join(dir, name)
+
+		res, err := s.do(cred, http.MethodHead, key, nil, nil, 0, 5*time.Second)
+		if err != nil {
+			return "", err
+		}
+		res.Body.Close()
+
+		switch res.StatusCode {
+		case http.StatusNotFound:
+			return key, nil
+		case http.StatusOK:
+			continue
+		default:
+			return "", s.mapToRegularError(res)
+		}
+	}
+
+	return "", errS3TooManyCollisions
+}
+
+// do sends a request signed with AWS Signature Version 4 to the bucket
+func (s *s3) do(cred s3Credential, method, key string, query url.Values, body io.Reader, size int64, timeout time.Duration) (*http.Response, error) {
+	endpointURL, err := url.Parse(cred.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	objectPath := "/" + cred.Bucket
+	if key != "" {
+		objectPath += "/" + key
+	}
+	endpointURL.Path = strings.TrimRight(endpointURL.Path, "/") + objectPath
+	endpointURL.RawPath = s3EscapePath(endpointURL.Path)
+	endpointURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequest(method, endpointURL.String(), body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+		req.Header.Set("Content-Type", "application/octet-stream")
+	}
+
+	signS3Request(req, cred, time.Now().UTC())
+
+	client := http.Client{
+		Timeout: timeout,
+		// S3 does not redirect the requests of the path-style URLs
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	if !s.isAllowedHost(endpointURL) {
+		client.Transport = s.guardedTransport
+	}
+
+	return client.Do(req)
+}
+
+// isAllowedHost checks whether the endpoint host, with or without the port, is in AllowedHosts
+func (s *s3) isAllowedHost(endpointURL *url.URL) bool {
+	for _, host := range s.config.AllowedHosts {
+		if strings.EqualFold(host, endpointURL.Host) || strings.EqualFold(host, endpointURL.Hostname()) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *s3) mapToRegularError(res *http.Response) error {
+	byteResponse, err := io.ReadAll(res.Body)
+	if err != nil {
+		return err
+	}
+
+	var errorXML s3ErrorXML
+	xml.Unmarshal(byteResponse, &errorXML)
+
+	switch {
+	case res.StatusCode == http.StatusNotFound && (errorXML.Code == "" || errorXML.Code == "NoSuchBucket"):
+		return errS3BucketNotFound
+	case res.StatusCode == http.StatusForbidden:
+		return errS3AccessDenied
+	case errorXML.Message != "":
+		return errors.New("S3 error: " + errorXML.Message)
+	}
+
+	// the endpoint is given by the user, so the response is not shown to them
+	log.Printf("s3 request: status %d: %s", res.StatusCode, byteResponse)
+	return errS3Unknown
+}
+
+func parseS3Credential(providerCredential string) (s3Credential, error) {
+	var cred s3Credential
+	if err := json.Unmarshal([]byte(providerCredential), &cred); err != nil {
+		return cred, errS3InvalidCredential
+	}
+
+	if cred.Endpoint == "" || cred.Bucket == "" || cred.AccessKeyID == "" || cred.SecretAccessKey == "" {
+		return cred, errS3InvalidCredential
+	}
+
+	if cred.Region == "" {
+		cred.Region = s3DefaultRegion
+	}
+
+	return cred, nil
+}
+
+// signS3Request adds AWS Signature Version 4 headers to the request.
+// The payload is not signed so the body can be streamed
+func signS3Request(req *http.Request, cred s3Credential, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", s3UnsignedPayload)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalHeaders := "host:" + req.URL.Host + "\n" +
+		"x-amz-content-sha256:" + s3UnsignedPayload + "\n" +
+		"x-amz-date:" + amzDate + "\n"
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.Query().Encode(),
+		canonicalHeaders,
+		signedHeaders,
+		s3UnsignedPayload,
+	}, "\n")
+
+	scope := date + "/" + cred.Region + "/s3/aws4_request"
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalRequestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+cred.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, cred.Region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set(
+		"Authorization",
+		"AWS4-HMAC-SHA256 Credential="+cred.AccessKeyID+"/"+scope+
+			", SignedHeaders="+signedHeaders+
+			", Signature="+signature,
+	)
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// s3EscapePath escapes every byte of the path except the unreserved characters and slashes
+func s3EscapePath(p string) string {
+	var sb strings.Builder
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || c == '/' {
+			sb.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&sb, "%%%02X", c)
+	}
+	return sb.String()
+}
@@ -0,0 +1,203 @@
+package storageprovider_test
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/infrastructure/storageprovider"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// newFakeS3 returns a minimal S3-compatible server stand-in with a single bucket
+func newFakeS3(bucket string, objects map[string]string) *httptest.Server {
+	// parts of the multipart uploads, keyed by the upload ID and the part number
+	uploads := map[string]map[string]string{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=minio_key/") ||
+			r.Header.Get("X-Amz-Date") == "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+		if parts[0] != bucket {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchBucket</Code><Message>The specified bucket does not exist</Message></Error>`))
+			return
+		}
+
+		// bucket request
+		if len(parts) == 1 {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+
+		key := parts[1]
+		query := r.URL.Query()
+		uploadID := query.Get("uploadId")
+		_, initiate := query["uploads"]
+		switch {
+		case r.Method == http.MethodHead:
+			if _, ok := objects[key]; !ok {
+				w.WriteHeader(http.StatusNotFound)
+			}
+		case r.Method == http.MethodPost && initiate:
+			uploadID = fmt.Sprintf("upload_%d", len(uploads)+1)
+			uploads[uploadID] = map[string]string{}
+			fmt.Fprintf(w, "<InitiateMultipartUploadResult><UploadId>%s</UploadId></InitiateMultipartUploadResult>", uploadID)
+		case r.Method == http.MethodPut && uploadID != "":
+			content, _ := io.ReadAll(r.Body)
+			uploads[uploadID][query.Get("partNumber")] = string(content)
+			w.Header().Set("ETag", `"etag_`+query.Get("partNumber")+`"`)
+		case r.Method == http.MethodPost && uploadID != "":
+			var complete struct {
+				Parts []struct {
+					PartNumber string
+					ETag       string
+				} `xml:"Part"`
+			}
+			xml.NewDecoder(r.Body).Decode(&complete)
+
+			var content strings.Builder
+			for _, part := range complete.Parts {
+				if part.ETag != `"etag_`+part.PartNumber+`"` {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+				content.WriteString(uploads[uploadID][part.PartNumber])
+			}
+			objects[key] = content.String()
+			delete(uploads, uploadID)
+		case r.Method == http.MethodPut:
+			content, _ := io.ReadAll(r.Body)
+			objects[key] = string(content)
+		}
+	}))
+}
+
+func s3Cred(endpoint, bucket string) domain.StorageProviderCredential {
+	b, _ := json.Marshal(map[string]string{
+		"endpoint":        endpoint,
+		"bucket":          bucket,
+		"accessKeyId":     "minio_key",
+		"secretAccessKey": "minio_secret",
+	})
+	return domain.StorageProviderCredential{UserAccessToken: string(b)}
+}
+
+func TestS3AccountInfo(t *testing.T) {
+	server := newFakeS3("submissions", map[string]string{})
+	defer server.Close()
+
+	s3 := storageprovider.NewS3StorageProvider("drophere", storageprovider.S3Config{AllowedHosts: []string{"127.0.0.1"}})
+
+	accountInfo, err := s3.AccountInfo(s3Cred(server.URL, "submissions"))
+	assert.Nil(t, err)
+	assert.Equal(t, "submissions@"+strings.TrimPrefix(server.URL, "http://"), accountInfo.Email)
+
+	_, err = s3.AccountInfo(s3Cred(server.URL, "unknown"))
+	assert.EqualError(t, err, "S3 bucket is not found")
+
+	_, err = s3.AccountInfo(domain.StorageProviderCredential{UserAccessToken: "not a json"})
+	assert.NotNil(t, err)
+}
+
+func TestS3Upload(t *testing.T) {
+	objects := map[string]string{
+		"drophere/drop-here/report.pdf": "existing file",
+	}
+	server := newFakeS3("submissions", objects)
+	defer server.Close()
+
+	s3 := storageprovider.NewS3StorageProvider("drophere", storageprovider.S3Config{AllowedHosts: []string{"127.0.0.1"}})
+	cred := s3Cred(server.URL, "submissions")
+
+	storedPath, err := s3.Upload(cred, strings.NewReader("new report"), "report.pdf", "drop-here")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/report (1).pdf", storedPath)
+
+	storedPath, err = s3.Upload(cred, strings.NewReader("newer report"), "report.pdf", "drop-here")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/report (2).pdf", storedPath)
+
+	storedPath, err = s3.Upload(cred, strings.NewReader("cv"), "my cv.pdf", "drop-here")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/my cv.pdf", storedPath)
+
+	assert.Equal(t, map[string]string{
+		"drophere/drop-here/report.pdf":     "existing file",
+		"drophere/drop-here/report (1).pdf": "new report",
+		"drophere/drop-here/report (2).pdf": "newer report",
+		"drophere/drop-here/my cv.pdf":      "cv",
+	}, objects)
+}
+
+func TestS3MultipartUpload(t *testing.T) {
+	objects := map[string]string{}
+	server := newFakeS3("submissions", objects)
+	defer server.Close()
+
+	s3 := storageprovider.NewS3StorageProvider("drophere", storageprovider.S3Config{UploadPartSize: 4, AllowedHosts: []string{"127.0.0.1"}})
+	cred := s3Cred(server.URL, "submissions")
+
+	storedPath, err := s3.Upload(cred, strings.NewReader("large report"), "report.pdf", "drop-here")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/report.pdf", storedPath)
+
+	// the file size is a multiple of the part size
+	storedPath, err = s3.Upload(cred, strings.NewReader("12345678"), "data.bin", "drop-here")
+	assert.Nil(t, err)
+	assert.Equal(t, "/drophere/drop-here/data.bin", storedPath)
+
+	assert.Equal(t, map[string]string{
+		"drophere/drop-here/report.pdf": "large report",
+		"drophere/drop-here/data.bin":   "12345678",
+	}, objects)
+}
+
+func TestS3RejectsInternalEndpoints(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	s3 := storageprovider.NewS3StorageProvider("drophere", storageprovider.S3Config{})
+
+	endpoints := []string{
+		server.URL,
+		strings.Replace(server.URL, "127.0.0.1", "localhost", 1),
+		"http://10.0.0.1:9000",
+		"http://169.254.169.254",
+	}
+	for _, endpoint := range endpoints {
+		_, err := s3.AccountInfo(s3Cred(endpoint, "submissions"))
+		if assert.NotNil(t, err, endpoint) {
+			assert.Contains(t, err.Error(), "the address is not allowed", endpoint)
+		}
+	}
+	assert.False(t, called)
+}
+
+func TestS3HidesUnknownErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte("internal admin panel"))
+	}))
+	defer server.Close()
+
+	s3 := storageprovider.NewS3StorageProvider("drophere", storageprovider.S3Config{AllowedHosts: []string{"127.0.0.1"}})
+
+	_, err := s3.AccountInfo(s3Cred(server.URL, "submissions"))
+	assert.EqualError(t, err, "Unknown S3 error")
+}
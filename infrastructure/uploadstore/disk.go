@@ -0,0 +1,263 @@
+package uploadstore
+
+import (
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+type disk struct {
+	directory string
+
+	// locks guards each upload from concurrent chunk writes
+	locksMu sync.Mutex
+	locks   map[string]*sync.Mutex
+
+	// finishing holds the uploads being forwarded to the storage provider, guarded by locksMu
+	finishing map[string]bool
+}
+
+// NewDisk returns ResumableUploadStore which keeps the partial files in a directory.
+// Each upload is stored as <id>.bin along with its state in <id>.info
+func NewDisk(directory string) (domain.ResumableUploadStore, error) {
+	if err := os.MkdirAll(directory, 0750); err != nil {
+		return nil, err
+	}
+
+	return &disk{
+		directory: directory,
+		locks:     make(map[string]*sync.Mutex),
+		finishing: make(map[string]bool),
+	}, nil
+}
+
+// Create implementation
+func (d *disk) Create(u domain.ResumableUpload) (domain.ResumableUpload, error) {
+	if !isValidID(u.ID) {
+		return u, domain.ErrResumableUploadNotFound
+	}
+
+	f, err := os.OpenFile(d.binPath(u.ID), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0640)
+	if err != nil {
+		return u, err
+	}
+	f.Close()
+
+	u.Offset = 0
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+
+	if err = d.saveInfo(u); err != nil {
+		os.Remove(d.binPath(u.ID))
+		return u, err
+	}
+
+	return u, nil
+}
+
+// Find implementation
+func (d *disk) Find(id string) (domain.ResumableUpload, error) {
+	var u domain.ResumableUpload
+
+	if !isValidID(id) {
+		return u, domain.ErrResumableUploadNotFound
+	}
+
+	b, err := ioutil.ReadFile(d.infoPath(id))
+	if os.IsNotExist(err) {
+		return u, domain.ErrResumableUploadNotFound
+	} else if err != nil {
+		return u, err
+	}
+
+	err = json.Unmarshal(b, &u)
+	return u, err
+}
+
+// WriteChunk appends the chunk to the partial file, bytes beyond the upload length are ignored
+func (d *disk) WriteChunk(id string, offset int64, chunk io.Reader) (domain.ResumableUpload, error) {
+	lock := d.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	u, err := d.Find(id)
+	if err != nil {
+		return u, err
+	}
+
+	if offset != u.Offset {
+		return u, domain.ErrResumableUploadOffsetMismatch
+	}
+
+	f, err := os.OpenFile(d.binPath(id), os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return u, err
+	}
+
+	// keep the bytes written before the connection is dropped, so the client can resume
+	n, copyErr := io.Copy(f, io.LimitReader(chunk, u.Length-u.Offset))
+	closeErr := f.Close()
+
+	u.Offset += n
+	if err = d.saveInfo(u); err != nil {
+		return u, err
+	}
+
+	if copyErr != nil {
+		return u, copyErr
+	}
+
+	return u, closeErr
+}
+
+// Open implementation
+func (d *disk) Open(id string) (io.ReadSeekCloser, error) {
+	if !isValidID(id) {
+		return nil, domain.ErrResumableUploadNotFound
+	}
+
+	f, err := os.Open(d.binPath(id))
+	if os.IsNotExist(err) {
+		return nil, domain.ErrResumableUploadNotFound
+	}
+
+	return f, err
+}
+
+// Delete implementation
+func (d *disk) Delete(id string) error {
+	if !isValidID(id) {
+		return domain.ErrResumableUploadNotFound
+	}
+
+	lock := d.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	err := os.Remove(d.infoPath(id))
+	if os.IsNotExist(err) {
+		return domain.ErrResumableUploadNotFound
+	} else if err != nil {
+		return err
+	}
+
+	d.locksMu.Lock()
+	delete(d.locks, id)
+	d.locksMu.Unlock()
+
+	return os.Remove(d.binPath(id))
+}
+
+// BeginFinish implementation
+func (d *disk) BeginFinish(id string) (domain.ResumableUpload, error) {
+	lock := d.lock(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	u, err := d.Find(id)
+	if err != nil {
+		return u, err
+	}
+
+	if !u.IsComplete() {
+		return u, domain.ErrResumableUploadOffsetMismatch
+	}
+
+	d.locksMu.Lock()
+	defer d.locksMu.Unlock()
+
+	if d.finishing[id] {
+		return u, domain.ErrResumableUploadFinishing
+	}
+	d.finishing[id] = true
+
+	return u, nil
+}
+
+// EndFinish implementation
+func (d *disk) EndFinish(id string) {
+	d.locksMu.Lock()
+	defer d.locksMu.Unlock()
+
+	delete(d.finishing, id)
+}
+
+// DeleteCreatedBefore removes abandoned uploads
+func (d *disk) DeleteCreatedBefore(t time.Time) error {
+	infoPaths, err := filepath.Glob(filepath.Join(d.directory, "*.info"))
+	if err != nil {
+		return err
+	}
+
+	for _, infoPath := range infoPaths {
+		id := strings.TrimSuffix(filepath.Base(infoPath), ".info")
+
+		u, err := d.Find(id)
+		if err != nil || !u.CreatedAt.Before(t) {
+			continue
+		}
+
+		if err = d.Delete(id); err != nil && err != domain.ErrResumableUploadNotFound {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (d *disk) saveInfo(u domain.ResumableUpload) error {
+	b, err := json.Marshal(u)
+	if err != nil {
+		return err
+	}
+
+	// write to temporary file first so a crash never leaves a broken state behind
+	tmpPath := d.infoPath(u.ID) + ".tmp"
+	if err = ioutil.WriteFile(tmpPath, b, 0640); err != nil {
+		return err
+	}
+
+	return os.Rename(tmpPath, d.infoPath(u.ID))
+}
+
+func (d *disk) lock(id string) *sync.Mutex {
+	d.locksMu.Lock()
+	defer d.locksMu.Unlock()
+
+	lock, ok := d.locks[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		d.locks[id] = lock
+	}
+	return lock
+}
+
+func (d *disk) binPath(id string) string {
+	return filepath.Join(d.directory, id+".bin")
+}
+
+func (d *disk) infoPath(id string) string {
+	return filepath.Join(d.directory, id+".info")
+}
+
+// isValidID prevents path traversal through the upload ID
+func isValidID(id string) bool {
+	if id == "" {
+		return false
+	}
+
+	for _, c := range id {
+		if !('a' <= c && c <= 'z') && !('A' <= c && c <= 'Z') && !('0' <= c && c <= '9') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+package uploadstore_test
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/infrastructure/uploadstore"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskResumableUpload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drophere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := uploadstore.NewDisk(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := store.Create(domain.ResumableUpload{
+		ID:       "abc123",
+		Length:   11,
+		Metadata: map[string]string{"filename": "hello.txt"},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, int64(0), u.Offset)
+
+	_, err = store.Create(domain.ResumableUpload{ID: "../escape", Length: 1})
+	assert.Equal(t, domain.ErrResumableUploadNotFound, err)
+
+	u, err = store.WriteChunk("abc123", 0, strings.NewReader("hello "))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(6), u.Offset)
+	assert.False(t, u.IsComplete())
+
+	_, err = store.BeginFinish("abc123")
+	assert.Equal(t, domain.ErrResumableUploadOffsetMismatch, err)
+
+	_, err = store.WriteChunk("abc123", 0, strings.NewReader("hello "))
+	assert.Equal(t, domain.ErrResumableUploadOffsetMismatch, err)
+
+	// bytes beyond the upload length are ignored
+	u, err = store.WriteChunk("abc123", 6, strings.NewReader("world!!!"))
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), u.Offset)
+	assert.True(t, u.IsComplete())
+
+	u, err = store.Find("abc123")
+	assert.Nil(t, err)
+	assert.Equal(t, "hello.txt", u.Metadata["filename"])
+
+	f, err := store.Open("abc123")
+	if assert.Nil(t, err) {
+		b, _ := ioutil.ReadAll(f)
+		f.Close()
+		assert.Equal(t, "hello world", string(b))
+	}
+
+	// only one request finishes the upload at a time
+	u, err = store.BeginFinish("abc123")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(11), u.Offset)
+	_, err = store.BeginFinish("abc123")
+	assert.Equal(t, domain.ErrResumableUploadFinishing, err)
+	store.EndFinish("abc123")
+	_, err = store.BeginFinish("abc123")
+	assert.Nil(t, err)
+	store.EndFinish("abc123")
+
+	assert.Nil(t, store.Delete("abc123"))
+	_, err = store.Find("abc123")
+	assert.Equal(t, domain.ErrResumableUploadNotFound, err)
+	assert.Equal(t, domain.ErrResumableUploadNotFound, store.Delete("abc123"))
+}
+
+func TestDiskDeleteCreatedBefore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "drophere")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	store, err := uploadstore.NewDisk(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	store.Create(domain.ResumableUpload{ID: "old", Length: 1, CreatedAt: now.Add(-48 * time.Hour)})
+	store.Create(domain.ResumableUpload{ID: "new", Length: 1, CreatedAt: now})
+
+	assert.Nil(t, store.DeleteCreatedBefore(now.Add(-24*time.Hour)))
+
+	_, err = store.Find("old")
+	assert.Equal(t, domain.ErrResumableUploadNotFound, err)
+	_, err = store.Find("new")
+	assert.Nil(t, err)
+}
@@ -0,0 +1,58 @@
+package webhooksender
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/infrastructure/netguard"
+)
+
+// maxDiscardedResponseSize limits how much of the response body is read before closing it
+const maxDiscardedResponseSize = 64 * 1024
+
+// ErrDisallowedAddress is returned when the webhook URL resolves to an internal address
+var ErrDisallowedAddress = netguard.ErrDisallowedAddress
+
+type httpSender struct {
+	client *http.Client
+}
+
+// NewHTTP returns webhook sender which POSTs the payload, the request is cancelled after the timeout.
+// The addresses are checked when connecting, after the host name is resolved and on every redirect,
+// so the webhooks can not reach the server itself or the internal network
+func NewHTTP(timeout time.Duration) domain.WebhookSender {
+	return &httpSender{
+		client: &http.Client{
+			Timeout:   timeout,
+			Transport: netguard.NewTransport(timeout),
+		},
+	}
+}
+
+// Send POSTs the body to the url
+func (s *httpSender) Send(url string, headers map[string]string, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	req.Header.Set("User-Agent", "Drophere-Webhook")
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// the response is not used, reading it lets the connection be reused
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDiscardedResponseSize))
+
+	return resp.StatusCode, nil
+}
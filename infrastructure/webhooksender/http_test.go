@@ -0,0 +1,44 @@
+package webhooksender_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/infrastructure/webhooksender"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSendRejectsInternalAddresses(t *testing.T) {
+	called := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+	defer server.Close()
+
+	sender := webhooksender.NewHTTP(time.Second)
+
+	urls := []string{
+		server.URL,
+		strings.Replace(server.URL, "127.0.0.1", "localhost", 1),
+		"http://10.0.0.1/hook",
+		"http://192.168.1.1/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"http://0.0.0.0/hook",
+		"http://0.1.2.3/hook",
+		"http://100.64.0.1/hook",
+		"http://100.127.255.254/hook",
+		"http://[::1]/hook",
+		"http://[fd00::1]/hook",
+	}
+	for _, url := range urls {
+		_, err := sender.Send(url, nil, []byte("{}"))
+		if assert.NotNil(t, err, url) {
+			assert.Contains(t, err.Error(), webhooksender.ErrDisallowedAddress.Error(), url)
+		}
+	}
+	assert.False(t, called)
+}
@@ -0,0 +1,55 @@
+package webhooksender
+
+import (
+	"sync"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// MockRequest is for testing purpose
+type MockRequest struct {
+	URL     string
+	Headers map[string]string
+	Body    string
+}
+
+// MockRequests is an in-memory storage for testing purpose
+var MockRequests []MockRequest
+
+var (
+	// mockMu guards MockRequests since the deliveries are sent concurrently
+	mockMu           sync.Mutex
+	presetStatusCode = 200
+	presetErr        error
+)
+
+func init() {
+	MockRequests = make([]MockRequest, 0)
+}
+
+// ClearRequests reset the MockRequests
+func ClearRequests() {
+	MockRequests = make([]MockRequest, 0)
+}
+
+// SetMockResult set the status code and error that Send returns
+func SetMockResult(statusCode int, err error) {
+	presetStatusCode = statusCode
+	presetErr = err
+}
+
+type mockSender struct{}
+
+// NewMock returns new mockSender instance
+func NewMock() domain.WebhookSender {
+	return &mockSender{}
+}
+
+// Send records the request and returns the pre-set result
+func (m *mockSender) Send(url string, headers map[string]string, body []byte) (int, error) {
+	mockMu.Lock()
+	defer mockMu.Unlock()
+
+	MockRequests = append(MockRequests, MockRequest{url, headers, string(body)})
+	return presetStatusCode, presetErr
+}
@@ -6,20 +6,130 @@ import (
 	"time"
 )
 
+type CreatedPersonalAccessToken struct {
+	Token               string               `json:"token"`
+	PersonalAccessToken *PersonalAccessToken `json:"personalAccessToken"`
+}
+
+type FailureReason struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+type FormAnswer struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type FormField struct {
+	Name          string   `json:"name"`
+	Label         string   `json:"label"`
+	Type          string   `json:"type"`
+	Required      bool     `json:"required"`
+	Options       []string `json:"options"`
+	UseInFileName bool     `json:"useInFileName"`
+}
+
+type FormFieldInput struct {
+	Name          string   `json:"name"`
+	Label         *string  `json:"label"`
+	Type          string   `json:"type"`
+	Required      *bool    `json:"required"`
+	Options       []string `json:"options"`
+	UseInFileName *bool    `json:"useInFileName"`
+}
+
 type Link struct {
-	ID              int              `json:"id"`
-	Title           string           `json:"title"`
-	IsProtected     bool             `json:"isProtected"`
-	Slug            *string          `json:"slug"`
-	Description     *string          `json:"description"`
-	Deadline        *time.Time       `json:"deadline"`
-	StorageProvider *StorageProvider `json:"storageProvider"`
+	ID                 int              `json:"id"`
+	Title              string           `json:"title"`
+	IsProtected        bool             `json:"isProtected"`
+	Slug               *string          `json:"slug"`
+	Description        *string          `json:"description"`
+	Deadline           *time.Time       `json:"deadline"`
+	OpensAt            *time.Time       `json:"opensAt"`
+	Status             string           `json:"status"`
+	StorageProvider    *StorageProvider `json:"storageProvider"`
+	MaxFileSize        int              `json:"maxFileSize"`
+	AllowedExtensions  []string         `json:"allowedExtensions"`
+	AllowedMimeTypes   []string         `json:"allowedMimeTypes"`
+	NotificationMode   string           `json:"notificationMode"`
+	FormFields         []*FormField     `json:"formFields"`
+	FileNameTemplate   string           `json:"fileNameTemplate"`
+	LatePolicy         string           `json:"latePolicy"`
+	GracePeriodMinutes int              `json:"gracePeriodMinutes"`
+	LateSubfolder      bool             `json:"lateSubfolder"`
+}
+
+type LinkStats struct {
+	From              time.Time          `json:"from"`
+	To                time.Time          `json:"to"`
+	BucketSize        string             `json:"bucketSize"`
+	Buckets           []*LinkStatsBucket `json:"buckets"`
+	Views             int                `json:"views"`
+	UploadAttempts    int                `json:"uploadAttempts"`
+	UploadsSucceeded  int                `json:"uploadsSucceeded"`
+	UploadsFailed     int                `json:"uploadsFailed"`
+	BytesReceived     int                `json:"bytesReceived"`
+	TopFailureReasons []*FailureReason   `json:"topFailureReasons"`
+}
+
+type LinkStatsBucket struct {
+	Start            time.Time `json:"start"`
+	Views            int       `json:"views"`
+	UploadAttempts   int       `json:"uploadAttempts"`
+	UploadsSucceeded int       `json:"uploadsSucceeded"`
+	UploadsFailed    int       `json:"uploadsFailed"`
+	BytesReceived    int       `json:"bytesReceived"`
 }
 
 type Message struct {
 	Message string `json:"message"`
 }
 
+type PersonalAccessToken struct {
+	ID         int        `json:"id"`
+	Name       string     `json:"name"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  time.Time  `json:"expiresAt"`
+	LastUsedAt *time.Time `json:"lastUsedAt"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+type Receipt struct {
+	ReceiptID  string    `json:"receiptId"`
+	LinkTitle  string    `json:"linkTitle"`
+	LinkSlug   string    `json:"linkSlug"`
+	FileName   string    `json:"fileName"`
+	Size       int       `json:"size"`
+	Sha256     string    `json:"sha256"`
+	UploadedAt time.Time `json:"uploadedAt"`
+}
+
+type RosterEntry struct {
+	Identifier string `json:"identifier"`
+	Name       string `json:"name"`
+	Email      string `json:"email"`
+}
+
+type RosterEntryStatus struct {
+	Identifier      string     `json:"identifier"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Status          string     `json:"status"`
+	SubmittedAt     *time.Time `json:"submittedAt"`
+	SubmissionCount int        `json:"submissionCount"`
+}
+
+type Session struct {
+	ID         int       `json:"id"`
+	UserAgent  string    `json:"userAgent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"createdAt"`
+	LastUsedAt time.Time `json:"lastUsedAt"`
+	ExpiresAt  time.Time `json:"expiresAt"`
+	Current    bool      `json:"current"`
+}
+
 type StorageProvider struct {
 	ID         int    `json:"id"`
 	ProviderID int    `json:"providerId"`
@@ -27,8 +137,36 @@ type StorageProvider struct {
 	Photo      string `json:"photo"`
 }
 
+type StorageProviderAuthorization struct {
+	AuthorizeURL string `json:"authorizeUrl"`
+}
+
+type Submission struct {
+	ID              int           `json:"id"`
+	LinkID          int           `json:"linkId"`
+	FileName        string        `json:"fileName"`
+	StoredPath      string        `json:"storedPath"`
+	Size            int           `json:"size"`
+	ContentType     string        `json:"contentType"`
+	UploaderName    string        `json:"uploaderName"`
+	UploaderEmail   string        `json:"uploaderEmail"`
+	UploaderIP      string        `json:"uploaderIp"`
+	ProviderID      int           `json:"providerId"`
+	Status          string        `json:"status"`
+	CreatedAt       time.Time     `json:"createdAt"`
+	ReceiptID       string        `json:"receiptId"`
+	Sha256          string        `json:"sha256"`
+	FormAnswers     []*FormAnswer `json:"formAnswers"`
+	Identifier      string        `json:"identifier"`
+	Late            bool          `json:"late"`
+	LatenessSeconds int           `json:"latenessSeconds"`
+}
+
 type Token struct {
-	LoginToken string `json:"loginToken"`
+	LoginToken         string     `json:"loginToken"`
+	Expiry             *time.Time `json:"expiry"`
+	RefreshToken       string     `json:"refreshToken"`
+	RefreshTokenExpiry *time.Time `json:"refreshTokenExpiry"`
 }
 
 type User struct {
@@ -39,4 +177,27 @@ type User struct {
 	DropboxEmail              *string            `json:"dropboxEmail"`
 	DropboxAvatar             *string            `json:"dropboxAvatar"`
 	ConnectedStorageProviders []*StorageProvider `json:"connectedStorageProviders"`
+	EmailVerified             bool               `json:"emailVerified"`
+}
+
+type Webhook struct {
+	ID        int       `json:"id"`
+	URL       string    `json:"url"`
+	Events    []string  `json:"events"`
+	Secret    string    `json:"secret"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type WebhookDelivery struct {
+	ID             int        `json:"id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	ResponseStatus int        `json:"responseStatus"`
+	Error          string     `json:"error"`
+	NextAttemptAt  *time.Time `json:"nextAttemptAt"`
+	DeliveredAt    *time.Time `json:"deliveredAt"`
+	CreatedAt      time.Time  `json:"createdAt"`
 }
@@ -5,6 +5,9 @@ package drophere_go
 import (
 	"context"
 	"errors"
+	"log"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/bccfilkom/drophere-go/domain"
@@ -17,13 +20,22 @@ var (
 
 type authenticator interface {
 	GetAuthenticatedUser(context.Context) *domain.User
+	GetAuthenticatedSessionID(context.Context) uint
+	GetAuthenticatedAccessToken(context.Context) *domain.AccessToken
 }
 
 // Resolver resolves given query from client
 type Resolver struct {
-	linkSvc       domain.LinkService
-	userSvc       domain.UserService
-	authenticator authenticator
+	linkSvc            domain.LinkService
+	userSvc            domain.UserService
+	submissionSvc      domain.SubmissionService
+	rosterSvc          domain.RosterService
+	notificationSvc    domain.NotificationService
+	webhookSvc         domain.WebhookService
+	analyticsSvc       domain.AnalyticsService
+	passwordAttemptSvc domain.PasswordAttemptService
+	accessTokenSvc     domain.AccessTokenService
+	authenticator      authenticator
 }
 
 // NewResolver func
@@ -31,11 +43,25 @@ func NewResolver(
 	userSvc domain.UserService,
 	authenticator authenticator,
 	linkSvc domain.LinkService,
+	submissionSvc domain.SubmissionService,
+	rosterSvc domain.RosterService,
+	notificationSvc domain.NotificationService,
+	webhookSvc domain.WebhookService,
+	analyticsSvc domain.AnalyticsService,
+	passwordAttemptSvc domain.PasswordAttemptService,
+	accessTokenSvc domain.AccessTokenService,
 ) *Resolver {
 	return &Resolver{
-		linkSvc:       linkSvc,
-		userSvc:       userSvc,
-		authenticator: authenticator,
+		linkSvc:            linkSvc,
+		userSvc:            userSvc,
+		submissionSvc:      submissionSvc,
+		rosterSvc:          rosterSvc,
+		notificationSvc:    notificationSvc,
+		webhookSvc:         webhookSvc,
+		analyticsSvc:       analyticsSvc,
+		passwordAttemptSvc: passwordAttemptSvc,
+		accessTokenSvc:     accessTokenSvc,
+		authenticator:      authenticator,
 	}
 }
 
@@ -58,20 +84,63 @@ func (r *mutationResolver) Register(ctx context.Context, email string, password
 		return nil, err
 	}
 
-	userCreds, err := r.userSvc.Auth(user.Email, password)
+	// the user can ask for another verification email when this one fails
+	if err = r.userSvc.RequestEmailVerification(user.ID); err != nil {
+		log.Println("send email verification: ", err)
+	}
+
+	userCreds, err := r.userSvc.Auth(user.Email, password, sessionDeviceFromContext(ctx))
 	if err != nil {
 		return nil, err
 	}
-	return &Token{LoginToken: userCreds.Token}, nil
+	return formatToken(userCreds), nil
 }
 
 // Login resolver
 func (r *mutationResolver) Login(ctx context.Context, email string, password string) (*Token, error) {
-	userCreds, err := r.userSvc.Auth(email, password)
+	userCreds, err := r.userSvc.Auth(email, password, sessionDeviceFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	return formatToken(userCreds), nil
+}
+
+// RefreshToken resolver
+func (r *mutationResolver) RefreshToken(ctx context.Context, refreshToken string) (*Token, error) {
+	userCreds, err := r.userSvc.RefreshSession(refreshToken, sessionDeviceFromContext(ctx))
 	if err != nil {
 		return nil, err
 	}
-	return &Token{LoginToken: userCreds.Token}, nil
+	return formatToken(userCreds), nil
+}
+
+// Logout resolver
+func (r *mutationResolver) Logout(ctx context.Context) (*Message, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	err := r.userSvc.Logout(user.ID, r.authenticator.GetAuthenticatedSessionID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{Message: "You have been logged out"}, nil
+}
+
+// LogoutAllSessions resolver
+func (r *mutationResolver) LogoutAllSessions(ctx context.Context) (*Message, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	if err := r.userSvc.LogoutAllSessions(user.ID); err != nil {
+		return nil, err
+	}
+
+	return &Message{Message: "All sessions have been logged out"}, nil
 }
 
 // RequestPasswordRecovery resolver
@@ -91,12 +160,35 @@ func (r *mutationResolver) RecoverPassword(ctx context.Context, email, recoverTo
 		return nil, err
 	}
 
-	userCreds, err := r.userSvc.Auth(email, newPassword)
+	userCreds, err := r.userSvc.Auth(email, newPassword, sessionDeviceFromContext(ctx))
 	if err != nil {
 		return nil, err
 	}
 
-	return &Token{LoginToken: userCreds.Token}, nil
+	return formatToken(userCreds), nil
+}
+
+// VerifyEmail resolver
+func (r *mutationResolver) VerifyEmail(ctx context.Context, email string, verifyToken string) (*Message, error) {
+	if err := r.userSvc.VerifyEmail(email, verifyToken); err != nil {
+		return nil, err
+	}
+
+	return &Message{Message: "Your email has been verified"}, nil
+}
+
+// ResendVerification resolver
+func (r *mutationResolver) ResendVerification(ctx context.Context) (*Message, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	if err := r.userSvc.RequestEmailVerification(user.ID); err != nil {
+		return nil, err
+	}
+
+	return &Message{Message: "Verification email has been sent to your email"}, nil
 }
 
 // UpdatePassword resolver
@@ -111,7 +203,7 @@ func (r *mutationResolver) UpdatePassword(ctx context.Context, oldPassword strin
 		return nil, err
 	}
 
-	return &Message{Message: "You password successfully updated"}, nil
+	return &Message{Message: "You password successfully updated, please login again"}, nil
 }
 
 // UpdateProfile resolver
@@ -130,7 +222,7 @@ func (r *mutationResolver) UpdateProfile(ctx context.Context, newName string) (*
 }
 
 // CreateLink resolver
-func (r *mutationResolver) CreateLink(ctx context.Context, title string, slug string, description *string, deadline *time.Time, password *string, providerID *int) (*Link, error) {
+func (r *mutationResolver) CreateLink(ctx context.Context, title string, slug string, description *string, deadline *time.Time, opensAt *time.Time, password *string, providerID *int, maxFileSize *int, allowedExtensions []string, allowedMimeTypes []string, notificationMode *string, formFields []*FormFieldInput, fileNameTemplate *string, latePolicy *string, gracePeriodMinutes *int, lateSubfolder *bool) (*Link, error) {
 	user := r.authenticator.GetAuthenticatedUser(ctx)
 	if user == nil {
 		return nil, errUnauthenticated
@@ -147,16 +239,40 @@ func (r *mutationResolver) CreateLink(ctx context.Context, title string, slug st
 		providerIDUintPtr = &providerIDUint
 	}
 
-	l, err := r.linkSvc.CreateLink(title, slug, desc, deadline, password, user, providerIDUintPtr)
+	l, err := r.linkSvc.CreateLink(
+		title,
+		slug,
+		desc,
+		deadline,
+		opensAt,
+		password,
+		user,
+		providerIDUintPtr,
+		linkSettings(
+			maxFileSize,
+			allowedExtensions,
+			allowedMimeTypes,
+			notificationMode,
+			formFields,
+			fileNameTemplate,
+			latePolicy,
+			gracePeriodMinutes,
+			lateSubfolder,
+		),
+	)
 	if err != nil {
 		return nil, err
 	}
 
+	if err = r.webhookSvc.DispatchLinkEvent(domain.WebhookEventLinkCreated, l); err != nil {
+		log.Println("dispatch link event: ", err)
+	}
+
 	return formatLink(*l), nil
 }
 
 // UpdateLink resolver
-func (r *mutationResolver) UpdateLink(ctx context.Context, linkID int, title string, slug string, description *string, deadline *time.Time, password *string, providerID *int) (*Link, error) {
+func (r *mutationResolver) UpdateLink(ctx context.Context, linkID int, title string, slug string, description *string, deadline *time.Time, opensAt *time.Time, password *string, providerID *int, maxFileSize *int, allowedExtensions []string, allowedMimeTypes []string, notificationMode *string, formFields []*FormFieldInput, fileNameTemplate *string, latePolicy *string, gracePeriodMinutes *int, lateSubfolder *bool) (*Link, error) {
 	user := r.authenticator.GetAuthenticatedUser(ctx)
 	if user == nil {
 		return nil, errUnauthenticated
@@ -183,8 +299,20 @@ func (r *mutationResolver) UpdateLink(ctx context.Context, linkID int, title str
 		slug,
 		description,
 		deadline,
+		opensAt,
 		password,
 		providerIDUintPtr,
+		linkSettings(
+			maxFileSize,
+			allowedExtensions,
+			allowedMimeTypes,
+			notificationMode,
+			formFields,
+			fileNameTemplate,
+			latePolicy,
+			gracePeriodMinutes,
+			lateSubfolder,
+		),
 	)
 
 	if err != nil {
@@ -215,6 +343,10 @@ func (r *mutationResolver) DeleteLink(ctx context.Context, linkID int) (*Message
 		return nil, err
 	}
 
+	if err = r.webhookSvc.DispatchLinkEvent(domain.WebhookEventLinkDeleted, l); err != nil {
+		log.Println("dispatch link event: ", err)
+	}
+
 	return &Message{Message: "Link Deleted!"}, nil
 }
 
@@ -226,12 +358,85 @@ func (r *mutationResolver) CheckLinkPassword(ctx context.Context, linkID int, pa
 		return nil, err
 	}
 
-	msg := "Invalid Password"
-	if r.linkSvc.CheckLinkPassword(l, password) {
-		msg = "Valid Password"
+	valid, err := r.checkLinkPassword(ctx, l, password)
+	if err != nil {
+		return nil, err
+	}
+
+	if !valid {
+		return &Message{Message: "Invalid Password"}, nil
+	}
+
+	return &Message{Message: "Valid Password"}, nil
+}
+
+// checkLinkPassword checks the password of protected link, the client is locked out
+// for a while after entering too many wrong passwords
+func (r *mutationResolver) checkLinkPassword(ctx context.Context, l *domain.Link, password string) (bool, error) {
+	if !l.IsProtected() {
+		return true, nil
+	}
+
+	clientIP := ClientIPFromContext(ctx)
+	if err := r.passwordAttemptSvc.Attempt(l, clientIP, time.Now()); err != nil {
+		return false, err
+	}
+
+	if !r.linkSvc.CheckLinkPassword(l, password) {
+		return false, nil
+	}
+
+	if err := r.passwordAttemptSvc.RecordSuccess(l, clientIP); err != nil {
+		log.Println("record password success: ", err)
+	}
+
+	return true, nil
+}
+
+// SubscribeReminder resolver
+func (r *mutationResolver) SubscribeReminder(ctx context.Context, linkID int, email string, name *string, password *string) (*Message, error) {
+	// this is for public use, no need to check user auth
+	l, err := r.linkSvc.FetchLink(uint(linkID))
+	if err != nil {
+		return nil, err
+	}
+
+	// only the uploaders who can upload to the link are reminded
+	linkPassword := ""
+	if password != nil {
+		linkPassword = *password
+	}
+
+	valid, err := r.checkLinkPassword(ctx, l, linkPassword)
+	if err != nil {
+		return nil, err
 	}
 
-	return &Message{Message: msg}, nil
+	if !valid {
+		return nil, domain.ErrLinkInvalidPassword
+	}
+
+	subscriberName := ""
+	if name != nil {
+		subscriberName = *name
+	}
+
+	err = r.notificationSvc.SubscribeReminder(l, email, subscriberName)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Message{Message: "Please confirm the reminders from your email"}, nil
+}
+
+// ConfirmReminderSubscription resolver
+func (r *mutationResolver) ConfirmReminderSubscription(ctx context.Context, token string) (*Message, error) {
+	// this is for public use, no need to check user auth
+	if err := r.notificationSvc.ConfirmReminderSubscription(token); err != nil {
+		return nil, err
+	}
+
+	return &Message{Message: "You will be reminded before the deadline"}, nil
 }
 
 // ConnectStorageProvider resolver
@@ -249,6 +454,21 @@ func (r *mutationResolver) ConnectStorageProvider(ctx context.Context, providerI
 	return &Message{Message: "Storage Provider successfully connected"}, nil
 }
 
+// StartStorageProviderAuthorization resolver
+func (r *mutationResolver) StartStorageProviderAuthorization(ctx context.Context, providerID int) (*StorageProviderAuthorization, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	authorizeURL, err := r.userSvc.StartStorageProviderAuthorization(user.ID, uint(providerID))
+	if err != nil {
+		return nil, err
+	}
+
+	return &StorageProviderAuthorization{AuthorizeURL: authorizeURL}, nil
+}
+
 // DisconnectStorageProvider resolver
 func (r *mutationResolver) DisconnectStorageProvider(ctx context.Context, providerID int) (*Message, error) {
 	user := r.authenticator.GetAuthenticatedUser(ctx)
@@ -264,6 +484,144 @@ func (r *mutationResolver) DisconnectStorageProvider(ctx context.Context, provid
 	return &Message{Message: "Storage Provider disconnected"}, nil
 }
 
+// ImportRoster resolver
+func (r *mutationResolver) ImportRoster(ctx context.Context, linkID int, csv string) ([]*RosterEntry, error) {
+	l, err := r.fetchOwnedLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := r.rosterSvc.ImportRoster(l, strings.NewReader(csv))
+	if err != nil {
+		return nil, err
+	}
+
+	formattedEntries := make([]*RosterEntry, len(entries))
+	for i, entry := range entries {
+		formattedEntries[i] = &RosterEntry{
+			Identifier: entry.Identifier,
+			Name:       entry.Name,
+			Email:      entry.Email,
+		}
+	}
+
+	return formattedEntries, nil
+}
+
+// CreateWebhook resolver
+func (r *mutationResolver) CreateWebhook(ctx context.Context, url string, events []string) (*Webhook, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	w, err := r.webhookSvc.CreateWebhook(user, url, events)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatWebhook(*w), nil
+}
+
+// UpdateWebhook resolver
+func (r *mutationResolver) UpdateWebhook(ctx context.Context, webhookID int, url *string, events []string, active *bool) (*Webhook, error) {
+	w, err := r.fetchOwnedWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	w, err = r.webhookSvc.UpdateWebhook(w.ID, url, events, active)
+	if err != nil {
+		return nil, err
+	}
+
+	return formatWebhook(*w), nil
+}
+
+// DeleteWebhook resolver
+func (r *mutationResolver) DeleteWebhook(ctx context.Context, webhookID int) (*Message, error) {
+	w, err := r.fetchOwnedWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = r.webhookSvc.DeleteWebhook(w.ID); err != nil {
+		return nil, err
+	}
+
+	return &Message{Message: "Webhook Deleted!"}, nil
+}
+
+// CreatePersonalAccessToken resolver
+func (r *mutationResolver) CreatePersonalAccessToken(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*CreatedPersonalAccessToken, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	t, token, err := r.accessTokenSvc.CreateAccessToken(user, name, scopes, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CreatedPersonalAccessToken{
+		Token:               token,
+		PersonalAccessToken: formatAccessToken(*t),
+	}, nil
+}
+
+// RevokePersonalAccessToken resolver
+func (r *mutationResolver) RevokePersonalAccessToken(ctx context.Context, tokenID int) (*Message, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	if err := r.accessTokenSvc.RevokeAccessToken(user.ID, uint(tokenID)); err != nil {
+		return nil, err
+	}
+
+	return &Message{Message: "Personal Access Token Revoked!"}, nil
+}
+
+// fetchOwnedLink returns the link if it belongs to the authenticated user
+func (r *Resolver) fetchOwnedLink(ctx context.Context, linkID int) (*domain.Link, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	l, err := r.linkSvc.FetchLink(uint(linkID))
+	if err != nil {
+		return nil, err
+	}
+
+	if l.UserID != user.ID {
+		return nil, errUnauthorized
+	}
+
+	return l, nil
+}
+
+// fetchOwnedWebhook returns the webhook if it belongs to the authenticated user
+func (r *Resolver) fetchOwnedWebhook(ctx context.Context, webhookID int) (*domain.Webhook, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	w, err := r.webhookSvc.FetchWebhook(uint(webhookID))
+	if err != nil {
+		return nil, err
+	}
+
+	if w.UserID != user.ID {
+		return nil, errUnauthorized
+	}
+
+	return w, nil
+}
+
 type queryResolver struct{ *Resolver }
 
 // Links resolver
@@ -309,6 +667,7 @@ func (r *queryResolver) Me(ctx context.Context) (*User, error) {
 		Email:                     user.Email,
 		Name:                      user.Name,
 		ConnectedStorageProviders: storageProviders,
+		EmailVerified:             user.IsEmailVerified(),
 	}, nil
 }
 
@@ -320,7 +679,304 @@ func (r *queryResolver) Link(ctx context.Context, slug string) (*Link, error) {
 		return nil, err
 	}
 
-	return formatLink(*link), nil
+	formattedLink := formatLink(*link)
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	isOwner := user != nil && user.ID == link.UserID
+
+	// the content may be secret until the link opens, e.g. exam questions
+	if formattedLink.Status == domain.LinkStatusNotYetOpen && !isOwner {
+		formattedLink.Description = nil
+		formattedLink.FormFields = []*FormField{}
+	}
+
+	// the owner's own visits are not counted
+	if !isOwner {
+		if err = r.analyticsSvc.RecordView(link); err != nil {
+			log.Println("record link view: ", err)
+		}
+	}
+
+	return formattedLink, nil
+}
+
+// Submissions resolver
+func (r *queryResolver) Submissions(ctx context.Context, linkID int) ([]*Submission, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	l, err := r.linkSvc.FetchLink(uint(linkID))
+	if err != nil {
+		return nil, err
+	}
+
+	if l.UserID != user.ID {
+		return nil, errUnauthorized
+	}
+
+	submissions, err := r.submissionSvc.ListSubmissions(l.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	formattedSubmissions := make([]*Submission, len(submissions))
+	for i, s := range submissions {
+		formattedSubmissions[i] = &Submission{
+			ID:              int(s.ID),
+			LinkID:          int(s.LinkID),
+			FileName:        s.FileName,
+			StoredPath:      s.StoredPath,
+			Size:            int(s.Size),
+			ContentType:     s.ContentType,
+			UploaderName:    s.UploaderName,
+			UploaderEmail:   s.UploaderEmail,
+			UploaderIP:      s.UploaderIP,
+			ProviderID:      int(s.ProviderID),
+			Status:          s.Status,
+			CreatedAt:       s.CreatedAt,
+			ReceiptID:       s.ReceiptID,
+			Sha256:          s.SHA256,
+			Identifier:      s.RosterIdentifier,
+			Late:            s.Late,
+			LatenessSeconds: int(s.LatenessSeconds),
+		}
+
+		formattedSubmissions[i].FormAnswers = formatFormAnswers(l.FormFields, s.FormAnswers)
+	}
+
+	return formattedSubmissions, nil
+}
+
+// VerifyReceipt resolver, it is public so the uploader can check the submission without logging in
+func (r *queryResolver) VerifyReceipt(ctx context.Context, receiptID string) (*Receipt, error) {
+	s, err := r.submissionSvc.VerifyReceipt(receiptID)
+	if err != nil {
+		return nil, err
+	}
+
+	l, err := r.linkSvc.FetchLink(s.LinkID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Receipt{
+		ReceiptID:  s.ReceiptID,
+		LinkTitle:  l.Title,
+		LinkSlug:   l.Slug,
+		FileName:   s.FileName,
+		Size:       int(s.Size),
+		Sha256:     s.SHA256,
+		UploadedAt: s.CreatedAt,
+	}, nil
+}
+
+// LinkRoster resolver
+func (r *queryResolver) LinkRoster(ctx context.Context, linkID int) ([]*RosterEntryStatus, error) {
+	l, err := r.fetchOwnedLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses, err := r.rosterSvc.RosterStatus(l)
+	if err != nil {
+		return nil, err
+	}
+
+	formattedStatuses := make([]*RosterEntryStatus, len(statuses))
+	for i, status := range statuses {
+		formattedStatuses[i] = &RosterEntryStatus{
+			Identifier:      status.Entry.Identifier,
+			Name:            status.Entry.Name,
+			Email:           status.Entry.Email,
+			Status:          status.Status,
+			SubmittedAt:     status.SubmittedAt,
+			SubmissionCount: status.SubmissionCount,
+		}
+	}
+
+	return formattedStatuses, nil
+}
+
+// LinkStats resolver
+func (r *queryResolver) LinkStats(ctx context.Context, linkID int, from *time.Time, to *time.Time) (*LinkStats, error) {
+	l, err := r.fetchOwnedLink(ctx, linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	rangeEnd := time.Now()
+	if to != nil {
+		rangeEnd = *to
+	}
+
+	rangeStart := rangeEnd.AddDate(0, 0, -30)
+	if from != nil {
+		rangeStart = *from
+	}
+
+	stats, err := r.analyticsSvc.LinkStats(l, rangeStart, rangeEnd)
+	if err != nil {
+		return nil, err
+	}
+
+	formattedStats := &LinkStats{
+		From:              stats.From,
+		To:                stats.To,
+		BucketSize:        stats.BucketSize,
+		Buckets:           make([]*LinkStatsBucket, len(stats.Buckets)),
+		Views:             stats.Views,
+		UploadAttempts:    stats.UploadAttempts,
+		UploadsSucceeded:  stats.UploadsSucceeded,
+		UploadsFailed:     stats.UploadsFailed,
+		BytesReceived:     int(stats.BytesReceived),
+		TopFailureReasons: make([]*FailureReason, len(stats.TopFailureReasons)),
+	}
+	for i, bucket := range stats.Buckets {
+		formattedStats.Buckets[i] = &LinkStatsBucket{
+			Start:            bucket.Start,
+			Views:            bucket.Views,
+			UploadAttempts:   bucket.UploadAttempts,
+			UploadsSucceeded: bucket.UploadsSucceeded,
+			UploadsFailed:    bucket.UploadsFailed,
+			BytesReceived:    int(bucket.BytesReceived),
+		}
+	}
+	for i, reason := range stats.TopFailureReasons {
+		formattedStats.TopFailureReasons[i] = &FailureReason{Reason: reason.Reason, Count: reason.Count}
+	}
+
+	return formattedStats, nil
+}
+
+// Webhooks resolver
+func (r *queryResolver) Webhooks(ctx context.Context) ([]*Webhook, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	webhooks, err := r.webhookSvc.ListWebhooks(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	formattedWebhooks := make([]*Webhook, len(webhooks))
+	for i := range webhooks {
+		formattedWebhooks[i] = formatWebhook(webhooks[i])
+	}
+
+	return formattedWebhooks, nil
+}
+
+// WebhookDeliveries resolver
+func (r *queryResolver) WebhookDeliveries(ctx context.Context, webhookID int, limit *int) ([]*WebhookDelivery, error) {
+	w, err := r.fetchOwnedWebhook(ctx, webhookID)
+	if err != nil {
+		return nil, err
+	}
+
+	deliveryLimit := 0
+	if limit != nil {
+		deliveryLimit = *limit
+	}
+
+	deliveries, err := r.webhookSvc.ListDeliveries(w.ID, deliveryLimit)
+	if err != nil {
+		return nil, err
+	}
+
+	formattedDeliveries := make([]*WebhookDelivery, len(deliveries))
+	for i, d := range deliveries {
+		formattedDeliveries[i] = &WebhookDelivery{
+			ID:             int(d.ID),
+			Event:          d.Event,
+			Payload:        d.Payload,
+			Status:         d.Status,
+			Attempts:       d.Attempts,
+			ResponseStatus: d.ResponseStatus,
+			Error:          d.LastError,
+			NextAttemptAt:  d.NextAttemptAt,
+			DeliveredAt:    d.DeliveredAt,
+			CreatedAt:      d.CreatedAt,
+		}
+	}
+
+	return formattedDeliveries, nil
+}
+
+// PersonalAccessTokens resolver
+func (r *queryResolver) PersonalAccessTokens(ctx context.Context) ([]*PersonalAccessToken, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	tokens, err := r.accessTokenSvc.ListAccessTokens(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	formattedTokens := make([]*PersonalAccessToken, len(tokens))
+	for i := range tokens {
+		formattedTokens[i] = formatAccessToken(tokens[i])
+	}
+
+	return formattedTokens, nil
+}
+
+// Sessions resolver
+func (r *queryResolver) Sessions(ctx context.Context) ([]*Session, error) {
+	user := r.authenticator.GetAuthenticatedUser(ctx)
+	if user == nil {
+		return nil, errUnauthenticated
+	}
+
+	sessions, err := r.userSvc.ListSessions(user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	currentSessionID := r.authenticator.GetAuthenticatedSessionID(ctx)
+	formattedSessions := make([]*Session, len(sessions))
+	for i, s := range sessions {
+		formattedSessions[i] = &Session{
+			ID:         int(s.ID),
+			UserAgent:  s.UserAgent,
+			IP:         s.IP,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.ID == currentSessionID,
+		}
+	}
+
+	return formattedSessions, nil
+}
+
+// formatFormAnswers follows the order of the link's form fields,
+// answers to the removed fields come last
+func formatFormAnswers(fields domain.FormFields, answers domain.FormAnswers) []*FormAnswer {
+	formattedAnswers := make([]*FormAnswer, 0, len(answers))
+	for _, field := range fields {
+		if value, ok := answers[field.Name]; ok {
+			formattedAnswers = append(formattedAnswers, &FormAnswer{Name: field.Name, Value: value})
+		}
+	}
+
+	removed := make([]string, 0)
+	for name := range answers {
+		if !fields.Has(name) {
+			removed = append(removed, name)
+		}
+	}
+	sort.Strings(removed)
+
+	for _, name := range removed {
+		formattedAnswers = append(formattedAnswers, &FormAnswer{Name: name, Value: answers[name]})
+	}
+
+	return formattedAnswers
 }
 
 func formatLink(link domain.Link) *Link {
@@ -331,6 +987,29 @@ func formatLink(link domain.Link) *Link {
 		Slug:        &link.Slug,
 		Description: &link.Description,
 		Deadline:    link.Deadline,
+		OpensAt:     link.OpensAt,
+		Status:      link.Status(time.Now()),
+
+		MaxFileSize:        int(link.MaxFileSize),
+		AllowedExtensions:  link.AllowedExtensionList(),
+		AllowedMimeTypes:   link.AllowedMimeTypeList(),
+		NotificationMode:   link.NotificationMode,
+		FormFields:         make([]*FormField, len(link.FormFields)),
+		FileNameTemplate:   link.FileNameTemplate,
+		LatePolicy:         link.LatePolicy,
+		GracePeriodMinutes: link.GracePeriodMinutes,
+		LateSubfolder:      link.LateSubfolder,
+	}
+
+	for i, field := range link.FormFields {
+		formattedLink.FormFields[i] = &FormField{
+			Name:          field.Name,
+			Label:         field.Label,
+			Type:          field.Type,
+			Required:      field.Required,
+			Options:       append([]string{}, field.Options...),
+			UseInFileName: field.UseInFileName,
+		}
 	}
 
 	if link.UserStorageCredential != nil {
@@ -347,24 +1026,88 @@ func formatLink(link domain.Link) *Link {
 
 func formatLinks(links []domain.Link) []*Link {
 	formattedLinks := make([]*Link, len(links))
-	for i, link := range links {
-		formattedLinks[i] = &Link{
-			ID:          int(link.ID),
-			Title:       link.Title,
-			IsProtected: link.IsProtected(),
-			Slug:        &links[i].Slug,
-			Description: &links[i].Description,
-			Deadline:    link.Deadline,
-		}
+	for i := range links {
+		formattedLinks[i] = formatLink(links[i])
+	}
+	return formattedLinks
+}
+
+func formatToken(creds *domain.UserCredentials) *Token {
+	return &Token{
+		LoginToken:         creds.Token,
+		Expiry:             creds.Expiry,
+		RefreshToken:       creds.RefreshToken,
+		RefreshTokenExpiry: creds.RefreshTokenExpiry,
+	}
+}
+
+func formatAccessToken(t domain.AccessToken) *PersonalAccessToken {
+	return &PersonalAccessToken{
+		ID:         int(t.ID),
+		Name:       t.Name,
+		Scopes:     t.ScopeList(),
+		ExpiresAt:  t.ExpiresAt,
+		LastUsedAt: t.LastUsedAt,
+		CreatedAt:  t.CreatedAt,
+	}
+}
+
+func formatWebhook(w domain.Webhook) *Webhook {
+	return &Webhook{
+		ID:        int(w.ID),
+		URL:       w.URL,
+		Events:    w.EventList(),
+		Secret:    w.Secret,
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt,
+	}
+}
+
+// linkSettings converts the optional link arguments, nil arguments are left untouched
+func linkSettings(
+	maxFileSize *int,
+	allowedExtensions, allowedMimeTypes []string,
+	notificationMode *string,
+	formFields []*FormFieldInput,
+	fileNameTemplate *string,
+	latePolicy *string,
+	gracePeriodMinutes *int,
+	lateSubfolder *bool,
+) domain.LinkSettings {
+	settings := domain.LinkSettings{
+		AllowedExtensions:  allowedExtensions,
+		AllowedMimeTypes:   allowedMimeTypes,
+		NotificationMode:   notificationMode,
+		FileNameTemplate:   fileNameTemplate,
+		LatePolicy:         latePolicy,
+		GracePeriodMinutes: gracePeriodMinutes,
+		LateSubfolder:      lateSubfolder,
+	}
+
+	if maxFileSize != nil {
+		maxFileSizeInt64 := int64(*maxFileSize)
+		settings.MaxFileSize = &maxFileSizeInt64
+	}
 
-		if link.UserStorageCredential != nil {
-			formattedLinks[i].StorageProvider = &StorageProvider{
-				ID:         int(link.UserStorageCredential.ID),
-				ProviderID: int(link.UserStorageCredential.ProviderID),
-				Email:      link.UserStorageCredential.Email,
-				Photo:      link.UserStorageCredential.Photo,
+	if formFields != nil {
+		settings.FormFields = make(domain.FormFields, len(formFields))
+		for i, field := range formFields {
+			settings.FormFields[i] = domain.FormField{
+				Name:    field.Name,
+				Type:    field.Type,
+				Options: field.Options,
+			}
+			if field.Label != nil {
+				settings.FormFields[i].Label = *field.Label
+			}
+			if field.Required != nil {
+				settings.FormFields[i].Required = *field.Required
+			}
+			if field.UseInFileName != nil {
+				settings.FormFields[i].UseInFileName = *field.UseInFileName
 			}
 		}
 	}
-	return formattedLinks
+
+	return settings
 }
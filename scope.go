@@ -0,0 +1,58 @@
+package drophere_go
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+var errAccessTokenScope = errors.New("The personal access token is not allowed to do this operation")
+
+// accessTokenFieldScopes maps the Query and Mutation fields to the scope required from the personal
+// access tokens, an empty scope is allowed for every token. The fields which are not listed,
+// e.g. managing the account, the sessions and the tokens, can only be used after signing in
+var accessTokenFieldScopes = map[string]string{
+	"Query.me":                             "",
+	"Query.link":                           "",
+	"Query.verifyReceipt":                  "",
+	"Query.links":                          domain.AccessTokenScopeLinksRead,
+	"Query.linkStats":                      domain.AccessTokenScopeLinksRead,
+	"Query.submissions":                    domain.AccessTokenScopeSubmissionsRead,
+	"Query.linkRoster":                     domain.AccessTokenScopeSubmissionsRead,
+	"Query.webhooks":                       domain.AccessTokenScopeWebhooks,
+	"Query.webhookDeliveries":              domain.AccessTokenScopeWebhooks,
+	"Mutation.checkLinkPassword":           "",
+	"Mutation.subscribeReminder":           "",
+	"Mutation.confirmReminderSubscription": "",
+	"Mutation.createLink":                  domain.AccessTokenScopeLinksWrite,
+	"Mutation.updateLink":                  domain.AccessTokenScopeLinksWrite,
+	"Mutation.deleteLink":                  domain.AccessTokenScopeLinksWrite,
+	"Mutation.importRoster":                domain.AccessTokenScopeLinksWrite,
+	"Mutation.createWebhook":               domain.AccessTokenScopeWebhooks,
+	"Mutation.updateWebhook":               domain.AccessTokenScopeWebhooks,
+	"Mutation.deleteWebhook":               domain.AccessTokenScopeWebhooks,
+}
+
+// AccessTokenScopeMiddleware rejects the Query and Mutation fields which the scopes of
+// the personal access token do not allow, the signed-in users are not restricted
+func (r *Resolver) AccessTokenScopeMiddleware(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	rctx := graphql.GetResolverContext(ctx)
+	if rctx == nil || (rctx.Object != "Query" && rctx.Object != "Mutation") || strings.HasPrefix(rctx.Field.Name, "__") {
+		return next(ctx)
+	}
+
+	t := r.authenticator.GetAuthenticatedAccessToken(ctx)
+	if t == nil {
+		return next(ctx)
+	}
+
+	scope, ok := accessTokenFieldScopes[rctx.Object+"."+rctx.Field.Name]
+	if !ok || (scope != "" && !t.HasScope(scope)) {
+		return nil, errAccessTokenScope
+	}
+
+	return next(ctx)
+}
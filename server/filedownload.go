@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/bccfilkom/drophere-go/domain"
+
+	"github.com/go-chi/chi"
+)
+
+type authenticator interface {
+	GetAuthenticatedUser(context.Context) *domain.User
+	GetAuthenticatedAccessToken(context.Context) *domain.AccessToken
+}
+
+func fileDownloadHandler(
+	authenticator authenticator,
+	linkSvc domain.LinkService,
+	submissionSvc domain.SubmissionService,
+	storageProviderPool domain.StorageProviderPool,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := authenticator.GetAuthenticatedUser(r.Context())
+		if user == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			writeError(w, "Access denied")
+			return
+		}
+
+		t := authenticator.GetAuthenticatedAccessToken(r.Context())
+		if t != nil && !t.HasScope(domain.AccessTokenScopeSubmissionsRead) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			writeError(w, "The personal access token is not allowed to do this operation")
+			return
+		}
+
+		submissionID, err := strconv.Atoi(chi.URLParam(r, "submissionId"))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, "Invalid Submission ID")
+			return
+		}
+
+		submission, err := submissionSvc.FetchSubmission(uint(submissionID))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			if err == domain.ErrSubmissionNotFound {
+				w.WriteHeader(http.StatusNotFound)
+				writeError(w, err.Error())
+			} else {
+				log.Println("file download: ", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, "Server Error")
+			}
+			return
+		}
+
+		l, err := linkSvc.FetchLink(submission.LinkID)
+		if err != nil {
+			log.Println("file download: ", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, "Server Error")
+			return
+		}
+
+		// only the link owner can download the submissions
+		if l.UserID != user.ID {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			writeError(w, "You are not allowed to do this operation")
+			return
+		}
+
+		storageProviderService, err := storageProviderPool.Get(submission.ProviderID)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			writeError(w, "Sorry, but the Storage Provider is unavailable at the time")
+			return
+		}
+
+		downloader, ok := storageProviderService.(domain.StorageProviderDownloader)
+		if !ok || submission.Status != domain.SubmissionStatusSucceeded {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			writeError(w, "The file is not available for download")
+			return
+		}
+
+		var creds domain.StorageProviderCredential
+		if l.UserStorageCredential != nil {
+			creds = l.UserStorageCredential.StorageProviderCredential()
+		}
+
+		file, err := downloader.Download(creds, submission.StoredPath)
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			if err == domain.ErrStorageProviderFileNotFound {
+				w.WriteHeader(http.StatusNotFound)
+				writeError(w, err.Error())
+			} else {
+				log.Println("file download: ", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, "Server Error")
+			}
+			return
+		}
+		defer file.Close()
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(
+			"attachment",
+			map[string]string{"filename": path.Base(submission.StoredPath)},
+		))
+		w.WriteHeader(http.StatusOK)
+
+		if _, err = io.Copy(w, file); err != nil && debug {
+			log.Println("file download: ", err)
+		}
+	}
+}
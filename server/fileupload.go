@@ -7,24 +7,10 @@ import (
 	"strconv"
 	"time"
 
-	"github.com/bccfilkom/drophere-go/domain"
+	drophere_go "github.com/bccfilkom/drophere-go"
 )
 
-func writeError(w http.ResponseWriter, msg string) {
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"errors": []map[string]string{
-			{
-				"message": msg,
-			},
-		},
-	})
-}
-
-func fileUploadHandler(
-	userSvc domain.UserService,
-	linkSvc domain.LinkService,
-	storageProviderPool domain.StorageProviderPool,
-) http.HandlerFunc {
+func fileUploadHandler(p *uploadProcessor) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 
@@ -34,10 +20,10 @@ func fileUploadHandler(
 			if debug {
 				log.Println("read file: ", err)
 			}
-			writeError(w, "Invalid File")
-			w.WriteHeader(http.StatusBadRequest)
+			writeUploadError(w, &uploadError{http.StatusBadRequest, "Invalid File"})
 			return
 		}
+		defer f.Close()
 
 		// get linkID
 		linkID, err := strconv.Atoi(r.FormValue("linkId"))
@@ -45,82 +31,65 @@ func fileUploadHandler(
 			if debug {
 				log.Println("parsing link ID: ", err)
 			}
-			writeError(w, "Invalid Link ID")
-			w.WriteHeader(http.StatusBadRequest)
+			writeUploadError(w, &uploadError{http.StatusBadRequest, "Invalid Link ID"})
 			return
 		}
 
-		// fetch link from database
-		l, err := linkSvc.FetchLink(uint(linkID))
+		// fetch link from database and check its password and deadline
+		l, err := p.openLink(uint(linkID), r.FormValue("password"), drophere_go.ClientIPFromContext(r.Context()))
 		if err != nil {
-			if err == domain.ErrLinkNotFound {
-				writeError(w, err.Error())
-				w.WriteHeader(http.StatusNotFound)
-			} else {
-				if debug {
-					log.Println("file upload: ", err)
-				}
-				writeError(w, "Server Error")
-				w.WriteHeader(http.StatusInternalServerError)
-			}
+			writeUploadError(w, err)
 			return
 		}
 
-		// check if the link is connected to a Storage Provider
-		if l.UserStorageCredentialID == nil || *l.UserStorageCredentialID < 1 || l.UserStorageCredential == nil {
-			writeError(w, "The link is unavailable")
-			w.WriteHeader(http.StatusServiceUnavailable)
-			return
+		// the first value of each form key is taken as the answer
+		values := make(map[string]string)
+		for key := range r.MultipartForm.Value {
+			values[key] = r.FormValue(key)
 		}
 
-		// check for password
-		if l.IsProtected() {
-			password := r.FormValue("password")
-
-			if !linkSvc.CheckLinkPassword(l, password) {
-				writeError(w, "Invalid Password")
-				w.WriteHeader(http.StatusUnprocessableEntity)
-				return
-			}
+		formAnswers, err := p.checkForm(l, values)
+		if err != nil {
+			writeUploadError(w, p.fail(l, err))
+			return
 		}
 
-		// check for deadline
-		if l.Deadline != nil && l.Deadline.Before(time.Now()) {
-			writeError(w, "Link is Expired")
-			w.WriteHeader(http.StatusForbidden)
+		rosterIdentifier, err := p.checkRoster(l, r.FormValue("identifier"), r.FormValue("uploaderEmail"))
+		if err != nil {
+			writeUploadError(w, p.fail(l, err))
 			return
 		}
 
-		storageProviderService, err := storageProviderPool.Get(l.UserStorageCredential.ProviderID)
-		if err != nil {
-			if debug {
-				log.Println("get storage provider service: ", err)
-			}
-			writeError(w, "Sorry, but the Storage Provider is unavailable at the time")
-			w.WriteHeader(http.StatusServiceUnavailable)
+		info := uploadInfo{
+			FileName:         fileHeader.Filename,
+			Size:             fileHeader.Size,
+			ContentType:      fileHeader.Header.Get("Content-Type"),
+			UploaderName:     r.FormValue("uploaderName"),
+			UploaderEmail:    r.FormValue("uploaderEmail"),
+			UploaderIP:       drophere_go.ClientIPFromContext(r.Context()),
+			FormAnswers:      formAnswers,
+			RosterIdentifier: rosterIdentifier,
+		}
+
+		// check the file size and type before sending it to the storage provider
+		if err = p.checkFile(l, f, info); err != nil {
+			writeUploadError(w, p.fail(l, err))
 			return
 		}
 
-		err = storageProviderService.Upload(
-			domain.StorageProviderCredential{
-				UserAccessToken: l.UserStorageCredential.ProviderCredential,
-			},
-			f,
-			fileHeader.Filename,
-			l.Slug,
-		)
+		submission, err := p.store(l, f, info)
 		if err != nil {
-			if debug {
-				log.Println("file upload: ", err)
-			}
-			writeError(w, "Server Error")
-			w.WriteHeader(http.StatusInternalServerError)
+			writeUploadError(w, p.fail(l, err))
 			return
 		}
 
+		// the receipt lets the uploader verify the submission later
+		w.WriteHeader(http.StatusOK)
 		json.NewEncoder(w).Encode(map[string]string{
-			"message": "File is successfully uploaded",
+			"message":    "File is successfully uploaded",
+			"receiptId":  submission.ReceiptID,
+			"sha256":     submission.SHA256,
+			"uploadedAt": submission.CreatedAt.Format(time.RFC3339),
 		})
-		w.WriteHeader(http.StatusOK)
 	}
 }
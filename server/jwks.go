@@ -0,0 +1,72 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/bccfilkom/drophere-go/infrastructure/auth"
+
+	"github.com/spf13/viper"
+)
+
+// jwtKeyConfig is an entry of jwt.keys
+type jwtKeyConfig struct {
+	ID        string `mapstructure:"id"`
+	Algorithm string `mapstructure:"algorithm"`
+	File      string `mapstructure:"file"`
+}
+
+// loadJWTKeys reads the keys from jwt.keys and signs with jwt.activeKeyId. Without configured keys,
+// the tokens are signed with the shared jwt.secret as before
+func loadJWTKeys() (*auth.KeySet, error) {
+	var keyConfigs []jwtKeyConfig
+	if err := viper.UnmarshalKey("jwt.keys", &keyConfigs); err != nil {
+		return nil, err
+	}
+
+	if len(keyConfigs) < 1 {
+		key, err := auth.NewHMACKey("", viper.GetString("jwt.signingAlgorithm"), viper.GetString("jwt.secret"))
+		if err != nil {
+			return nil, err
+		}
+		return auth.NewKeySet(key)
+	}
+
+	activeKeyID := viper.GetString("jwt.activeKeyId")
+	var active *auth.Key
+	retired := make([]*auth.Key, 0, len(keyConfigs))
+	for _, keyConfig := range keyConfigs {
+		pemBytes, err := ioutil.ReadFile(keyConfig.File)
+		if err != nil {
+			return nil, err
+		}
+
+		key, err := auth.ParsePEMKey(keyConfig.ID, keyConfig.Algorithm, pemBytes)
+		if err != nil {
+			return nil, fmt.Errorf("key %q: %s", keyConfig.ID, err)
+		}
+
+		if key.ID == activeKeyID {
+			active = key
+		} else {
+			retired = append(retired, key)
+		}
+	}
+
+	if active == nil {
+		return nil, fmt.Errorf("active key %q is not found in jwt.keys", activeKeyID)
+	}
+
+	return auth.NewKeySet(active, retired...)
+}
+
+// jwksHandler publishes the public keys so other services can verify the tokens
+func jwksHandler(authenticator *auth.JWTAuthenticator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", "public, max-age=300")
+		json.NewEncoder(w).Encode(authenticator.JWKS())
+	}
+}
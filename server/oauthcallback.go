@@ -0,0 +1,74 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/bccfilkom/drophere-go/domain"
+
+	"github.com/go-chi/chi"
+)
+
+// oauthCallbackHandler completes the storage provider authorization and
+// redirects the user back to the web app with the result
+func oauthCallbackHandler(userSvc domain.UserService, webURL string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		redirect := func(params url.Values) {
+			http.Redirect(w, r, webURL+"?"+params.Encode(), http.StatusFound)
+		}
+
+		providerID, err := strconv.Atoi(chi.URLParam(r, "provider"))
+		if err != nil {
+			redirect(url.Values{
+				"status":  {"failed"},
+				"message": {domain.ErrStorageProviderInvalid.Error()},
+			})
+			return
+		}
+
+		// the user may deny the access on the consent page
+		if providerErr := r.URL.Query().Get("error"); providerErr != "" {
+			redirect(url.Values{
+				"status":     {"failed"},
+				"providerId": {strconv.Itoa(providerID)},
+				"message":    {"Authorization is denied"},
+			})
+			return
+		}
+
+		err = userSvc.CompleteStorageProviderAuthorization(
+			uint(providerID),
+			r.URL.Query().Get("state"),
+			r.URL.Query().Get("code"),
+		)
+		if err != nil {
+			log.Println("oauth callback: ", err)
+			redirect(url.Values{
+				"status":     {"failed"},
+				"providerId": {strconv.Itoa(providerID)},
+				"message":    {oauthCallbackMessage(err)},
+			})
+			return
+		}
+
+		redirect(url.Values{
+			"status":     {"success"},
+			"providerId": {strconv.Itoa(providerID)},
+		})
+	}
+}
+
+// oauthCallbackMessage tells the user why the authorization failed, the other errors
+// may come from the storage provider's responses so they are not shown
+func oauthCallbackMessage(err error) string {
+	switch err {
+	case domain.ErrStorageProviderInvalid,
+		domain.ErrStorageProviderInvalidAuthorizationState,
+		domain.ErrStorageProviderAuthorizationUnsupported,
+		domain.ErrUserNotFound:
+		return err.Error()
+	}
+	return "Authorization failed"
+}
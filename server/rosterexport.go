@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/bccfilkom/drophere-go/domain"
+
+	"github.com/go-chi/chi"
+)
+
+func rosterExportHandler(
+	authenticator authenticator,
+	linkSvc domain.LinkService,
+	rosterSvc domain.RosterService,
+) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user := authenticator.GetAuthenticatedUser(r.Context())
+		if user == nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			writeError(w, "Access denied")
+			return
+		}
+
+		t := authenticator.GetAuthenticatedAccessToken(r.Context())
+		if t != nil && !t.HasScope(domain.AccessTokenScopeSubmissionsRead) {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			writeError(w, "The personal access token is not allowed to do this operation")
+			return
+		}
+
+		linkID, err := strconv.Atoi(chi.URLParam(r, "linkId"))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			writeError(w, "Invalid Link ID")
+			return
+		}
+
+		l, err := linkSvc.FetchLink(uint(linkID))
+		if err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			if err == domain.ErrLinkNotFound {
+				w.WriteHeader(http.StatusNotFound)
+				writeError(w, err.Error())
+			} else {
+				log.Println("roster export: ", err)
+				w.WriteHeader(http.StatusInternalServerError)
+				writeError(w, "Server Error")
+			}
+			return
+		}
+
+		// only the link owner can see the roster
+		if l.UserID != user.ID {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusForbidden)
+			writeError(w, "You are not allowed to do this operation")
+			return
+		}
+
+		// the CSV is buffered so a failure can still be reported with the proper status
+		body := &bytes.Buffer{}
+		if err = rosterSvc.ExportRosterStatus(l, body); err != nil {
+			log.Println("roster export: ", err)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			writeError(w, "Server Error")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", mime.FormatMediaType(
+			"attachment",
+			map[string]string{"filename": l.Slug + "-roster.csv"},
+		))
+		w.WriteHeader(http.StatusOK)
+		body.WriteTo(w)
+	}
+}
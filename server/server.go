@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"path/filepath"
 	"time"
 
@@ -12,14 +13,24 @@ import (
 
 	drophere_go "github.com/bccfilkom/drophere-go"
 	"github.com/bccfilkom/drophere-go/domain"
+	"github.com/bccfilkom/drophere-go/domain/accesstoken"
+	"github.com/bccfilkom/drophere-go/domain/analytics"
 	"github.com/bccfilkom/drophere-go/domain/link"
+	"github.com/bccfilkom/drophere-go/domain/notification"
+	"github.com/bccfilkom/drophere-go/domain/passwordattempt"
+	"github.com/bccfilkom/drophere-go/domain/roster"
+	"github.com/bccfilkom/drophere-go/domain/submission"
 	"github.com/bccfilkom/drophere-go/domain/user"
+	"github.com/bccfilkom/drophere-go/domain/webhook"
+	"github.com/bccfilkom/drophere-go/infrastructure/attemptstore"
 	"github.com/bccfilkom/drophere-go/infrastructure/auth"
 	"github.com/bccfilkom/drophere-go/infrastructure/database/mysql"
 	"github.com/bccfilkom/drophere-go/infrastructure/hasher"
 	"github.com/bccfilkom/drophere-go/infrastructure/mailer"
 	"github.com/bccfilkom/drophere-go/infrastructure/storageprovider"
 	"github.com/bccfilkom/drophere-go/infrastructure/stringgenerator"
+	"github.com/bccfilkom/drophere-go/infrastructure/uploadstore"
+	"github.com/bccfilkom/drophere-go/infrastructure/webhooksender"
 
 	"github.com/99designs/gqlgen/handler"
 	"github.com/go-chi/chi"
@@ -65,14 +76,15 @@ func main() {
 	userRepo := mysql.NewUserRepository(db)
 	linkRepo := mysql.NewLinkRepository(db)
 	userStorageCredRepo := mysql.NewUserStorageCredentialRepository(db)
+	submissionRepo := mysql.NewSubmissionRepository(db)
+	rosterRepo := mysql.NewRosterRepository(db)
+	reminderRepo := mysql.NewReminderRepository(db)
+	webhookRepo := mysql.NewWebhookRepository(db)
+	analyticsRepo := mysql.NewAnalyticsRepository(db)
+	sessionRepo := mysql.NewSessionRepository(db)
+	accessTokenRepo := mysql.NewAccessTokenRepository(db)
 
 	// initialize infrastructures
-	authenticator := auth.NewJWT(
-		viper.GetString("jwt.secret"),
-		time.Duration(viper.GetInt("jwt.duration"))*time.Hour,
-		viper.GetString("jwt.signingAlgorithm"),
-		userRepo,
-	)
 	bcryptHasher := hasher.NewBcryptHasher()
 	// mailtrap := mailer.NewMailtrap(
 	// 	viper.GetString("mailer.mailtrap.username"),
@@ -84,14 +96,73 @@ func main() {
 	)
 	uuidGenerator := stringgenerator.NewUUID()
 
+	accessTokenSvc := accesstoken.NewService(accessTokenRepo, bcryptHasher, uuidGenerator)
+	accessTokenDuration := 15 * time.Minute
+	if durationCfg := viper.GetDuration("jwt.accessTokenDuration"); durationCfg > 0 {
+		accessTokenDuration = durationCfg
+	}
+	jwtKeys, err := loadJWTKeys()
+	if err != nil {
+		panic(fmt.Errorf("config: %s", err))
+	}
+	authenticator := auth.NewJWT(
+		jwtKeys,
+		accessTokenDuration,
+		userRepo,
+		sessionRepo,
+		accessTokenSvc,
+	)
+
+	webhookTimeout := 10 * time.Second
+	if timeoutCfg := viper.GetDuration("app.webhook.timeout"); timeoutCfg > 0 {
+		webhookTimeout = timeoutCfg
+	}
+	webhookSender := webhooksender.NewHTTP(webhookTimeout)
+
 	remoteDirectory := "drophere"
 	if remoteDirCfg := viper.GetString("app.storageRootDirectoryName"); remoteDirCfg != "" {
 		remoteDirectory = remoteDirCfg
 	}
 
-	dropboxService := storageprovider.NewDropboxStorageProvider(remoteDirectory)
+	dropboxService := storageprovider.NewDropboxStorageProvider(
+		remoteDirectory,
+		storageprovider.DropboxConfig{
+			APIBaseURL:      viper.GetString("storageProvider.dropbox.apiBaseURL"),
+			ContentBaseURL:  viper.GetString("storageProvider.dropbox.contentBaseURL"),
+			UploadChunkSize: viper.GetInt64("storageProvider.dropbox.uploadChunkSize"),
+		},
+		storageprovider.OAuth2Config{
+			ClientID:     viper.GetString("storageProvider.dropbox.clientId"),
+			ClientSecret: viper.GetString("storageProvider.dropbox.clientSecret"),
+		},
+	)
+	googleDriveService := storageprovider.NewGoogleDriveStorageProvider(
+		remoteDirectory,
+		storageprovider.GoogleDriveConfig{
+			APIBaseURL:      viper.GetString("storageProvider.googleDrive.apiBaseURL"),
+			UploadChunkSize: viper.GetInt64("storageProvider.googleDrive.uploadChunkSize"),
+		},
+		storageprovider.OAuth2Config{
+			ClientID:     viper.GetString("storageProvider.googleDrive.clientId"),
+			ClientSecret: viper.GetString("storageProvider.googleDrive.clientSecret"),
+		},
+	)
+	s3Service := storageprovider.NewS3StorageProvider(
+		remoteDirectory,
+		storageprovider.S3Config{
+			UploadPartSize: viper.GetInt64("storageProvider.s3.uploadPartSize"),
+			AllowedHosts:   viper.GetStringSlice("storageProvider.s3.allowedHosts"),
+		},
+	)
 	storageProviderPool := domain.StorageProviderPool{}
 	storageProviderPool.Register(dropboxService)
+	storageProviderPool.Register(googleDriveService)
+	storageProviderPool.Register(s3Service)
+
+	// local file system storage is only enabled when the directory is configured
+	if localStorageDir := viper.GetString("storageProvider.local.directory"); localStorageDir != "" {
+		storageProviderPool.Register(storageprovider.NewLocalStorageProvider(localStorageDir, remoteDirectory))
+	}
 
 	basePath := viper.GetString("app.templatePath")
 	htmlTemplates, err := htmlTemplate.ParseGlob(filepath.Join(basePath, "html", "*.html"))
@@ -104,10 +175,29 @@ func main() {
 		panic(err)
 	}
 
+	// deadline reminders are sent at these durations before the deadline
+	reminderOffsets := []time.Duration{24 * time.Hour, time.Hour}
+	if offsetsCfg := viper.GetStringSlice("app.notification.reminderOffsets"); len(offsetsCfg) > 0 {
+		reminderOffsets = make([]time.Duration, 0, len(offsetsCfg))
+		for _, offsetCfg := range offsetsCfg {
+			offset, err := time.ParseDuration(offsetCfg)
+			if err != nil || offset <= 0 {
+				panic(fmt.Errorf("config: invalid reminder offset %q", offsetCfg))
+			}
+			reminderOffsets = append(reminderOffsets, offset)
+		}
+	}
+
+	// the authorization state is signed with this secret, so it must not be guessable
+	if viper.GetString("app.storageProviderAuthorization.stateSecret") == "" {
+		panic(fmt.Errorf("config: app.storageProviderAuthorization.stateSecret must not be empty"))
+	}
+
 	// initialize services
 	userSvc := user.NewService(
 		userRepo,
 		userStorageCredRepo,
+		sessionRepo,
 		authenticator,
 		sendgridMailer,
 		bcryptHasher,
@@ -116,15 +206,164 @@ func main() {
 		htmlTemplates,
 		textTemplates,
 		user.Config{
-			PasswordRecoveryTokenExpiryDuration: viper.GetInt("app.passwordRecovery.tokenExpiryDuration"),
-			RecoverPasswordWebURL:               viper.GetString("app.passwordRecovery.webURL"),
-			MailerEmail:                         viper.GetString("app.passwordRecovery.mailer.email"),
-			MailerName:                          viper.GetString("app.passwordRecovery.mailer.name"),
+			PasswordRecoveryTokenExpiryDuration:     viper.GetInt("app.passwordRecovery.tokenExpiryDuration"),
+			RecoverPasswordWebURL:                   viper.GetString("app.passwordRecovery.webURL"),
+			MailerEmail:                             viper.GetString("app.passwordRecovery.mailer.email"),
+			MailerName:                              viper.GetString("app.passwordRecovery.mailer.name"),
+			StorageProviderAuthorizationSecret:      viper.GetString("app.storageProviderAuthorization.stateSecret"),
+			StorageProviderAuthorizationCallbackURL: viper.GetString("app.storageProviderAuthorization.callbackURL"),
+			RefreshTokenDuration:                    viper.GetDuration("jwt.refreshTokenDuration"),
+			EmailVerificationTokenExpiryDuration:    viper.GetInt("app.emailVerification.tokenExpiryDuration"),
+			VerifyEmailWebURL:                       viper.GetString("app.emailVerification.webURL"),
+		},
+	)
+	linkSvc := link.NewService(
+		linkRepo,
+		userStorageCredRepo,
+		bcryptHasher,
+		link.Config{RequireVerifiedEmail: viper.GetBool("app.emailVerification.requiredForLinks")},
+	)
+	submissionSvc := submission.NewService(submissionRepo, uuidGenerator)
+	rosterSvc := roster.NewService(rosterRepo, submissionRepo)
+	analyticsSvc := analytics.NewService(analyticsRepo)
+	passwordAttemptSvc := passwordattempt.NewService(
+		attemptstore.NewMemory(),
+		passwordattempt.Config{
+			MaxAttempts: viper.GetInt("app.passwordAttempt.maxAttempts"),
+			Window:      viper.GetDuration("app.passwordAttempt.window"),
+			BaseLockout: viper.GetDuration("app.passwordAttempt.baseLockout"),
+			MaxLockout:  viper.GetDuration("app.passwordAttempt.maxLockout"),
 		},
 	)
-	linkSvc := link.NewService(linkRepo, userStorageCredRepo, bcryptHasher)
+	notificationSvc := notification.NewService(
+		linkRepo,
+		submissionRepo,
+		reminderRepo,
+		userRepo,
+		sendgridMailer,
+		uuidGenerator,
+		htmlTemplates,
+		textTemplates,
+		notification.Config{
+			MailerEmail:                viper.GetString("app.notification.mailer.email"),
+			MailerName:                 viper.GetString("app.notification.mailer.name"),
+			ReminderOffsets:            reminderOffsets,
+			ConfirmReminderWebURL:      viper.GetString("app.notification.confirmReminderWebURL"),
+			ConfirmationResendInterval: viper.GetDuration("app.notification.confirmationResendInterval"),
+		},
+	)
+
+	// send the daily upload summary at the configured hour (server local time)
+	go func() {
+		digestHour := viper.GetInt("app.notification.digestHour")
+		for {
+			now := time.Now()
+			next := time.Date(now.Year(), now.Month(), now.Day(), digestHour, 0, 0, 0, now.Location())
+			if !next.After(now) {
+				next = next.AddDate(0, 0, 1)
+			}
+			time.Sleep(time.Until(next))
+
+			if err := notificationSvc.SendDailyDigests(); err != nil {
+				log.Println("send daily digests: ", err)
+			}
+		}
+	}()
+
+	webhookSvc := webhook.NewService(
+		webhookRepo,
+		linkRepo,
+		webhookSender,
+		uuidGenerator,
+		webhook.Config{
+			MaxAttempts:    viper.GetInt("app.webhook.maxAttempts"),
+			InitialBackoff: viper.GetDuration("app.webhook.initialBackoff"),
+			MaxBackoff:     viper.GetDuration("app.webhook.maxBackoff"),
+			Concurrency:    viper.GetInt("app.webhook.concurrency"),
+		},
+	)
+
+	// remind the owners and the subscribed uploaders of the approaching deadlines
+	go func() {
+		for now := range time.Tick(time.Minute) {
+			if err := notificationSvc.SendDeadlineReminders(now); err != nil {
+				log.Println("send deadline reminders: ", err)
+			}
+		}
+	}()
+
+	// send the webhook deliveries and retry the failed ones
+	go func() {
+		for now := range time.Tick(10 * time.Second) {
+			if err := webhookSvc.DeliverDue(now); err != nil {
+				log.Println("deliver webhooks: ", err)
+			}
+		}
+	}()
+
+	// the links expiring while the server is down are not reported
+	go func() {
+		last := time.Now()
+		for now := range time.Tick(time.Minute) {
+			if err := webhookSvc.DispatchExpiredLinks(last, now); err != nil {
+				log.Println("dispatch expired links: ", err)
+			}
+			last = now
+		}
+	}()
+
+	resolver := drophere_go.NewResolver(userSvc, authenticator, linkSvc, submissionSvc, rosterSvc, notificationSvc, webhookSvc, analyticsSvc, passwordAttemptSvc, accessTokenSvc)
 
-	resolver := drophere_go.NewResolver(userSvc, authenticator, linkSvc)
+	uploadProcessor := &uploadProcessor{
+		userSvc:             userSvc,
+		linkSvc:             linkSvc,
+		submissionSvc:       submissionSvc,
+		notificationSvc:     notificationSvc,
+		rosterSvc:           rosterSvc,
+		webhookSvc:          webhookSvc,
+		analyticsSvc:        analyticsSvc,
+		passwordAttemptSvc:  passwordAttemptSvc,
+		storageProviderPool: storageProviderPool,
+	}
+
+	// partial files of resumable uploads
+	resumableUploadDir := filepath.Join(os.TempDir(), "drophere-uploads")
+	if resumableUploadDirCfg := viper.GetString("app.resumableUpload.directory"); resumableUploadDirCfg != "" {
+		resumableUploadDir = resumableUploadDirCfg
+	}
+
+	resumableUploadStore, err := uploadstore.NewDisk(resumableUploadDir)
+	if err != nil {
+		panic(err)
+	}
+
+	resumableUploadExpiry := 24 * time.Hour
+	if expiryCfg := viper.GetInt("app.resumableUpload.expiryDuration"); expiryCfg > 0 {
+		resumableUploadExpiry = time.Duration(expiryCfg) * time.Hour
+	}
+
+	// remove abandoned uploads periodically
+	go func() {
+		for range time.Tick(time.Hour) {
+			if err := resumableUploadStore.DeleteCreatedBefore(time.Now().Add(-resumableUploadExpiry)); err != nil {
+				log.Println("clean up resumable uploads: ", err)
+			}
+		}
+	}()
+
+	resumableUploadHandler := &resumableUploadHandler{
+		processor:   uploadProcessor,
+		store:       resumableUploadStore,
+		idGenerator: uuidGenerator,
+		maxSize:     viper.GetInt64("app.resumableUpload.maxSize"),
+		basePath:    "/uploads",
+	}
+
+	// the client address is only taken from the proxy headers when the request comes through these proxies
+	trustedProxies, err := drophere_go.ParseTrustedProxies(viper.GetStringSlice("app.trustedProxies"))
+	if err != nil {
+		panic(fmt.Errorf("config: %s", err))
+	}
 
 	// setup router
 	router := chi.NewRouter()
@@ -134,17 +373,27 @@ func main() {
 		AllowedOrigins:   []string{"*"},
 		AllowCredentials: true,
 		AllowedHeaders:   []string{"*"},
+		AllowedMethods:   []string{"GET", "HEAD", "POST", "PATCH", "DELETE", "OPTIONS"},
+		ExposedHeaders:   tusHeaders,
 		Debug:            debug,
 	}).Handler)
 	router.Use(authenticator.Middleware())
 	router.Use(middleware.RequestID)
-	router.Use(middleware.RealIP)
+	router.Use(drophere_go.ClientIPMiddleware(trustedProxies))
 	router.Use(middleware.Logger)
 	router.Use(middleware.Recoverer)
 
 	router.Handle("/", handler.Playground("GraphQL playground", "/query"))
-	router.Handle("/query", handler.GraphQL(drophere_go.NewExecutableSchema(drophere_go.Config{Resolvers: resolver})))
-	router.Post("/uploadfile", fileUploadHandler(userSvc, linkSvc, storageProviderPool))
+	router.Handle("/query", handler.GraphQL(
+		drophere_go.NewExecutableSchema(drophere_go.Config{Resolvers: resolver}),
+		handler.ResolverMiddleware(resolver.AccessTokenScopeMiddleware),
+	))
+	router.Post("/uploadfile", fileUploadHandler(uploadProcessor))
+	router.Mount("/uploads", resumableUploadHandler.Routes())
+	router.Get("/oauth/callback/{provider}", oauthCallbackHandler(userSvc, viper.GetString("app.storageProviderAuthorization.webURL")))
+	router.Get("/submissions/{submissionId}/download", fileDownloadHandler(authenticator, linkSvc, submissionSvc, storageProviderPool))
+	router.Get("/links/{linkId}/roster.csv", rosterExportHandler(authenticator, linkSvc, rosterSvc))
+	router.Get("/.well-known/jwks.json", jwksHandler(authenticator))
 
 	log.Printf("connect to http://localhost:%s/ for GraphQL playground", port)
 	err = http.ListenAndServe(":"+port, router)
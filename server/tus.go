@@ -0,0 +1,336 @@
+package main
+
+import (
+	"encoding/base64"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	drophere_go "github.com/bccfilkom/drophere-go"
+	"github.com/bccfilkom/drophere-go/domain"
+
+	"github.com/go-chi/chi"
+)
+
+// tusVersion is the supported version of the tus resumable upload protocol (https://tus.io)
+const tusVersion = "1.0.0"
+
+// tusHeaders must be readable by the browser for tus clients to work
+var tusHeaders = []string{
+	"Location",
+	"Tus-Resumable",
+	"Tus-Version",
+	"Tus-Extension",
+	"Tus-Max-Size",
+	"Upload-Offset",
+	"Upload-Length",
+	"Upload-Receipt-Id",
+	"Upload-Receipt-Sha256",
+}
+
+// resumableUploadHandler receives a file in several requests following the tus protocol,
+// the file is forwarded to the storage provider once every chunk is received.
+// Upload-Metadata must contain linkId and filename, and may contain filetype, password,
+// uploaderName, uploaderEmail, identifier and the form answers prefixed with formFieldPrefix
+type resumableUploadHandler struct {
+	processor   *uploadProcessor
+	store       domain.ResumableUploadStore
+	idGenerator domain.StringGenerator
+	maxSize     int64
+	basePath    string
+}
+
+// Routes returns the router of the tus endpoints
+func (h *resumableUploadHandler) Routes() chi.Router {
+	router := chi.NewRouter()
+	router.Use(h.tusResumableMiddleware)
+
+	router.Options("/", h.options)
+	router.Post("/", h.create)
+	router.Head("/{uploadId}", h.head)
+	router.Patch("/{uploadId}", h.patch)
+	router.Delete("/{uploadId}", h.delete)
+
+	return router
+}
+
+func (h *resumableUploadHandler) tusResumableMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", tusVersion)
+
+		// OPTIONS is used for version discovery, so the header is not required
+		if r.Method != http.MethodOptions && r.Header.Get("Tus-Resumable") != tusVersion {
+			w.Header().Set("Tus-Version", tusVersion)
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *resumableUploadHandler) options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Version", tusVersion)
+	w.Header().Set("Tus-Extension", "creation,termination")
+	if h.maxSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.maxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *resumableUploadHandler) create(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		writeUploadError(w, &uploadError{http.StatusBadRequest, "Invalid Upload-Length"})
+		return
+	}
+
+	if h.maxSize > 0 && length > h.maxSize {
+		writeUploadError(w, &uploadError{http.StatusRequestEntityTooLarge, "File is too large"})
+		return
+	}
+
+	metadata := parseTusMetadata(r.Header.Get("Upload-Metadata"))
+
+	linkID, err := strconv.Atoi(metadata["linkId"])
+	if err != nil {
+		writeUploadError(w, &uploadError{http.StatusBadRequest, "Invalid Link ID"})
+		return
+	}
+
+	if metadata["filename"] == "" {
+		writeUploadError(w, &uploadError{http.StatusBadRequest, "Invalid File"})
+		return
+	}
+
+	// check the link before receiving any byte, the password is not kept afterward
+	l, err := h.processor.openLink(uint(linkID), metadata["password"], drophere_go.ClientIPFromContext(r.Context()))
+	if err != nil {
+		writeUploadError(w, err)
+		return
+	}
+	delete(metadata, "password")
+
+	if _, err = h.processor.checkForm(l, metadata); err != nil {
+		writeUploadError(w, h.processor.fail(l, err))
+		return
+	}
+
+	if _, err = h.processor.checkRoster(l, metadata["identifier"], metadata["uploaderEmail"]); err != nil {
+		writeUploadError(w, h.processor.fail(l, err))
+		return
+	}
+
+	// the file type is checked once the content is received
+	err = h.processor.checkFile(l, nil, uploadInfo{FileName: metadata["filename"], Size: length})
+	if err != nil {
+		writeUploadError(w, h.processor.fail(l, err))
+		return
+	}
+
+	u, err := h.store.Create(domain.ResumableUpload{
+		ID:       h.idGenerator.Generate(),
+		Length:   length,
+		Metadata: metadata,
+	})
+	if err != nil {
+		writeUploadError(w, h.processor.fail(l, err))
+		return
+	}
+
+	if u.IsComplete() {
+		submission, err := h.finish(u, r)
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		setReceiptHeaders(w, submission)
+	}
+
+	w.Header().Set("Location", strings.TrimRight(h.basePath, "/")+"/"+u.ID)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *resumableUploadHandler) head(w http.ResponseWriter, r *http.Request) {
+	u, err := h.store.Find(chi.URLParam(r, "uploadId"))
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(u.Length, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *resumableUploadHandler) patch(w http.ResponseWriter, r *http.Request) {
+	if r.Header.Get("Content-Type") != "application/offset+octet-stream" {
+		w.WriteHeader(http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		writeUploadError(w, &uploadError{http.StatusBadRequest, "Invalid Upload-Offset"})
+		return
+	}
+
+	u, err := h.store.WriteChunk(chi.URLParam(r, "uploadId"), offset, r.Body)
+	if err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	// a completed upload is retried by sending an empty chunk at the end of the file
+	if u.IsComplete() {
+		submission, err := h.finish(u, r)
+		if err != nil {
+			writeUploadError(w, err)
+			return
+		}
+		setReceiptHeaders(w, submission)
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(u.Offset, 10))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *resumableUploadHandler) delete(w http.ResponseWriter, r *http.Request) {
+	if err := h.store.Delete(chi.URLParam(r, "uploadId")); err != nil {
+		h.writeStoreError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// finish forwards the completed file to the storage provider. The partial file is kept
+// when the storage provider fails, so the uploader can retry without sending the file again.
+// Only one request finishes the upload, the concurrent retries are rejected
+func (h *resumableUploadHandler) finish(u domain.ResumableUpload, r *http.Request) (*domain.Submission, error) {
+	u, err := h.store.BeginFinish(u.ID)
+	if err != nil {
+		return nil, h.storeError(err)
+	}
+	defer h.store.EndFinish(u.ID)
+
+	linkID, err := strconv.Atoi(u.Metadata["linkId"])
+	if err != nil {
+		return nil, &uploadError{http.StatusBadRequest, "Invalid Link ID"}
+	}
+
+	l, err := h.processor.fetchLink(uint(linkID))
+	if err != nil {
+		if _, ok := err.(*uploadError); ok {
+			h.store.Delete(u.ID)
+		}
+		return nil, err
+	}
+
+	if _, err = h.processor.checkAcceptingUploads(l, time.Now()); err != nil {
+		if _, ok := err.(*uploadError); ok {
+			h.store.Delete(u.ID)
+		}
+		return nil, h.processor.fail(l, err)
+	}
+
+	// the link settings and roster may have changed since the upload was created
+	formAnswers, err := h.processor.checkForm(l, u.Metadata)
+	var rosterIdentifier string
+	if err == nil {
+		rosterIdentifier, err = h.processor.checkRoster(l, u.Metadata["identifier"], u.Metadata["uploaderEmail"])
+	}
+	if err != nil {
+		if _, ok := err.(*uploadError); ok {
+			h.store.Delete(u.ID)
+		}
+		return nil, h.processor.fail(l, err)
+	}
+
+	file, err := h.store.Open(u.ID)
+	if err != nil {
+		return nil, h.processor.fail(l, err)
+	}
+
+	info := uploadInfo{
+		FileName:         u.Metadata["filename"],
+		Size:             u.Length,
+		ContentType:      u.Metadata["filetype"],
+		UploaderName:     u.Metadata["uploaderName"],
+		UploaderEmail:    u.Metadata["uploaderEmail"],
+		UploaderIP:       drophere_go.ClientIPFromContext(r.Context()),
+		FormAnswers:      formAnswers,
+		RosterIdentifier: rosterIdentifier,
+	}
+
+	if err = h.processor.checkFile(l, file, info); err != nil {
+		file.Close()
+		if _, ok := err.(*uploadError); ok {
+			h.store.Delete(u.ID)
+		}
+		return nil, h.processor.fail(l, err)
+	}
+
+	submission, err := h.processor.store(l, file, info)
+	file.Close()
+	if err != nil {
+		return nil, h.processor.fail(l, err)
+	}
+
+	if err = h.store.Delete(u.ID); err != nil {
+		log.Println("delete resumable upload: ", err)
+	}
+
+	return submission, nil
+}
+
+func (h *resumableUploadHandler) writeStoreError(w http.ResponseWriter, err error) {
+	writeUploadError(w, h.storeError(err))
+}
+
+// storeError maps the errors of the upload store to the HTTP responses
+func (h *resumableUploadHandler) storeError(err error) error {
+	switch err {
+	case domain.ErrResumableUploadNotFound:
+		return &uploadError{http.StatusNotFound, err.Error()}
+	case domain.ErrResumableUploadOffsetMismatch, domain.ErrResumableUploadFinishing:
+		return &uploadError{http.StatusConflict, err.Error()}
+	default:
+		return err
+	}
+}
+
+// setReceiptHeaders sends the receipt of the completed upload
+func setReceiptHeaders(w http.ResponseWriter, submission *domain.Submission) {
+	w.Header().Set("Upload-Receipt-Id", submission.ReceiptID)
+	w.Header().Set("Upload-Receipt-Sha256", submission.SHA256)
+}
+
+// parseTusMetadata decodes Upload-Metadata header, i.e. comma-separated
+// key and base64-encoded value pairs
+func parseTusMetadata(header string) map[string]string {
+	metadata := make(map[string]string)
+
+	for _, pair := range strings.Split(header, ",") {
+		fields := strings.Fields(pair)
+		if len(fields) == 0 {
+			continue
+		}
+
+		value := ""
+		if len(fields) > 1 {
+			decoded, err := base64.StdEncoding.DecodeString(fields[1])
+			if err != nil {
+				continue
+			}
+			value = string(decoded)
+		}
+
+		metadata[fields[0]] = value
+	}
+
+	return metadata
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bccfilkom/drophere-go/domain"
+)
+
+// uploadError is an upload failure whose message can be shown to the uploader
+type uploadError struct {
+	status  int
+	message string
+}
+
+func (e *uploadError) Error() string {
+	return e.message
+}
+
+// formFieldPrefix prefixes the form field names in the upload request,
+// e.g. the answer to "studentId" field is sent as "field_studentId"
+const formFieldPrefix = "field_"
+
+// uploadInfo describes the uploaded file and its uploader
+type uploadInfo struct {
+	FileName      string
+	Size          int64
+	ContentType   string
+	UploaderName  string
+	UploaderEmail string
+	UploaderIP    string
+	FormAnswers   domain.FormAnswers
+	// RosterIdentifier is the uploader's identifier matched against the link's roster
+	RosterIdentifier string
+}
+
+// uploadProcessor holds the checks and steps shared by every upload endpoint
+type uploadProcessor struct {
+	userSvc             domain.UserService
+	linkSvc             domain.LinkService
+	submissionSvc       domain.SubmissionService
+	notificationSvc     domain.NotificationService
+	rosterSvc           domain.RosterService
+	webhookSvc          domain.WebhookService
+	analyticsSvc        domain.AnalyticsService
+	passwordAttemptSvc  domain.PasswordAttemptService
+	storageProviderPool domain.StorageProviderPool
+}
+
+func writeError(w http.ResponseWriter, msg string) {
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"errors": []map[string]string{
+			{
+				"message": msg,
+			},
+		},
+	})
+}
+
+// writeUploadError responds with the upload error, other errors are hidden from the uploader
+func writeUploadError(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch e := err.(type) {
+	case *uploadError:
+		w.WriteHeader(e.status)
+		writeError(w, e.message)
+		return
+	case *domain.TooManyAttemptsError:
+		w.Header().Set("Retry-After", strconv.Itoa(e.RetryAfterSeconds()))
+		w.WriteHeader(http.StatusTooManyRequests)
+		writeError(w, e.Error())
+		return
+	}
+
+	if debug {
+		log.Println("file upload: ", err)
+	}
+	w.WriteHeader(http.StatusInternalServerError)
+	writeError(w, "Server Error")
+}
+
+// fetchLink returns the link if it is able to store files
+func (p *uploadProcessor) fetchLink(linkID uint) (*domain.Link, error) {
+	l, err := p.linkSvc.FetchLink(linkID)
+	if err != nil {
+		if err == domain.ErrLinkNotFound {
+			return nil, &uploadError{http.StatusNotFound, err.Error()}
+		}
+		return nil, err
+	}
+
+	// check if the link is connected to a Storage Provider
+	if l.UserStorageCredentialID == nil || *l.UserStorageCredentialID < 1 || l.UserStorageCredential == nil {
+		return nil, &uploadError{http.StatusServiceUnavailable, "The link is unavailable"}
+	}
+
+	return l, nil
+}
+
+// checkPassword checks the password of protected link. The client is locked out
+// for a while after entering too many wrong passwords
+func (p *uploadProcessor) checkPassword(l *domain.Link, password, clientIP string) error {
+	if !l.IsProtected() {
+		return nil
+	}
+
+	if err := p.passwordAttemptSvc.Attempt(l, clientIP, time.Now()); err != nil {
+		return err
+	}
+
+	if !p.linkSvc.CheckLinkPassword(l, password) {
+		return &uploadError{http.StatusUnprocessableEntity, "Invalid Password"}
+	}
+
+	if err := p.passwordAttemptSvc.RecordSuccess(l, clientIP); err != nil {
+		log.Println("record password success: ", err)
+	}
+
+	return nil
+}
+
+// checkAcceptingUploads checks whether the link is open at the given time
+// following its late policy, and returns how late the upload is
+func (p *uploadProcessor) checkAcceptingUploads(l *domain.Link, at time.Time) (time.Duration, error) {
+	lateness, err := p.linkSvc.CheckUploadTime(l, at)
+	switch err {
+	case domain.ErrLinkNotYetOpen, domain.ErrLinkExpired:
+		return 0, &uploadError{http.StatusForbidden, err.Error()}
+	}
+
+	return lateness, err
+}
+
+// openLink runs every check needed before receiving the file
+func (p *uploadProcessor) openLink(linkID uint, password, clientIP string) (*domain.Link, error) {
+	l, err := p.fetchLink(linkID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.analyticsSvc.RecordUploadAttempt(l); err != nil {
+		log.Println("record upload attempt: ", err)
+	}
+
+	if err = p.checkPassword(l, password, clientIP); err != nil {
+		return nil, p.fail(l, err)
+	}
+
+	if _, err = p.checkAcceptingUploads(l, time.Now()); err != nil {
+		return nil, p.fail(l, err)
+	}
+
+	return l, nil
+}
+
+// fail records the failed upload in the link's stats and returns the error. The message
+// shown to the uploader is recorded as the reason
+func (p *uploadProcessor) fail(l *domain.Link, err error) error {
+	reason := "Server Error"
+	switch e := err.(type) {
+	case *uploadError:
+		reason = e.message
+	case *domain.TooManyAttemptsError:
+		reason = "Too many failed password attempts"
+	}
+
+	if recordErr := p.analyticsSvc.RecordUploadFailed(l, reason); recordErr != nil {
+		log.Println("record upload failure: ", recordErr)
+	}
+
+	return err
+}
+
+// checkForm validates the uploader's answers to the link's form fields. The answers are
+// taken from the values whose key starts with formFieldPrefix
+func (p *uploadProcessor) checkForm(l *domain.Link, values map[string]string) (domain.FormAnswers, error) {
+	answers := make(map[string]string)
+	for key, value := range values {
+		if strings.HasPrefix(key, formFieldPrefix) {
+			answers[strings.TrimPrefix(key, formFieldPrefix)] = value
+		}
+	}
+
+	validAnswers, err := p.linkSvc.ValidateFormAnswers(l, answers)
+	if answerErr, ok := err.(*domain.FormAnswerError); ok {
+		return nil, &uploadError{http.StatusUnprocessableEntity, answerErr.Error()}
+	}
+
+	return validAnswers, err
+}
+
+// checkRoster matches the uploader's identifier against the link's roster,
+// the uploader's email is used when the identifier is not entered
+func (p *uploadProcessor) checkRoster(l *domain.Link, identifier, uploaderEmail string) (string, error) {
+	if strings.TrimSpace(identifier) == "" {
+		identifier = uploaderEmail
+	}
+
+	rosterIdentifier, err := p.rosterSvc.MatchIdentifier(l, identifier)
+	switch err {
+	case domain.ErrRosterIdentifierRequired, domain.ErrRosterIdentifierNotFound:
+		return "", &uploadError{http.StatusUnprocessableEntity, err.Error()}
+	}
+
+	return rosterIdentifier, err
+}
+
+// checkFile checks the file against the link's upload constraints. The file content is sniffed
+// to detect its type when file is not nil, the file is rewound afterward
+func (p *uploadProcessor) checkFile(l *domain.Link, file io.ReadSeeker, info uploadInfo) error {
+	var head []byte
+	if file != nil {
+		// the content sniffer only considers the first 512 bytes
+		head = make([]byte, 512)
+		n, err := io.ReadFull(file, head)
+		if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+			return err
+		}
+		head = head[:n]
+
+		if _, err = file.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	switch err := p.linkSvc.CheckFileConstraints(l, info.FileName, info.Size, head); err {
+	case nil:
+		return nil
+	case domain.ErrLinkFileTooLarge:
+		return &uploadError{
+			http.StatusRequestEntityTooLarge,
+			fmt.Sprintf("%s, the maximum size is %d bytes", err.Error(), l.MaxFileSize),
+		}
+	case domain.ErrLinkFileExtensionNotAllowed:
+		return &uploadError{
+			http.StatusUnsupportedMediaType,
+			fmt.Sprintf("%s, the allowed extensions are %s", err.Error(), strings.Join(l.AllowedExtensionList(), ", ")),
+		}
+	case domain.ErrLinkFileTypeNotAllowed:
+		return &uploadError{
+			http.StatusUnsupportedMediaType,
+			fmt.Sprintf("%s, the allowed types are %s", err.Error(), strings.Join(l.AllowedMimeTypeList(), ", ")),
+		}
+	default:
+		return err
+	}
+}
+
+// store sends the file to the link's storage provider and records the submission
+func (p *uploadProcessor) store(l *domain.Link, file io.ReadSeeker, info uploadInfo) (*domain.Submission, error) {
+	storageProviderService, err := p.storageProviderPool.Get(l.UserStorageCredential.ProviderID)
+	if err != nil {
+		if debug {
+			log.Println("get storage provider service: ", err)
+		}
+		return nil, &uploadError{http.StatusServiceUnavailable, "Sorry, but the Storage Provider is unavailable at the time"}
+	}
+
+	// the file is received completely at this point, so the lateness is measured now
+	receivedAt := time.Now()
+	lateness, err := p.checkAcceptingUploads(l, receivedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	// hash the received bytes for the uploader's receipt
+	hasher := sha256.New()
+	if _, err = io.Copy(hasher, file); err != nil {
+		return nil, err
+	}
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	submission := &domain.Submission{
+		LinkID:           l.ID,
+		FileName:         info.FileName,
+		Size:             info.Size,
+		ContentType:      info.ContentType,
+		UploaderName:     info.UploaderName,
+		UploaderEmail:    info.UploaderEmail,
+		UploaderIP:       info.UploaderIP,
+		ProviderID:       storageProviderService.ID(),
+		Status:           domain.SubmissionStatusSucceeded,
+		SHA256:           hex.EncodeToString(hasher.Sum(nil)),
+		FormAnswers:      info.FormAnswers,
+		RosterIdentifier: info.RosterIdentifier,
+		Late:             lateness > 0,
+		LatenessSeconds:  int64(lateness / time.Second),
+		CreatedAt:        receivedAt,
+	}
+
+	folder := l.Slug
+	if submission.Late && l.LateSubfolder {
+		folder = path.Join(l.Slug, domain.LateFolder)
+	}
+
+	submission.StoredPath, err = uploadToStorageProvider(
+		p.userSvc,
+		storageProviderService,
+		*l.UserStorageCredential,
+		file,
+		p.linkSvc.StoredFileName(l, submission),
+		folder,
+	)
+	if err != nil {
+		submission.Status = domain.SubmissionStatusFailed
+	}
+
+	// failing to record the submission must not fail the upload itself
+	if _, recordErr := p.submissionSvc.RecordSubmission(submission); recordErr != nil {
+		log.Println("record submission: ", recordErr)
+	}
+
+	event := domain.WebhookEventUploadCompleted
+	if err != nil {
+		event = domain.WebhookEventUploadFailed
+	}
+	if dispatchErr := p.webhookSvc.DispatchUploadEvent(event, l, submission); dispatchErr != nil {
+		log.Println("dispatch upload event: ", dispatchErr)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err = p.analyticsSvc.RecordUploadSucceeded(l, submission.Size); err != nil {
+		log.Println("record upload success: ", err)
+	}
+
+	// the uploader does not need to wait for the emails to be sent
+	notifiedSubmission := *submission
+	go func() {
+		if err := p.notificationSvc.SendReceipt(l, &notifiedSubmission); err != nil {
+			log.Println("send receipt: ", err)
+		}
+
+		if err := p.notificationSvc.NotifyUpload(l, &notifiedSubmission); err != nil {
+			log.Println("notify upload: ", err)
+		}
+	}()
+
+	return submission, nil
+}
+
+// uploadToStorageProvider uploads the file using the link's storage credential. The access token
+// is refreshed when it is expired, then the upload is retried once with the new token
+func uploadToStorageProvider(
+	userSvc domain.UserService,
+	storageProviderService domain.StorageProviderService,
+	cred domain.UserStorageCredential,
+	file io.ReadSeeker,
+	fileName, slug string,
+) (string, error) {
+	var err error
+
+	// refresh ahead of time to avoid uploading the file twice
+	if cred.IsExpiredAt(time.Now().Add(time.Minute)) {
+		cred, err = userSvc.RefreshStorageProviderCredential(cred)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	storedPath, err := storageProviderService.Upload(cred.StorageProviderCredential(), file, fileName, slug)
+	if err != domain.ErrStorageProviderCredentialExpired || cred.ProviderRefreshToken == "" {
+		return storedPath, err
+	}
+
+	cred, err = userSvc.RefreshStorageProviderCredential(cred)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err = file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	return storageProviderService.Upload(cred.StorageProviderCredential(), file, fileName, slug)
+}